@@ -0,0 +1,245 @@
+// Package archive turns a directory into a tar stream for favus upload
+// --archive and reads single members back out of the uploaded object. The
+// tar stream is compressed in independent blocks (gzip members / zstd frames)
+// and an index sidecar (<key>.index.json) records where every entry and every
+// block lives, so one entry can be fetched with a ranged GET.
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoCOMA/Favus/pkg/utils"
+)
+
+// IndexSuffix is appended to the archive key to name the index sidecar.
+const IndexSuffix = ".index.json"
+
+// IndexKey returns the key of the index sidecar for an archive key.
+func IndexKey(key string) string { return key + IndexSuffix }
+
+// Index describes an uploaded archive. Offsets of entries are in the tar
+// stream; blocks map tar stream ranges to byte ranges of the stored object.
+type Index struct {
+	Version        int       `json:"version"`
+	Format         string    `json:"format"`                // tar
+	Compression    string    `json:"compression,omitempty"` // gzip | zstd, empty for a plain tar
+	Key            string    `json:"key"`
+	Source         string    `json:"source"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Size           int64     `json:"size"`           // tar stream bytes
+	CompressedSize int64     `json:"compressedSize"` // object bytes
+	Blocks         []Block   `json:"blocks"`
+	Entries        []Entry   `json:"entries"`
+}
+
+// Block is one independently decodable member/frame of the object.
+type Block struct {
+	Offset           int64 `json:"offset"` // in the tar stream
+	Size             int64 `json:"size"`
+	CompressedOffset int64 `json:"compressedOffset"` // in the object
+	CompressedSize   int64 `json:"compressedSize"`
+}
+
+// Entry is one tar member. Offset is where its data starts in the tar stream.
+type Entry struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"` // file | dir | symlink
+	Offset   int64     `json:"offset,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	Mode     int64     `json:"mode"`
+	ModTime  time.Time `json:"modTime"`
+	Linkname string    `json:"linkname,omitempty"`
+}
+
+// Ext returns the archive extension for a compression format.
+func Ext(compression string) string {
+	switch compression {
+	case "gzip":
+		return ".tar.gz"
+	case "zstd":
+		return ".tar.zst"
+	}
+	return ".tar"
+}
+
+// CompressionForKey derives the compression from an archive key's extension.
+// ok is false when the key has no tar extension.
+func CompressionForKey(key string) (compression string, ok bool) {
+	k := strings.ToLower(key)
+	switch {
+	case strings.HasSuffix(k, ".tar.gz"), strings.HasSuffix(k, ".tgz"):
+		return "gzip", true
+	case strings.HasSuffix(k, ".tar.zst"), strings.HasSuffix(k, ".tzst"):
+		return "zstd", true
+	case strings.HasSuffix(k, ".tar"):
+		return "", true
+	}
+	return "", false
+}
+
+// Lookup returns the entry with the given name ("./" prefixes and trailing
+// slashes are ignored).
+func (idx *Index) Lookup(name string) (*Entry, bool) {
+	want := cleanName(name)
+	for i := range idx.Entries {
+		if cleanName(idx.Entries[i].Name) == want {
+			return &idx.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// BlockRange returns the blocks [first, last] that hold tar bytes
+// [off, off+size).
+func (idx *Index) BlockRange(off, size int64) (first, last int, err error) {
+	if len(idx.Blocks) == 0 {
+		return 0, 0, fmt.Errorf("index has no blocks")
+	}
+	end := off + size
+	first = sort.Search(len(idx.Blocks), func(i int) bool { return idx.Blocks[i].Offset+idx.Blocks[i].Size > off })
+	last = sort.Search(len(idx.Blocks), func(i int) bool { return idx.Blocks[i].Offset+idx.Blocks[i].Size >= end })
+	if first >= len(idx.Blocks) || last >= len(idx.Blocks) {
+		return 0, 0, fmt.Errorf("bytes %d-%d are outside the archive (%d bytes)", off, end, idx.Size)
+	}
+	return first, last, nil
+}
+
+func cleanName(name string) string {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+	return strings.TrimSuffix(name, "/")
+}
+
+// countingWriter tracks how many bytes went into the tar stream so far.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Write streams dir as a tar archive to w and appends an Entry per member to
+// idx.Entries. Names are relative to dir; the tar stream size goes to idx.Size.
+// Sockets, devices and other special files are skipped.
+func Write(dir string, w io.Writer, idx *Index) error {
+	cw := &countingWriter{w: w}
+	tw := tar.NewWriter(cw)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode().IsRegular(), info.IsDir():
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		default:
+			utils.Info(fmt.Sprintf("archive: skipping special file %s (%s)", path, info.Mode().Type()))
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("tar header for %s: %w", path, err)
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("write tar header for %s: %w", path, err)
+		}
+
+		// WriteHeader 직후의 위치가 곧 이 항목 데이터의 시작 오프셋
+		e := Entry{Name: hdr.Name, Mode: hdr.Mode, ModTime: hdr.ModTime.UTC(), Linkname: link}
+		switch {
+		case info.IsDir():
+			e.Type = "dir"
+		case link != "":
+			e.Type = "symlink"
+		default:
+			e.Type, e.Offset, e.Size = "file", cw.n, hdr.Size
+			if err := copyFile(tw, path, hdr.Size); err != nil {
+				return err
+			}
+		}
+		idx.Entries = append(idx.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("finish tar stream: %w", err)
+	}
+	idx.Size = cw.n
+	return nil
+}
+
+func copyFile(tw *tar.Writer, path string, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.CopyN(tw, f, size); err != nil {
+		return fmt.Errorf("archive %s: %w (did it change while archiving?)", path, err)
+	}
+	return nil
+}
+
+// Scan walks dir the way Write does and estimates the tar stream size (one
+// header block per entry; long names that need PAX headers add a little).
+func Scan(dir string) (files, dirs int, dataBytes, tarBytes int64, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			dirs++
+		case info.Mode().IsRegular():
+			files++
+			dataBytes += info.Size()
+			tarBytes += (info.Size() + 511) / 512 * 512
+		case info.Mode()&fs.ModeSymlink != 0:
+		default:
+			return nil
+		}
+		tarBytes += 512
+		return nil
+	})
+	return files, dirs, dataBytes, tarBytes + 1024, err
+}
@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/GoCOMA/Favus/internal/downloader"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// LoadIndex fetches and parses the index sidecar of the archive at key.
+func LoadIndex(ctx context.Context, store storage.ObjectStore, bucket, key string) (*Index, error) {
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(IndexKey(key))})
+	if err != nil {
+		return nil, fmt.Errorf("get archive index s3://%s/%s: %w", bucket, IndexKey(key), err)
+	}
+	defer out.Body.Close()
+	var idx Index
+	if err := json.NewDecoder(out.Body).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("parse archive index %s: %w", IndexKey(key), err)
+	}
+	if idx.Format != "tar" {
+		return nil, fmt.Errorf("archive index %s: unsupported format %q", IndexKey(key), idx.Format)
+	}
+	return &idx, nil
+}
+
+// Extract writes the data of regular file e to w. It fetches only the blocks
+// that hold the entry, with a single ranged GET.
+func Extract(ctx context.Context, store storage.ObjectStore, bucket string, idx *Index, e *Entry, w io.Writer) error {
+	if e.Type != "file" {
+		return fmt.Errorf("%s is a %s, not a regular file", e.Name, e.Type)
+	}
+	if e.Size == 0 {
+		return nil
+	}
+
+	// 압축하지 않은 tar 는 항목 데이터만 바로 읽는다
+	from, to, skip := e.Offset, e.Offset+e.Size-1, int64(0)
+	if idx.Compression != "" {
+		first, last, err := idx.BlockRange(e.Offset, e.Size)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+		b0, b1 := idx.Blocks[first], idx.Blocks[last]
+		from, to = b0.CompressedOffset, b1.CompressedOffset+b1.CompressedSize-1
+		skip = e.Offset - b0.Offset
+	}
+
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(idx.Key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", from, to)),
+	})
+	if err != nil {
+		return fmt.Errorf("get %s bytes %d-%d: %w", idx.Key, from, to, err)
+	}
+	defer out.Body.Close()
+
+	var r io.Reader = out.Body
+	if idx.Compression != "" {
+		dec, err := downloader.NewDecoder(out.Body, idx.Compression)
+		if err != nil {
+			return fmt.Errorf("%s: %w", idx.Key, err)
+		}
+		defer dec.Close()
+		if _, err := io.CopyN(io.Discard, dec, skip); err != nil {
+			return fmt.Errorf("seek to %s in block: %w", e.Name, err)
+		}
+		r = dec
+	}
+	n, err := io.CopyN(w, r, e.Size)
+	if err != nil {
+		return fmt.Errorf("extract %s: got %d of %d bytes: %w", e.Name, n, e.Size, err)
+	}
+	return nil
+}
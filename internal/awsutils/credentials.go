@@ -33,7 +33,7 @@ func LoadAWSConfig(profile string) (aws.Config, error) {
 		}
 		// 파일 없음 등은 경고만 출력하고 대화형 입력으로 진행합니다.
 		fmt.Printf(" AWS 설정 로드 실패: %v\n", err)
-		fmt.Println(" 대화형 입력으로 진행합니다.\n")
+		fmt.Print(" 대화형 입력으로 진행합니다.\n\n")
 		goto promptCredentials
 	}
 
@@ -75,6 +75,23 @@ promptCredentials:
 	return cfg, nil
 }
 
+// LoadAWSConfigNoPrompt 는 LoadAWSConfig 와 같지만 인증 정보가 없으면 묻지 않고
+// 에러를 반환한다. 터미널이 없는 곳(HTTP 핸들러, 백그라운드 에이전트)에서 쓴다.
+func LoadAWSConfigNoPrompt(profile string) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{}
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return cfg, fmt.Errorf("load aws config: %w", err)
+	}
+	if _, err := cfg.Credentials.Retrieve(context.TODO()); err != nil {
+		return cfg, fmt.Errorf("aws credentials not found (set AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or a profile): %w", err)
+	}
+	return cfg, nil
+}
+
 // 🟡 입력된 값이 없을 때만 프롬프트 출력
 func promptIfEmpty(accessKey, secretKey, region string) (string, string, string) {
 	reader := bufio.NewReader(os.Stdin)
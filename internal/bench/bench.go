@@ -0,0 +1,230 @@
+// Package bench measures multipart upload throughput for a grid of part sizes
+// and concurrencies. Each combination uploads generated (incompressible) data
+// into its own multipart upload, which is always aborted afterwards, so a
+// benchmark leaves no objects behind.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MinPartSizeMB is the S3 minimum part size (except for the last part).
+const MinPartSizeMB = 5
+
+// Default grid.
+var (
+	DefaultPartSizesMB = []int{8, 16, 32, 64}
+	DefaultConcurrency = []int{2, 4, 8, 16}
+)
+
+// Options describe one benchmark.
+type Options struct {
+	Store       storage.ObjectStore
+	Bucket      string
+	Prefix      string // keys are <Prefix>p<MB>-c<N>
+	Size        int64  // bytes uploaded per combination
+	PartSizesMB []int
+	Concurrency []int
+	Attempts    int // per part (default 3)
+
+	// Progress, when set, is called after each combination.
+	Progress func(done, total int, r Result)
+}
+
+// Result is the measurement of one combination.
+type Result struct {
+	PartSizeMB     int     `json:"partSizeMB" yaml:"partSizeMB"`
+	Concurrency    int     `json:"concurrency" yaml:"concurrency"`
+	Bytes          int64   `json:"bytes" yaml:"bytes"` // bytes of parts that succeeded
+	Parts          int     `json:"parts" yaml:"parts"`
+	FailedParts    int     `json:"failedParts" yaml:"failedParts"` // failed on every attempt
+	Attempts       int     `json:"attempts" yaml:"attempts"`
+	FailedAttempts int     `json:"failedAttempts" yaml:"failedAttempts"`
+	ErrorRate      float64 `json:"errorRate" yaml:"errorRate"` // failedAttempts / attempts
+	Seconds        float64 `json:"seconds" yaml:"seconds"`
+	ThroughputBps  float64 `json:"throughputBps" yaml:"throughputBps"`
+	Error          string  `json:"error,omitempty" yaml:"error,omitempty"`           // first error, if any
+	AbortError     string  `json:"abortError,omitempty" yaml:"abortError,omitempty"` // the upload may be left over
+	Key            string  `json:"key" yaml:"key"`
+	UploadID       string  `json:"uploadId,omitempty" yaml:"uploadId,omitempty"`
+}
+
+// OK reports whether every part of the combination was uploaded.
+func (r Result) OK() bool {
+	return r.FailedParts == 0 && r.Error == "" && r.Parts > 0
+}
+
+// Run benchmarks every combination in order. When ctx is cancelled it stops
+// after aborting the upload in progress and returns the results so far
+// together with ctx.Err().
+func Run(ctx context.Context, o Options) ([]Result, error) {
+	if o.Size <= 0 {
+		return nil, fmt.Errorf("bench: size must be positive")
+	}
+	if o.Attempts <= 0 {
+		o.Attempts = 3
+	}
+	maxPart := 0
+	for _, mb := range o.PartSizesMB {
+		if mb < MinPartSizeMB {
+			return nil, fmt.Errorf("bench: part size %d MB is below the S3 minimum of %d MB", mb, MinPartSizeMB)
+		}
+		maxPart = max(maxPart, mb)
+	}
+	for _, c := range o.Concurrency {
+		if c < 1 {
+			return nil, fmt.Errorf("bench: concurrency must be at least 1, got %d", c)
+		}
+	}
+	// 한 번 만든 난수 버퍼를 모든 파트가 읽기 전용으로 공유한다
+	data := make([]byte, min(int64(maxPart)<<20, o.Size))
+	if _, err := rand.Read(data); err != nil {
+		return nil, fmt.Errorf("bench: generate data: %w", err)
+	}
+
+	total := len(o.PartSizesMB) * len(o.Concurrency)
+	results := make([]Result, 0, total)
+	for _, mb := range o.PartSizesMB {
+		for _, c := range o.Concurrency {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			r := runOne(ctx, o, data, mb, c)
+			results = append(results, r)
+			if o.Progress != nil {
+				o.Progress(len(results), total, r)
+			}
+		}
+	}
+	return results, ctx.Err()
+}
+
+func runOne(ctx context.Context, o Options, data []byte, partMB, concurrency int) Result {
+	r := Result{PartSizeMB: partMB, Concurrency: concurrency, Key: fmt.Sprintf("%sp%d-c%d", o.Prefix, partMB, concurrency)}
+	partSize := int64(partMB) << 20
+
+	out, err := o.Store.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(r.Key),
+	})
+	if err != nil {
+		r.Error = fmt.Sprintf("create multipart upload: %v", err)
+		return r
+	}
+	r.UploadID = aws.ToString(out.UploadId)
+	defer func() {
+		// ctx 가 취소됐어도 반드시 정리한다
+		actx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := o.Store.AbortMultipartUpload(actx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(o.Bucket),
+			Key:      aws.String(r.Key),
+			UploadId: aws.String(r.UploadID),
+		}); err != nil {
+			r.AbortError = err.Error()
+		}
+	}()
+
+	type part struct {
+		num  int32
+		size int64
+	}
+	jobs := make(chan part)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	record := func(size int64, attempts, failed int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.Attempts += attempts
+		r.FailedAttempts += failed
+		if err != nil {
+			r.FailedParts++
+			if r.Error == "" {
+				r.Error = err.Error()
+			}
+			return
+		}
+		r.Parts++
+		r.Bytes += size
+	}
+
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				var (
+					err      error
+					attempts int
+					failed   int
+				)
+				for attempts < o.Attempts {
+					attempts++
+					_, err = o.Store.UploadPart(ctx, &s3.UploadPartInput{
+						Bucket:        aws.String(o.Bucket),
+						Key:           aws.String(r.Key),
+						UploadId:      aws.String(r.UploadID),
+						PartNumber:    aws.Int32(p.num),
+						Body:          bytes.NewReader(data[:p.size]),
+						ContentLength: aws.Int64(p.size),
+					})
+					if err == nil || errors.Is(err, context.Canceled) {
+						break
+					}
+					failed++
+				}
+				record(p.size, attempts, failed, err)
+			}
+		}()
+	}
+send:
+	for off, n := int64(0), int32(1); off < o.Size; off, n = off+partSize, n+1 {
+		select {
+		case <-ctx.Done():
+			break send
+		case jobs <- part{num: n, size: min(partSize, o.Size-off)}:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	r.Seconds = time.Since(start).Seconds()
+	if r.Seconds > 0 {
+		r.ThroughputBps = float64(r.Bytes) / r.Seconds
+	}
+	if r.Attempts > 0 {
+		r.ErrorRate = float64(r.FailedAttempts) / float64(r.Attempts)
+	}
+	return r
+}
+
+// Rank orders results best first: combinations whose parts all succeeded,
+// by throughput, then by error rate.
+func Rank(results []Result) []Result {
+	out := append([]Result(nil), results...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.OK() != b.OK() {
+			return a.OK()
+		}
+		if a.ThroughputBps != b.ThroughputBps {
+			return a.ThroughputBps > b.ThroughputBps
+		}
+		return a.ErrorRate < b.ErrorRate
+	})
+	return out
+}
@@ -0,0 +1,182 @@
+package favus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/GoCOMA/Favus/internal/bench"
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchBucket      string
+	benchSize        string
+	benchPartSizes   []int
+	benchConcurrency []int
+	benchPrefix      string
+	benchSave        bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Find the fastest part size and concurrency for a bucket",
+	Long: `Uploads generated data once per part size × concurrency combination, measures
+throughput and error rate, and aborts every multipart upload it started. The
+results are ranked; the best values can be saved as partSizeMB and
+maxConcurrency in the config file (~/.favus/config.yaml, or --config).`,
+	Example: `
+  favus bench --bucket my-bucket --size 2GB
+  favus bench -b my-bucket --size 512MB --part-sizes 16,32,64 --concurrency 4,8,16,32
+  favus bench -b my-bucket --size 1GB -o json --save`,
+	RunE: runBench,
+}
+
+// benchDocument is the --output json/yaml document.
+type benchDocument struct {
+	Bucket  string         `json:"bucket" yaml:"bucket"`
+	Size    int64          `json:"sizeBytes" yaml:"sizeBytes"`
+	Results []bench.Result `json:"results" yaml:"results"` // best first
+	Best    *benchBest     `json:"best,omitempty" yaml:"best,omitempty"`
+}
+
+type benchBest struct {
+	PartSizeMB     int `json:"partSizeMB" yaml:"partSizeMB"`
+	MaxConcurrency int `json:"maxConcurrency" yaml:"maxConcurrency"`
+}
+
+func runBench(cmd *cobra.Command, _ []string) error {
+	conf, err := LoadConfigWithOverrides(benchBucket, "", "")
+	if err != nil {
+		return err
+	}
+	if conf.Bucket == "" {
+		return fmt.Errorf("S3 bucket name is required (use --bucket or config/ENV)")
+	}
+	size, err := parseByteSize(benchSize)
+	if err != nil {
+		return fmt.Errorf("--size: %w", err)
+	}
+	store, err := OpenObjectStore(conf)
+	if err != nil {
+		return err
+	}
+
+	runs := len(benchPartSizes) * len(benchConcurrency)
+	fmt.Fprintf(os.Stderr, "🏁 Benchmarking s3://%s: %d combinations × %s (%s in total, every upload is aborted)\n",
+		conf.Bucket, runs, reportBytes(float64(size)), reportBytes(float64(size)*float64(runs)))
+
+	// Ctrl-C: 진행 중인 업로드를 abort 하고 그때까지의 결과를 보여준다
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	results, err := bench.Run(ctx, bench.Options{
+		Store:       store,
+		Bucket:      conf.Bucket,
+		Prefix:      strings.TrimSuffix(benchPrefix, "/") + "/" + uuid.NewString()[:8] + "/",
+		Size:        size,
+		PartSizesMB: benchPartSizes,
+		Concurrency: benchConcurrency,
+		Progress: func(done, total int, r bench.Result) {
+			line := fmt.Sprintf("  [%d/%d] part %d MiB × %d: %s/s, %d/%d attempts failed",
+				done, total, r.PartSizeMB, r.Concurrency, reportBytes(r.ThroughputBps), r.FailedAttempts, r.Attempts)
+			if r.Error != "" {
+				line += " — " + shorten(r.Error, 100)
+			}
+			fmt.Fprintln(os.Stderr, line)
+			if r.AbortError != "" {
+				fmt.Fprintf(os.Stderr, "  ⚠️  could not abort %s (upload ID %s): %s — clean up with favus kill-orphans\n", r.Key, r.UploadID, r.AbortError)
+			}
+		},
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "⏹  Interrupted after %d of %d combinations\n", len(results), runs)
+	}
+
+	ranked := bench.Rank(results)
+	doc := benchDocument{Bucket: conf.Bucket, Size: size, Results: ranked}
+	if len(ranked) > 0 && ranked[0].OK() {
+		doc.Best = &benchBest{PartSizeMB: ranked[0].PartSizeMB, MaxConcurrency: ranked[0].Concurrency}
+	}
+	err = printOutput(doc, func(tw *tabwriter.Writer) {
+		tableRow(tw, "RANK", "PART SIZE", "CONCURRENCY", "THROUGHPUT", "TIME", "PARTS", "FAILED ATTEMPTS", "ERROR RATE", "ERROR")
+		for i, r := range ranked {
+			tableRow(tw, i+1, fmt.Sprintf("%d MiB", r.PartSizeMB), r.Concurrency, reportBytes(r.ThroughputBps)+"/s",
+				seconds(r.Seconds), fmt.Sprintf("%d/%d", r.Parts, r.Parts+r.FailedParts),
+				fmt.Sprintf("%d/%d", r.FailedAttempts, r.Attempts), fmt.Sprintf("%.1f%%", r.ErrorRate*100), shorten(r.Error, 60))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if doc.Best == nil {
+		fmt.Fprintln(os.Stderr, "⚠️  No combination uploaded all of its parts; nothing to save.")
+		return nil
+	}
+	path := cfgPath
+	if path == "" {
+		path = config.DefaultConfigPath()
+	}
+	save := benchSave
+	if !save && outputFormat == outputTable {
+		save = PromptYesNoDefault(fmt.Sprintf("💾 Save partSizeMB=%d and maxConcurrency=%d to %s?",
+			doc.Best.PartSizeMB, doc.Best.MaxConcurrency, path), false)
+	}
+	if !save {
+		return nil
+	}
+	if err := config.SetValues(path, map[string]any{
+		"partSizeMB":     doc.Best.PartSizeMB,
+		"maxConcurrency": doc.Best.MaxConcurrency,
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ Saved partSizeMB=%d and maxConcurrency=%d to %s\n", doc.Best.PartSizeMB, doc.Best.MaxConcurrency, path)
+	return nil
+}
+
+// parseByteSize parses sizes such as 512MB, 2GiB, 1.5G or 1048576. KB/MB/GB/TB
+// are decimal, KiB/MiB/GiB/TiB and K/M/G/T binary.
+func parseByteSize(s string) (int64, error) {
+	v := strings.TrimSpace(s)
+	i := len(v)
+	for i > 0 && (v[i-1] < '0' || v[i-1] > '9') && v[i-1] != '.' {
+		i--
+	}
+	num, err := strconv.ParseFloat(v[:i], 64)
+	if err != nil || num <= 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 512MB, 2GB, 1GiB)", s)
+	}
+	mult := map[string]float64{
+		"": 1, "b": 1,
+		"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+		"k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+	}[strings.ToLower(strings.TrimSpace(v[i:]))]
+	if mult == 0 {
+		return 0, fmt.Errorf("invalid size unit in %q (use B, KB, MB, GB, TB or KiB, MiB, GiB, TiB)", s)
+	}
+	return int64(num * mult), nil
+}
+
+func init() {
+	benchCmd.Flags().StringVarP(&benchBucket, "bucket", "b", "", "Bucket to benchmark (overrides config/ENV)")
+	benchCmd.Flags().StringVar(&benchSize, "size", "512MB", "Data uploaded per combination, e.g. 512MB or 2GB")
+	benchCmd.Flags().IntSliceVar(&benchPartSizes, "part-sizes", bench.DefaultPartSizesMB, "Part sizes to try, in MB (minimum 5)")
+	benchCmd.Flags().IntSliceVar(&benchConcurrency, "concurrency", bench.DefaultConcurrency, "Concurrencies to try")
+	benchCmd.Flags().StringVar(&benchPrefix, "prefix", "favus-bench", "Key prefix of the (aborted) benchmark uploads")
+	benchCmd.Flags().BoolVar(&benchSave, "save", false, "Save the best values to the config file without asking")
+	addOutputFlag(benchCmd)
+	rootCmd.AddCommand(benchCmd)
+}
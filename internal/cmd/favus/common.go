@@ -9,6 +9,8 @@ import (
 
 	"github.com/GoCOMA/Favus/internal/awsutils"
 	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/keytemplate"
+	"github.com/GoCOMA/Favus/internal/storage"
 	"github.com/GoCOMA/Favus/internal/uploader"
 	"github.com/aws/aws-sdk-go-v2/aws"
 )
@@ -149,12 +151,56 @@ func LoadConfigWithOverrides(flagBucket, flagKey, flagRegion string) (*config.Co
 	return conf, nil
 }
 
+// resolveKeyTemplate picks the key template for an upload: --key-template
+// (flagTmpl) or keyTemplate from config/ENV. An explicit --key (flagKey) turns
+// the config template off and cannot be combined with --key-template. It
+// returns nil when no template applies.
+func resolveKeyTemplate(flagTmpl, flagKey string, conf *config.Config) (*keytemplate.Template, error) {
+	tmpl := strings.TrimSpace(flagTmpl)
+	switch {
+	case tmpl != "" && flagKey != "":
+		return nil, fmt.Errorf("--key and --key-template cannot be combined")
+	case tmpl == "" && flagKey == "":
+		tmpl = strings.TrimSpace(conf.KeyTemplate)
+	}
+	if tmpl == "" {
+		return nil, nil
+	}
+	return keytemplate.Parse(tmpl)
+}
+
+// CreateUploaderWithAWS builds an uploader for conf.Bucket. Plain bucket names
+// and s3:// use AWS; file:// and mem:// locations are served locally without credentials.
 func CreateUploaderWithAWS(conf *config.Config) (*uploader.Uploader, error) {
+	return createUploaderInRegion(conf, "")
+}
+
+// createUploaderInRegion is CreateUploaderWithAWS with the S3 region
+// overridden (empty keeps the AWS config region).
+func createUploaderInRegion(conf *config.Config, region string) (*uploader.Uploader, error) {
+	loc, err := resolveLocation(conf)
+	if err != nil {
+		return nil, err
+	}
+	if loc.IsLocal() {
+		st, err := storage.OpenLocal(loc)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", loc, err)
+		}
+		up := uploader.NewUploaderWithStore(conf, st)
+		up.Location = loc.String()
+		return up, nil
+	}
+
 	awsCfg, err := awsutils.LoadAWSConfig(profile)
 	if err != nil {
 		return nil, fmt.Errorf("load aws config: %w", err)
 	}
 
+	if region != "" {
+		return uploader.NewUploaderWithStore(conf, storage.NewS3StoreInRegion(awsCfg, region)), nil
+	}
+
 	up, err := uploader.NewUploaderWithAWSConfig(conf, awsCfg)
 	if err != nil {
 		return nil, fmt.Errorf("init uploader: %w", err)
@@ -163,6 +209,33 @@ func CreateUploaderWithAWS(conf *config.Config) (*uploader.Uploader, error) {
 	return up, nil
 }
 
+// OpenObjectStore returns the backend serving conf.Bucket (see CreateUploaderWithAWS).
+func OpenObjectStore(conf *config.Config) (storage.ObjectStore, error) {
+	loc, err := resolveLocation(conf)
+	if err != nil {
+		return nil, err
+	}
+	if loc.IsLocal() {
+		return storage.OpenLocal(loc)
+	}
+	awsCfg, err := awsutils.LoadAWSConfig(profile)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	return storage.NewS3Store(awsCfg), nil
+}
+
+// resolveLocation parses conf.Bucket (name, s3://, file:// or mem://) and
+// rewrites it to the bare bucket name the backend expects.
+func resolveLocation(conf *config.Config) (storage.Location, error) {
+	loc, err := storage.ParseLocation(conf.Bucket)
+	if err != nil {
+		return loc, err
+	}
+	conf.Bucket = loc.Bucket
+	return loc, nil
+}
+
 func ValidateFile(filePath string) error {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", filePath)
@@ -170,8 +243,10 @@ func ValidateFile(filePath string) error {
 	return nil
 }
 
-func FormatSuccessMessage(action, bucket, key string) string {
-	return fmt.Sprintf("✅ %s → s3://%s/%s", action, bucket, key)
+// FormatSuccessMessage reports action on key under location, the bucket in
+// URL form (Uploader.LocationURL, storage.Location.String).
+func FormatSuccessMessage(action, location, key string) string {
+	return fmt.Sprintf("✅ %s → %s/%s", action, strings.TrimSuffix(location, "/"), key)
 }
 
 func PromptForMissingConfig(validator *ConfigValidator) {
@@ -1,7 +1,15 @@
 package favus
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
 
 	"github.com/spf13/cobra"
 )
@@ -17,7 +25,8 @@ var deleteCmd = &cobra.Command{
 	Long:  "Deletes a single object from the configured S3 bucket.",
 	Example: `
   favus delete --key uploads/video.mp4
-  favus delete --bucket my-bucket --key uploads/video.mp4 -c config.yaml`,
+  favus delete --bucket my-bucket --key uploads/video.mp4 -c config.yaml
+  favus delete --key uploads/video.mp4 --dry-run`,
 	RunE: runDelete,
 }
 
@@ -38,14 +47,63 @@ func runDelete(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if dryRun {
+		return planDelete(up.Store(), conf.Bucket, conf.Key)
+	}
+
 	if err := up.DeleteFile(conf.Key); err != nil {
 		return fmt.Errorf("delete failed: %w", err)
 	}
 
-	fmt.Println(FormatSuccessMessage("Deleted", conf.Bucket, conf.Key))
+	fmt.Println(FormatSuccessMessage("Deleted", up.LocationURL(), conf.Key))
 	return nil
 }
 
+// deletePlan lists the object a delete would remove.
+type deletePlan struct {
+	Operation string       `json:"operation"`
+	Bucket    string       `json:"bucket"`
+	Objects   []planObject `json:"objects"`
+	Notes     []string     `json:"notes,omitempty"`
+}
+
+type planObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"lastModified,omitempty"`
+}
+
+func planDelete(store storage.ObjectStore, bucket, key string) error {
+	plan := deletePlan{Operation: "delete", Bucket: bucket, Objects: []planObject{}}
+	head, err := store.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || (apiErr.ErrorCode() != "NotFound" && apiErr.ErrorCode() != "NoSuchKey") {
+			return fmt.Errorf("head object: %w", err)
+		}
+		// S3 DeleteObject는 없는 키도 성공으로 처리하므로 실제 실행 결과와 맞춘다
+		plan.Notes = append(plan.Notes, "object does not exist; delete would be a no-op")
+	} else {
+		plan.Objects = append(plan.Objects, planObject{
+			Key:          key,
+			Size:         aws.ToInt64(head.ContentLength),
+			ETag:         aws.ToString(head.ETag),
+			LastModified: aws.ToTime(head.LastModified),
+		})
+	}
+
+	return printPlan(plan, func() {
+		fmt.Printf("Would delete %d object(s) from %s:\n", len(plan.Objects), bucket)
+		for _, o := range plan.Objects {
+			fmt.Printf("  - %s (%d bytes, ETag %s, modified %s)\n", o.Key, o.Size, o.ETag, o.LastModified.UTC().Format(time.RFC3339))
+		}
+		for _, n := range plan.Notes {
+			fmt.Printf("ℹ️  %s\n", n)
+		}
+	})
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 	deleteCmd.Flags().StringVar(&delBucket, "bucket", "", "S3 bucket (overrides config/ENV)")
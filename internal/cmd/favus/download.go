@@ -0,0 +1,157 @@
+package favus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/downloader"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadBucket string
+	downloadKey    string
+	downloadOutput string
+	downloadRaw    bool
+	downloadForce  bool
+
+	catBucket string
+	catKey    string
+	catRaw    bool
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download an object, decompressing favus --compress uploads",
+	Long: `Downloads an object to a local file. Objects uploaded with --compress (gzip or zstd)
+are decompressed while streaming, saved under their original file name and checked
+against the favus-original-size metadata. Use --raw to keep the stored bytes.`,
+	Example: `
+  favus download --bucket my-bucket --key backups/db.dump.zst
+  favus download -b my-bucket -k backups/db.dump.zst -o /restore/
+  favus download -b my-bucket -k logs/app.log.gz --raw`,
+	RunE: runDownload,
+}
+
+var catCmd = &cobra.Command{
+	Use:   "cat",
+	Short: "Write an object to stdout, decompressing favus --compress uploads",
+	Example: `
+  favus cat --bucket my-bucket --key logs/app.log.gz | grep ERROR
+  favus cat -b my-bucket -k logs/app.log.gz --raw > app.log.gz`,
+	RunE: runCat,
+}
+
+func runDownload(_ *cobra.Command, _ []string) error {
+	conf, err := LoadConfigWithOverrides(downloadBucket, downloadKey, "")
+	if err != nil {
+		return err
+	}
+	validator := NewConfigValidator(conf).RequireBucket().RequireKey()
+	PromptForMissingConfig(validator)
+
+	store, err := OpenObjectStore(conf)
+	if err != nil {
+		return err
+	}
+	obj, err := downloader.Open(context.Background(), store, conf.Bucket, conf.Key, downloadRaw)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	// -o 가 없으면 현재 디렉터리, 디렉터리면 그 안에 원래 파일명으로 저장
+	dest := obj.Name
+	if downloadOutput != "" {
+		dest = downloadOutput
+		if fi, err := os.Stat(dest); err == nil && fi.IsDir() {
+			dest = filepath.Join(dest, obj.Name)
+		}
+	}
+	if _, err := os.Stat(dest); err == nil && !downloadForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", dest)
+	}
+	if obj.Encoding != "" {
+		fmt.Printf("🗜  %s object → decompressing to %s\n", obj.Encoding, dest)
+	}
+
+	// 크기 확인까지 끝난 뒤에만 최종 이름으로 옮긴다
+	tmp := dest + ".favus-download"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", tmp, err)
+	}
+	bar := progressbar.NewOptions64(
+		obj.Size,
+		progressbar.OptionSetDescription("download"),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionSetWriter(os.Stdout),
+	)
+	n, err := io.Copy(io.MultiWriter(f, bar), obj.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		if errors.Is(err, downloader.ErrSizeMismatch) {
+			return fmt.Errorf("s3://%s/%s: %w; nothing was saved", conf.Bucket, conf.Key, err)
+		}
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("rename %s: %w", tmp, err)
+	}
+
+	fmt.Println()
+	fmt.Printf("✅ Downloaded s3://%s/%s → %s (%d bytes)\n", conf.Bucket, conf.Key, dest, n)
+	return nil
+}
+
+func runCat(_ *cobra.Command, _ []string) error {
+	conf, err := LoadConfigWithOverrides(catBucket, catKey, "")
+	if err != nil {
+		return err
+	}
+	if conf.Bucket == "" || conf.Key == "" {
+		return fmt.Errorf("--bucket and --key are required")
+	}
+
+	store, err := OpenObjectStore(conf)
+	if err != nil {
+		return err
+	}
+	obj, err := downloader.Open(context.Background(), store, conf.Bucket, conf.Key, catRaw)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	// os.Stdout 은 stderr 로 돌려져 있고 planOut 만 진짜 stdout (reserveStdout)
+	if _, err := io.Copy(planOut, obj.Body); err != nil {
+		return fmt.Errorf("cat s3://%s/%s: %w", conf.Bucket, conf.Key, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(downloadCmd)
+	downloadCmd.Flags().StringVarP(&downloadBucket, "bucket", "b", "", "S3 bucket name (overrides config/ENV)")
+	downloadCmd.Flags().StringVarP(&downloadKey, "key", "k", "", "S3 object key")
+	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", "", "Output file or directory (default: original file name in the current directory)")
+	downloadCmd.Flags().BoolVar(&downloadRaw, "raw", false, "Save the stored bytes without decompressing")
+	downloadCmd.Flags().BoolVar(&downloadForce, "force", false, "Overwrite an existing output file")
+
+	rootCmd.AddCommand(catCmd)
+	catCmd.Flags().StringVarP(&catBucket, "bucket", "b", "", "S3 bucket name (overrides config/ENV)")
+	catCmd.Flags().StringVarP(&catKey, "key", "k", "", "S3 object key")
+	catCmd.Flags().BoolVar(&catRaw, "raw", false, "Write the stored bytes without decompressing")
+}
@@ -0,0 +1,142 @@
+package favus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GoCOMA/Favus/internal/archive"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dryRun     bool
+	planFormat string
+
+	// planOut receives the plan. For JSON plans (and favus cat) it is the real
+	// stdout while os.Stdout points at stderr, so prompts and progress chatter
+	// cannot corrupt the document.
+	planOut = os.Stdout
+)
+
+// dryRunCommands plan themselves under --dry-run; readOnlyCommands never
+// mutate anything and simply run. Every other command rejects --dry-run
+// instead of silently doing the real thing.
+var (
+	dryRunCommands   = []string{"favus upload", "favus resume", "favus delete", "favus kill-orphans"}
+	readOnlyCommands = []string{
+		"favus ls-orphans", "favus list-uploads", "favus ls-objects", "favus list-buckets",
+		"favus duplicate-stats", "favus queue list", "favus presign get", "favus presign put", "favus cat",
+		"favus report show",
+		"favus version", "favus help",
+	}
+)
+
+func checkDryRunSupport(cmd *cobra.Command) error {
+	if !dryRun {
+		return nil
+	}
+	switch planFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid --plan-format %q (use text or json)", planFormat)
+	}
+	path := cmd.CommandPath()
+	for _, c := range dryRunCommands {
+		if path == c {
+			if planFormat == "json" {
+				reserveStdout()
+			}
+			return nil
+		}
+	}
+	for _, c := range readOnlyCommands {
+		if path == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("--dry-run is not supported by '%s'", path)
+}
+
+// reserveStdout points os.Stdout at stderr so that only planOut writes to the
+// real stdout. Used by commands whose stdout is a JSON document.
+func reserveStdout() {
+	if planOut == os.Stdout {
+		planOut, os.Stdout = os.Stdout, os.Stderr
+	}
+}
+
+// printPlan writes plan as JSON (--plan-format json) or via text.
+func printPlan(plan any, text func()) error {
+	if planFormat == "json" {
+		enc := json.NewEncoder(planOut)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	fmt.Println("🧪 Dry run — no changes will be made")
+	text()
+	return nil
+}
+
+func printUploadPlan(p *uploader.UploadPlan) {
+	target := fmt.Sprintf("s3://%s/%s", p.Bucket, p.Key)
+	if p.Location != "" {
+		target = strings.TrimSuffix(p.Location, "/") + "/" + p.Key
+	}
+	fmt.Printf("Operation:   %s\n", p.Operation)
+	fmt.Printf("File:        %s (%d bytes)\n", p.FilePath, p.FileSize)
+	fmt.Printf("Target:      %s\n", target)
+	if p.RequestedKey != "" {
+		fmt.Printf("Key rewrite: %s → %s\n", p.RequestedKey, p.Key)
+	}
+	if p.UploadID != "" {
+		fmt.Printf("Upload ID:   %s\n", p.UploadID)
+	}
+	if p.Archive != "" {
+		fmt.Printf("Archive:     %s (index %s)\n", p.Archive, archive.IndexKey(p.Key))
+	} else if p.Compress {
+		if p.CompressLevel != 0 {
+			fmt.Printf("Compress:    yes (Content-Encoding: %s, level %d)\n", p.ContentEncoding, p.CompressLevel)
+		} else {
+			fmt.Printf("Compress:    yes (Content-Encoding: %s)\n", p.ContentEncoding)
+		}
+	} else if p.CompressCheck != nil {
+		fmt.Printf("Compress:    skipped (%s)\n", p.CompressCheck.Reason)
+	} else {
+		fmt.Println("Compress:    no")
+	}
+	fmt.Printf("Bucket:      %s\n", p.BucketCheck)
+	if d := p.Duplicate; d != nil {
+		verdict := "upload"
+		if !d.Upload {
+			verdict = "skip"
+		}
+		line := fmt.Sprintf("%s (%s)", verdict, d.Reason)
+		if d.Error != "" {
+			line += " — error: " + d.Error
+		}
+		fmt.Printf("Duplicate:   %s\n", line)
+	}
+	fmt.Printf("Parts:       %d × %d bytes, concurrency %d — %d part(s) / %d bytes to upload\n",
+		p.TotalParts, p.PartSizeBytes, p.MaxConcurrency, p.RemainingParts, p.RemainingBytes)
+	if len(p.Parts) > 0 {
+		fmt.Printf("  %6s  %14s  %12s  %s\n", "Part", "Offset", "Size", "State")
+		for _, pt := range p.Parts {
+			state := "upload"
+			if pt.Done {
+				state = "done"
+			}
+			fmt.Printf("  %6d  %14d  %12d  %s\n", pt.PartNumber, pt.Offset, pt.Size, state)
+		}
+	}
+	for _, n := range p.Notes {
+		fmt.Printf("ℹ️  %s\n", n)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Plan the operation and print it without making any changes")
+	rootCmd.PersistentFlags().StringVar(&planFormat, "plan-format", "text", "Dry-run plan format: text or json")
+}
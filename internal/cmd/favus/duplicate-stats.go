@@ -3,12 +3,21 @@ package favus
 import (
 	"context"
 	"fmt"
+	"sort"
+	"text/tabwriter"
 
 	"github.com/GoCOMA/Favus/internal/config"
 	"github.com/GoCOMA/Favus/internal/duplicate"
 	"github.com/spf13/cobra"
 )
 
+// duplicateStats is the document printed by duplicate-stats. Field names
+// inside each structure are the ones RedisBloom reports (e.g. "Size").
+type duplicateStats struct {
+	CuckooFilter   map[string]any `json:"cuckooFilter,omitempty" yaml:"cuckooFilter,omitempty"`
+	CountMinSketch map[string]any `json:"countMinSketch,omitempty" yaml:"countMinSketch,omitempty"`
+}
+
 var duplicateStatsCmd = &cobra.Command{
 	Use:   "duplicate-stats",
 	Short: "Show duplicate checker statistics",
@@ -36,21 +45,54 @@ func runDuplicateStats(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to get statistics: %w", err)
 	}
 
-	// Display statistics
-	fmt.Println("Duplicate Checker Statistics")
-	fmt.Println("================================")
-
-	if cfStats, ok := stats["cuckoo_filter"]; ok {
-		fmt.Printf("🪣 Cuckoo Filter: %+v\n", cfStats)
+	out := duplicateStats{
+		CuckooFilter:   infoFields(stats["cuckoo_filter"]),
+		CountMinSketch: infoFields(stats["count_min_sketch"]),
 	}
+	return printOutput(out, func(tw *tabwriter.Writer) {
+		tableRow(tw, "STRUCTURE", "FIELD", "VALUE")
+		for _, sec := range []struct {
+			name   string
+			fields map[string]any
+		}{{"cuckoo_filter", out.CuckooFilter}, {"count_min_sketch", out.CountMinSketch}} {
+			keys := make([]string, 0, len(sec.fields))
+			for k := range sec.fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				tableRow(tw, sec.name, k, sec.fields[k])
+			}
+		}
+	})
+}
 
-	if cmsStats, ok := stats["count_min_sketch"]; ok {
-		fmt.Printf("📈 Count-Min Sketch: %+v\n", cmsStats)
+// infoFields turns a CF.INFO / CMS.INFO reply (alternating field names and
+// values under RESP2, a map under RESP3) into a map. nil when unavailable.
+func infoFields(reply any) map[string]any {
+	switch r := reply.(type) {
+	case []interface{}:
+		m := make(map[string]any, len(r)/2)
+		for i := 0; i+1 < len(r); i += 2 {
+			m[fmt.Sprint(r[i])] = r[i+1]
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]any, len(r))
+		for k, v := range r {
+			m[fmt.Sprint(k)] = v
+		}
+		return m
+	case map[string]interface{}:
+		return r
+	case nil:
+		return nil
+	default:
+		return map[string]any{"info": fmt.Sprint(r)}
 	}
-
-	return nil
 }
 
 func init() {
+	addOutputFlag(duplicateStatsCmd)
 	rootCmd.AddCommand(duplicateStatsCmd)
 }
@@ -0,0 +1,113 @@
+package favus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/GoCOMA/Favus/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractBucket string
+	extractKey    string
+	extractEntry  string
+	extractOutput string
+	extractList   bool
+	extractForce  bool
+)
+
+var extractCmd = &cobra.Command{
+	Use:   "extract",
+	Short: "Fetch one file out of an archive uploaded with --archive",
+	Long: `Reads the index sidecar (<key>.index.json) of an archive made by 'favus upload --archive'
+and downloads a single entry with one ranged GET, decompressing only the blocks that hold it.`,
+	Example: `
+  favus extract --bucket my-bucket --key snapshots/photos.tar.zst --list
+  favus extract -b my-bucket -k snapshots/photos.tar.zst --entry 2024/img_0001.jpg
+  favus extract -b my-bucket -k snapshots/photos.tar.zst --entry notes.txt -o - | less`,
+	RunE: runExtract,
+}
+
+func runExtract(_ *cobra.Command, _ []string) error {
+	conf, err := LoadConfigWithOverrides(extractBucket, extractKey, "")
+	if err != nil {
+		return err
+	}
+	if conf.Bucket == "" || conf.Key == "" {
+		return fmt.Errorf("--bucket and --key are required")
+	}
+	if !extractList && extractEntry == "" {
+		return fmt.Errorf("--entry or --list is required")
+	}
+
+	store, err := OpenObjectStore(conf)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	idx, err := archive.LoadIndex(ctx, store, conf.Bucket, conf.Key)
+	if err != nil {
+		return err
+	}
+
+	if extractList {
+		fmt.Fprintf(planOut, "%-8s  %12s  %-20s  %s\n", "Type", "Size", "Modified", "Name")
+		for _, e := range idx.Entries {
+			fmt.Fprintf(planOut, "%-8s  %12d  %-20s  %s\n", e.Type, e.Size, e.ModTime.Format("2006-01-02T15:04:05Z"), e.Name)
+		}
+		return nil
+	}
+
+	e, ok := idx.Lookup(extractEntry)
+	if !ok {
+		return fmt.Errorf("%s is not in archive s3://%s/%s", extractEntry, conf.Bucket, conf.Key)
+	}
+
+	// -o - 는 stdout (reserveStdout 로 잡담은 stderr 로)
+	if extractOutput == "-" {
+		return archive.Extract(ctx, store, conf.Bucket, idx, e, planOut)
+	}
+	dest := path.Base(e.Name)
+	if extractOutput != "" {
+		dest = extractOutput
+		if fi, err := os.Stat(dest); err == nil && fi.IsDir() {
+			dest = filepath.Join(dest, path.Base(e.Name))
+		}
+	}
+	if _, err := os.Stat(dest); err == nil && !extractForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", dest)
+	}
+	tmp := dest + ".favus-download"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(e.Mode).Perm())
+	if err != nil {
+		return fmt.Errorf("create %s: %w", tmp, err)
+	}
+	err = archive.Extract(ctx, store, conf.Bucket, idx, e, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dest)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = os.Chtimes(dest, e.ModTime, e.ModTime)
+	fmt.Printf("✅ Extracted %s (%d bytes) from s3://%s/%s → %s\n", e.Name, e.Size, conf.Bucket, conf.Key, dest)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(extractCmd)
+	extractCmd.Flags().StringVarP(&extractBucket, "bucket", "b", "", "S3 bucket name (overrides config/ENV)")
+	extractCmd.Flags().StringVarP(&extractKey, "key", "k", "", "Archive object key (the index is <key>.index.json)")
+	extractCmd.Flags().StringVarP(&extractEntry, "entry", "e", "", "Path of the file inside the archive")
+	extractCmd.Flags().StringVarP(&extractOutput, "output", "o", "", "Output file or directory, - for stdout (default: entry's base name)")
+	extractCmd.Flags().BoolVar(&extractList, "list", false, "List the archive's entries instead of extracting")
+	extractCmd.Flags().BoolVar(&extractForce, "force", false, "Overwrite an existing output file")
+}
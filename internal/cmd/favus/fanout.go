@@ -0,0 +1,96 @@
+package favus
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// openDestinations parses --dest values (s3://bucket/key[?region=...] or
+// mem://bucket/key) and opens a store for each. AWS config is loaded once.
+func openDestinations(raws []string) ([]uploader.Destination, error) {
+	var (
+		awsCfg    aws.Config
+		awsLoaded bool
+	)
+	seen := make(map[string]bool, len(raws))
+	dests := make([]uploader.Destination, 0, len(raws))
+	for _, raw := range raws {
+		loc, key, err := storage.ParseObjectURL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("--dest: %w", err)
+		}
+		u, _ := url.Parse(raw) // ParseObjectURL already validated it
+		region := u.Query().Get("region")
+
+		id := loc.String() + "/" + key
+		if seen[id] {
+			return nil, fmt.Errorf("--dest %s is given more than once", id)
+		}
+		seen[id] = true
+
+		d := uploader.Destination{URL: raw, Bucket: loc.Bucket, Key: key, Region: region}
+		if loc.IsLocal() {
+			st, err := storage.OpenLocal(loc)
+			if err != nil {
+				return nil, fmt.Errorf("open %s: %w", loc, err)
+			}
+			d.Store, d.Location = st, loc.String()
+		} else {
+			if !awsLoaded {
+				if awsCfg, err = awsutils.LoadAWSConfig(profile); err != nil {
+					return nil, fmt.Errorf("load aws config: %w", err)
+				}
+				awsLoaded = true
+			}
+			d.Store = storage.NewS3StoreInRegion(awsCfg, region)
+		}
+		dests = append(dests, d)
+	}
+	return dests, nil
+}
+
+// runFanOut uploads filePath to every --dest (see uploader.FanOutUpload).
+func runFanOut(conf *config.Config, raws []string) error {
+	dests, err := openDestinations(raws)
+	if err != nil {
+		return err
+	}
+	up := uploader.NewUploaderWithStore(conf, dests[0].Store)
+
+	if dryRun {
+		plans, err := up.PlanFanOut(filePath, dests)
+		if err != nil {
+			return fmt.Errorf("plan fan-out: %w", err)
+		}
+		return printPlan(plans, func() {
+			for i, p := range plans {
+				if i > 0 {
+					fmt.Println()
+				}
+				printUploadPlan(p)
+			}
+		})
+	}
+
+	results, err := up.FanOutUpload(filePath, dests)
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Printf("❌ %s: %s\n", r.Destination, r.Error)
+			if r.StatusFile != "" {
+				fmt.Printf("   ↳ favus resume --file %s\n", r.StatusFile)
+			}
+			continue
+		}
+		fmt.Println(FormatSuccessMessage("Upload complete", r.Location, r.Key))
+	}
+	if err != nil {
+		return fmt.Errorf("fan-out upload failed: %w", err)
+	}
+	return nil
+}
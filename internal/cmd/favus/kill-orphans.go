@@ -3,9 +3,10 @@ package favus
 import (
 	"context"
 	"fmt"
-	"os"
+	"time"
 
-	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/uploader"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/spf13/cobra"
@@ -17,9 +18,11 @@ var killOrphansCmd = &cobra.Command{
 	Use:   "kill-orphans",
 	Short: "Abort ALL incomplete multipart uploads in a bucket",
 	Long: `Scans the given S3 bucket and aborts every in-progress multipart upload.
-This is destructive and may interrupt ongoing uploads.`,
+This is destructive and may interrupt ongoing uploads. Uploads whose status
+file is locked by a running favus process on this machine are skipped.`,
 	Example: `
-  favus kill-orphans --bucket my-bucket`,
+  favus kill-orphans --bucket my-bucket
+  favus kill-orphans --bucket my-bucket --dry-run --plan-format json`,
 	RunE: runKillOrphans,
 }
 
@@ -27,6 +30,7 @@ type AbortStats struct {
 	Total   int
 	Aborted int
 	Failed  int
+	Skipped int // locked by a running favus process
 }
 
 func (s AbortStats) HasFailures() bool {
@@ -38,10 +42,10 @@ func (s AbortStats) Print() {
 		fmt.Println("✅ 미완성 멀티파트 업로드가 없습니다.")
 		return
 	}
-	fmt.Printf("완료: 대상 %d, 성공 %d, 실패 %d\n", s.Total, s.Aborted, s.Failed)
+	fmt.Printf("완료: 대상 %d, 성공 %d, 실패 %d, 건너뜀 %d\n", s.Total, s.Aborted, s.Failed, s.Skipped)
 }
 
-func abortSingleUpload(ctx context.Context, client *s3.Client, bucket string, key, uploadID *string) error {
+func abortSingleUpload(ctx context.Context, client storage.ObjectStore, bucket string, key, uploadID *string) error {
 	_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
 		Bucket:   ToStringPtr(bucket),
 		Key:      key,
@@ -61,18 +65,12 @@ func runKillOrphans(_ *cobra.Command, _ []string) error {
 	validator := NewConfigValidator(conf).RequireBucket()
 	PromptForMissingConfig(validator)
 
-	// Setup AWS config and S3 client
-	awsCfg, err := awsutils.LoadAWSConfig(profile)
+	// Setup object store (S3 or file:// / mem:// backend)
+	client, err := OpenObjectStore(conf)
 	if err != nil {
-		return fmt.Errorf("load aws config: %w", err)
+		return err
 	}
 
-	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		if os.Getenv("AWS_ENDPOINT_URL") != "" {
-			o.UsePathStyle = true
-		}
-	})
-
 	fmt.Printf("🔍 Scanning bucket '%s' for incomplete multipart uploads...\n", conf.Bucket)
 
 	// Paginate through and abort all incomplete uploads
@@ -82,6 +80,17 @@ func runKillOrphans(_ *cobra.Command, _ []string) error {
 		MaxUploads: aws.Int32(1000),
 	})
 
+	// 로컬에서 살아 있는 favus 프로세스가 진행 중인 업로드는 abort 하지 않는다
+	locks := uploader.ActiveLocks(uploader.StatusDir())
+	lockedBy := func(uploadID string) (uploader.SessionLock, bool) {
+		l, ok := locks[uploadID]
+		return l, ok && l.Bucket == conf.Bucket
+	}
+
+	if dryRun {
+		return planKillOrphans(ctx, client, conf.Bucket, paginator, lockedBy)
+	}
+
 	stats := AbortStats{}
 	for paginator.HasMorePages() {
 		out, err := paginator.NextPage(ctx)
@@ -94,6 +103,11 @@ func runKillOrphans(_ *cobra.Command, _ []string) error {
 			key := StringPtrValue(up.Key)
 			uid := StringPtrValue(up.UploadId)
 
+			if l, ok := lockedBy(uid); ok {
+				stats.Skipped++
+				fmt.Printf("⏭️  건너뜀: key=%s uploadId=%s (%s 에서 진행 중)\n", key, uid, l.Owner())
+				continue
+			}
 			if err := abortSingleUpload(ctx, client, conf.Bucket, up.Key, up.UploadId); err != nil {
 				stats.Failed++
 				fmt.Printf("❌ abort 실패: key=%s uploadId=%s err=%v\n", key, uid, err)
@@ -111,6 +125,72 @@ func runKillOrphans(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// orphanPlan lists the uploads kill-orphans would abort.
+type orphanPlan struct {
+	Operation  string         `json:"operation"`
+	Bucket     string         `json:"bucket"`
+	Uploads    []orphanUpload `json:"uploads"`
+	Skipped    []orphanUpload `json:"skipped,omitempty"` // locked by a running favus process
+	TotalParts int            `json:"totalParts"`
+	TotalBytes int64          `json:"totalBytes"`
+}
+
+type orphanUpload struct {
+	Key       string    `json:"key"`
+	UploadID  string    `json:"uploadId"`
+	Initiated time.Time `json:"initiated,omitempty"`
+	Parts     int       `json:"parts"`
+	Bytes     int64     `json:"bytes"`
+	LockedBy  string    `json:"lockedBy,omitempty"`
+}
+
+func planKillOrphans(ctx context.Context, client storage.ObjectStore, bucket string, paginator *s3.ListMultipartUploadsPaginator, lockedBy func(string) (uploader.SessionLock, bool)) error {
+	plan := orphanPlan{Operation: "kill-orphans", Bucket: bucket, Uploads: []orphanUpload{}}
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list multipart uploads: %w", err)
+		}
+		for _, up := range out.Uploads {
+			ou := orphanUpload{Key: aws.ToString(up.Key), UploadID: aws.ToString(up.UploadId), Initiated: aws.ToTime(up.Initiated)}
+			if l, ok := lockedBy(ou.UploadID); ok {
+				ou.LockedBy = l.Owner()
+				plan.Skipped = append(plan.Skipped, ou)
+				continue
+			}
+			parts := s3.NewListPartsPaginator(client, &s3.ListPartsInput{Bucket: aws.String(bucket), Key: up.Key, UploadId: up.UploadId})
+			for parts.HasMorePages() {
+				page, err := parts.NextPage(ctx)
+				if err != nil {
+					return fmt.Errorf("list parts for %s: %w", ou.Key, err)
+				}
+				for _, p := range page.Parts {
+					ou.Parts++
+					ou.Bytes += aws.ToInt64(p.Size)
+				}
+			}
+			plan.TotalParts += ou.Parts
+			plan.TotalBytes += ou.Bytes
+			plan.Uploads = append(plan.Uploads, ou)
+		}
+	}
+
+	return printPlan(plan, func() {
+		for _, u := range plan.Skipped {
+			fmt.Printf("Would skip %s (%s): locked by %s\n", u.UploadID, u.Key, u.LockedBy)
+		}
+		if len(plan.Uploads) == 0 {
+			fmt.Println("✅ 미완성 멀티파트 업로드가 없습니다.")
+			return
+		}
+		fmt.Printf("Would abort %d upload(s) in '%s' (%d parts, %d bytes):\n", len(plan.Uploads), bucket, plan.TotalParts, plan.TotalBytes)
+		fmt.Printf("  %-36s  %6s  %14s  %-20s  %s\n", "UploadID", "Parts", "Bytes", "Initiated(UTC)", "Key")
+		for _, u := range plan.Uploads {
+			fmt.Printf("  %-36s  %6d  %14d  %-20s  %s\n", u.UploadID, u.Parts, u.Bytes, u.Initiated.UTC().Format(time.RFC3339), u.Key)
+		}
+	})
+}
+
 func init() {
 	rootCmd.AddCommand(killOrphansCmd)
 	killOrphansCmd.Flags().StringVar(&killBucket, "bucket", "", "S3 bucket (overrides config/ENV)")
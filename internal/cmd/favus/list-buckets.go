@@ -3,11 +3,12 @@ package favus
 import (
 	"context"
 	"fmt"
+	"text/tabwriter"
 	"time"
 
 	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/storage"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/spf13/cobra"
 )
 
@@ -17,15 +18,14 @@ var listBucketsCmd = &cobra.Command{
 	RunE:  runListBuckets,
 }
 
-func formatBucketInfo(bucket types.Bucket) (string, string) {
-	bucketName := StringPtrValue(bucket.Name)
-
-	creationDate := "N/A"
-	if bucket.CreationDate != nil {
-		creationDate = bucket.CreationDate.Format(time.RFC3339)
-	}
+// bucketEntry is one bucket in --output json/yaml.
+type bucketEntry struct {
+	Name         string `json:"name" yaml:"name"`
+	CreationDate string `json:"creationDate,omitempty" yaml:"creationDate,omitempty"` // RFC 3339, UTC
+}
 
-	return bucketName, creationDate
+type bucketListing struct {
+	Buckets []bucketEntry `json:"buckets" yaml:"buckets"`
 }
 
 func runListBuckets(_ *cobra.Command, _ []string) error {
@@ -35,7 +35,7 @@ func runListBuckets(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("load aws config: %w", err)
 	}
 
-	s3Client := s3.NewFromConfig(awsCfg)
+	s3Client := storage.NewS3Store(awsCfg)
 
 	// List buckets
 	result, err := s3Client.ListBuckets(context.TODO(), &s3.ListBucketsInput{})
@@ -43,20 +43,31 @@ func runListBuckets(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("could not list buckets: %w", err)
 	}
 
-	if len(result.Buckets) == 0 {
-		fmt.Println("No buckets found in the account.")
-		return nil
+	buckets := make([]bucketEntry, 0, len(result.Buckets))
+	for _, b := range result.Buckets {
+		e := bucketEntry{Name: StringPtrValue(b.Name)}
+		if b.CreationDate != nil {
+			e.CreationDate = b.CreationDate.UTC().Format(time.RFC3339)
+		}
+		buckets = append(buckets, e)
 	}
 
-	fmt.Println("Buckets:")
-	for _, bucket := range result.Buckets {
-		bucketName, creationDate := formatBucketInfo(bucket)
-		fmt.Printf("- %s (Created: %s)\n", bucketName, creationDate)
+	err = printOutput(bucketListing{Buckets: buckets}, func(tw *tabwriter.Writer) {
+		tableRow(tw, "NAME", "CREATED")
+		for _, b := range buckets {
+			tableRow(tw, b.Name, b.CreationDate)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(buckets) == 0 {
+		noteEmpty("No buckets found in the account.")
 	}
-
 	return nil
 }
 
 func init() {
+	addOutputFlag(listBucketsCmd)
 	rootCmd.AddCommand(listBucketsCmd)
 }
@@ -2,12 +2,10 @@ package favus
 
 import (
 	"fmt"
-	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/GoCOMA/Favus/internal/awsutils"
-	"github.com/GoCOMA/Favus/internal/uploader"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +17,24 @@ var (
 	lsObjectsWithIncomplete bool
 )
 
+// objectEntry is one object in --output json/yaml.
+type objectEntry struct {
+	Key          string `json:"key" yaml:"key"`
+	Size         int64  `json:"size" yaml:"size"`
+	StorageClass string `json:"storageClass,omitempty" yaml:"storageClass,omitempty"`
+	LastModified string `json:"lastModified,omitempty" yaml:"lastModified,omitempty"` // RFC 3339, UTC
+	ETag         string `json:"etag,omitempty" yaml:"etag,omitempty"`
+}
+
+// objectListing is the document printed by ls-objects. incompleteUploads is
+// present only with --with-incomplete.
+type objectListing struct {
+	Bucket            string            `json:"bucket" yaml:"bucket"`
+	Prefix            string            `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Objects           []objectEntry     `json:"objects" yaml:"objects"`
+	IncompleteUploads *[]multipartEntry `json:"incompleteUploads,omitempty" yaml:"incompleteUploads,omitempty"`
+}
+
 var listObjectsCmd = &cobra.Command{
 	Use:   "ls-objects",
 	Short: "List completed objects in an S3 bucket",
@@ -26,7 +42,8 @@ var listObjectsCmd = &cobra.Command{
 	Example: `
   favus ls-objects --bucket my-bucket
   favus ls-objects --bucket my-bucket --prefix uploads/
-  favus ls-objects --bucket my-bucket --prefix logs/ --max 50`,
+  favus ls-objects --bucket my-bucket --prefix logs/ --max 50
+  favus ls-objects --bucket my-bucket -o json | jq -r '.objects[].key'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// 1) Load effective config prepared by PersistentPreRunE
@@ -45,16 +62,10 @@ var listObjectsCmd = &cobra.Command{
 		}
 		conf.Bucket = effBucket
 
-		// 3) AWS config (LocalStack or real AWS)
-		awsCfg, err := awsutils.LoadAWSConfig(profile)
+		// 3) Create uploader (LocalStack, real AWS or file:// / mem:// backend) and list
+		up, err := CreateUploaderWithAWS(conf)
 		if err != nil {
-			return fmt.Errorf("load aws config: %w", err)
-		}
-
-		// 3) Create uploader and list
-		up, err := uploader.NewUploaderWithAWSConfig(conf, awsCfg)
-		if err != nil {
-			return fmt.Errorf("init uploader: %w", err)
+			return err
 		}
 
 		// 4) Load object list from S3
@@ -63,59 +74,58 @@ var listObjectsCmd = &cobra.Command{
 			return fmt.Errorf("fail to load objects: %w", err)
 		}
 
-		printedAnything := false
-
-		if len(objects) > 0 {
-			fmt.Println("Objects:")
-			fmt.Fprintf(os.Stdout, "%5s  %-12s  %-12s  %-20s  %s\n", "#", "Size", "Storage", "Modified", "Key")
-			for i, o := range objects {
-				size := aws.ToInt64(o.Size)
-				sc := string(o.StorageClass)
-				if sc == "" {
-					sc = "-"
-				}
-				mod := "-"
-				if o.LastModified != nil {
-					mod = o.LastModified.UTC().Format(time.RFC3339)
-				}
-				fmt.Fprintf(os.Stdout, "%5d  %-12d  %-12s  %-20s  %s\n", i+1, size, sc, mod, aws.ToString(o.Key))
+		listing := objectListing{Bucket: effBucket, Prefix: strings.TrimSpace(lsObjectsPrefix), Objects: []objectEntry{}}
+		for _, o := range objects {
+			e := objectEntry{
+				Key:          aws.ToString(o.Key),
+				Size:         aws.ToInt64(o.Size),
+				StorageClass: string(o.StorageClass),
+				ETag:         strings.Trim(aws.ToString(o.ETag), `"`),
+			}
+			if o.LastModified != nil {
+				e.LastModified = o.LastModified.UTC().Format(time.RFC3339)
 			}
-			printedAnything = true
+			listing.Objects = append(listing.Objects, e)
 		}
 
 		// 5) Optionally list incomplete multipart uploads
+		var incomplete []multipartEntry
 		if lsObjectsWithIncomplete {
 			uploads, err := up.ListMultipartUploads()
 			if err != nil {
 				return fmt.Errorf("fail to load incomplete uploads: %w", err)
 			}
-			if len(uploads) > 0 {
-				if printedAnything {
-					fmt.Println()
+			incomplete = make([]multipartEntry, 0, len(uploads))
+			for i := range uploads {
+				incomplete = append(incomplete, newMultipartEntry(&uploads[i]))
+			}
+			listing.IncompleteUploads = &incomplete
+		}
+
+		err = printOutput(listing, func(tw *tabwriter.Writer) {
+			if len(listing.Objects) > 0 {
+				tableRow(tw, "#", "SIZE", "STORAGE", "MODIFIED", "KEY")
+				for i, o := range listing.Objects {
+					tableRow(tw, i+1, o.Size, o.StorageClass, o.LastModified, o.Key)
 				}
-				fmt.Println("Incomplete multipart uploads:")
-				fmt.Fprintf(os.Stdout, "%-36s  %-20s  %s\n", "UploadID", "Initiated(UTC)", "Key")
-				for _, u := range uploads {
-					uid := "-"
-					key := "-"
-					initiated := "-"
-					if u.UploadId != nil {
-						uid = *u.UploadId
-					}
-					if u.Key != nil {
-						key = *u.Key
-					}
-					if u.Initiated != nil {
-						initiated = u.Initiated.UTC().Format(time.RFC3339)
-					}
-					fmt.Fprintf(os.Stdout, "%-36s  %-20s  %s\n", uid, initiated, key)
+			}
+			if len(incomplete) > 0 {
+				if len(listing.Objects) > 0 {
+					// 두 표의 열 너비를 따로 맞춘다
+					_ = tw.Flush()
+					fmt.Fprintln(tw)
+				}
+				tableRow(tw, "UPLOAD ID", "INITIATED", "KEY")
+				for _, u := range incomplete {
+					tableRow(tw, u.UploadID, u.Initiated, u.Key)
 				}
-				printedAnything = true
 			}
+		})
+		if err != nil {
+			return err
 		}
-
-		if !printedAnything {
-			fmt.Println("(no results)")
+		if len(listing.Objects) == 0 && len(incomplete) == 0 {
+			noteEmpty("(no results)")
 		}
 		return nil
 	},
@@ -127,4 +137,5 @@ func init() {
 	listObjectsCmd.Flags().StringVarP(&lsObjectsPrefix, "prefix", "p", "", "Optional key prefix to filter objects")
 	listObjectsCmd.Flags().Int32VarP(&lsObjectsMax, "max", "m", 0, "Max number of results to return (0 = unlimited)")
 	listObjectsCmd.Flags().BoolVarP(&lsObjectsWithIncomplete, "with-incomplete", "i", false, "Also show incomplete multipart uploads")
+	addOutputFlag(listObjectsCmd)
 }
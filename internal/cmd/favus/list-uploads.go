@@ -60,37 +60,31 @@ func runListUploads(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("list multipart uploads: %w", err)
 	}
 
-	if len(items) == 0 {
-		fmt.Println("No ongoing multipart uploads.")
-		sendUIEvent(context.Background(), conf.Bucket, []map[string]string{})
-		return nil
-	}
-
-	fmt.Printf("Ongoing multipart uploads in bucket %s:\n", conf.Bucket)
+	uploads := make([]multipartEntry, 0, len(items))
 	uiItems := make([]map[string]string, 0, len(items))
-
-	for _, u := range items {
-		// Extract upload information
-		key, uploadID, initiated := "", "", "-"
-
-		// Extract fields from MultipartUpload struct
-		key = StringPtrValue(u.Key)
-		uploadID = StringPtrValue(u.UploadId)
-		if u.Initiated != nil {
-			initiated = u.Initiated.Format(time.RFC3339)
-		}
-
-		// Console output
-		fmt.Printf("- UploadID: %s | Key: %s | Initiated: %s\n", uploadID, key, initiated)
+	for i := range items {
+		e := newMultipartEntry(&items[i])
+		uploads = append(uploads, e)
 
 		// UI data
+		initiated := e.Initiated
+		if initiated == "" {
+			initiated = "-"
+		}
 		uiItems = append(uiItems, map[string]string{
-			"uploadId":  uploadID,
-			"key":       key,
+			"uploadId":  e.UploadID,
+			"key":       e.Key,
 			"initiated": initiated,
 		})
 	}
 
+	if err := printMultipartListing(conf.Bucket, uploads); err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		noteEmpty("No ongoing multipart uploads.")
+	}
+
 	sendUIEvent(cmd.Context(), conf.Bucket, uiItems)
 	return nil
 }
@@ -98,4 +92,5 @@ func runListUploads(cmd *cobra.Command, _ []string) error {
 func init() {
 	rootCmd.AddCommand(listUploadsCmd)
 	listUploadsCmd.Flags().StringVar(&listBucket, "bucket", "", "S3 bucket to inspect (overrides config/ENV)")
+	addOutputFlag(listUploadsCmd)
 }
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"text/tabwriter"
 	"time"
 
-	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/uploader"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -25,40 +26,65 @@ var lsOrphansCmd = &cobra.Command{
 that may be wasting storage space and prints their metadata.`,
 	Example: `
   favus ls-orphans --bucket my-bucket
-  favus ls-orphans --config config.yaml`,
+  favus ls-orphans --config config.yaml
+  favus ls-orphans --bucket my-bucket -o json`,
 	RunE: runLsOrphans,
 }
 
-func formatUploadInfo(up *types.MultipartUpload) (string, string, string, string, string) {
-	key := StringPtrValue(up.Key)
-	uid := StringPtrValue(up.UploadId)
+// multipartEntry is one incomplete multipart upload in --output json/yaml
+// (ls-orphans, list-uploads, ls-objects --with-incomplete).
+type multipartEntry struct {
+	Key          string `json:"key" yaml:"key"`
+	UploadID     string `json:"uploadId" yaml:"uploadId"`
+	Initiated    string `json:"initiated,omitempty" yaml:"initiated,omitempty"` // RFC 3339, UTC
+	Initiator    string `json:"initiator,omitempty" yaml:"initiator,omitempty"`
+	StorageClass string `json:"storageClass,omitempty" yaml:"storageClass,omitempty"`
+	LockedBy     string `json:"lockedBy,omitempty" yaml:"lockedBy,omitempty"` // a local favus process is running it
+}
+
+// multipartListing is the document printed by ls-orphans and list-uploads.
+type multipartListing struct {
+	Bucket  string           `json:"bucket" yaml:"bucket"`
+	Uploads []multipartEntry `json:"uploads" yaml:"uploads"`
+}
 
-	initiated := "-"
+func newMultipartEntry(up *types.MultipartUpload) multipartEntry {
+	e := multipartEntry{
+		Key:          StringPtrValue(up.Key),
+		UploadID:     StringPtrValue(up.UploadId),
+		StorageClass: string(up.StorageClass),
+	}
 	if up.Initiated != nil {
-		initiated = up.Initiated.UTC().Format(time.RFC3339)
+		e.Initiated = up.Initiated.UTC().Format(time.RFC3339)
 	}
-
-	initiator := "-"
 	if up.Initiator != nil {
 		if up.Initiator.DisplayName != nil && *up.Initiator.DisplayName != "" {
-			initiator = StringPtrValue(up.Initiator.DisplayName)
+			e.Initiator = StringPtrValue(up.Initiator.DisplayName)
 		} else if up.Initiator.ID != nil && *up.Initiator.ID != "" {
-			initiator = StringPtrValue(up.Initiator.ID)
+			e.Initiator = StringPtrValue(up.Initiator.ID)
 		}
 	}
+	return e
+}
 
-	storageClass := string(up.StorageClass)
-	if storageClass == "" {
-		storageClass = "-"
+// markLocked fills LockedBy for the uploads whose status file is locked by a
+// live process (see uploader.AcquireLock).
+func markLocked(bucket string, uploads []multipartEntry) {
+	locks := uploader.ActiveLocks(uploader.StatusDir())
+	for i := range uploads {
+		if l, ok := locks[uploads[i].UploadID]; ok && l.Bucket == bucket {
+			uploads[i].LockedBy = l.Owner()
+		}
 	}
-
-	return uid, key, initiated, initiator, storageClass
 }
 
-func createS3ClientWithPathStyle(awsCfg aws.Config) *s3.Client {
-	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		if os.Getenv("AWS_ENDPOINT_URL") != "" {
-			o.UsePathStyle = true
+// printMultipartListing prints uploads in the --output format.
+func printMultipartListing(bucket string, uploads []multipartEntry) error {
+	markLocked(bucket, uploads)
+	return printOutput(multipartListing{Bucket: bucket, Uploads: uploads}, func(tw *tabwriter.Writer) {
+		tableRow(tw, "UPLOAD ID", "KEY", "INITIATED", "INITIATOR", "STORAGE CLASS", "LOCKED BY")
+		for _, u := range uploads {
+			tableRow(tw, u.UploadID, u.Key, u.Initiated, u.Initiator, u.StorageClass, u.LockedBy)
 		}
 	})
 }
@@ -75,22 +101,20 @@ func runLsOrphans(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("S3 bucket name is required")
 	}
 
-	// Setup AWS config and S3 client
-	awsCfg, err := awsutils.LoadAWSConfig(profile)
+	// Setup object store (S3 or file:// / mem:// backend)
+	s3Client, err := OpenObjectStore(conf)
 	if err != nil {
 		return err
 	}
 
-	s3Client := createS3ClientWithPathStyle(awsCfg)
-
-	// Scan for incomplete uploads
-	fmt.Println("🔍 Scanning for incomplete uploads in:", conf.Bucket)
+	// Scan for incomplete uploads (진행 메시지는 stderr — stdout 은 목록 전용)
+	fmt.Fprintln(os.Stderr, "🔍 Scanning for incomplete uploads in:", conf.Bucket)
 
 	ctx := context.Background()
 	var (
 		keyMarker      *string
 		uploadIDMarker *string
-		totalFound     int
+		uploads        = []multipartEntry{}
 	)
 
 	for {
@@ -104,11 +128,8 @@ func runLsOrphans(_ *cobra.Command, _ []string) error {
 			return fmt.Errorf("list multipart uploads: %w", err)
 		}
 
-		for _, up := range out.Uploads {
-			uid, key, initiated, initiator, storageClass := formatUploadInfo(&up)
-			fmt.Printf("- UploadID: %s | Key: %s | Initiated: %s | Initiator: %s | StorageClass: %s\n",
-				uid, key, initiated, initiator, storageClass)
-			totalFound++
+		for i := range out.Uploads {
+			uploads = append(uploads, newMultipartEntry(&out.Uploads[i]))
 		}
 
 		// Handle pagination
@@ -120,15 +141,19 @@ func runLsOrphans(_ *cobra.Command, _ []string) error {
 		break
 	}
 
-	if totalFound == 0 {
-		fmt.Println("✅ Found 0 orphan uploads")
+	if err := printMultipartListing(conf.Bucket, uploads); err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		noteEmpty("✅ Found 0 orphan uploads")
 	} else {
-		fmt.Printf("✅ Found %d incomplete multipart upload(s)\n", totalFound)
+		noteEmpty(fmt.Sprintf("✅ Found %d incomplete multipart upload(s)", len(uploads)))
 	}
 	return nil
 }
 
 func init() {
 	lsOrphansCmd.Flags().StringVarP(&lsOrphansBucket, "bucket", "b", "", "Target S3 bucket name")
+	addOutputFlag(lsOrphansCmd)
 	rootCmd.AddCommand(lsOrphansCmd)
 }
@@ -0,0 +1,105 @@
+package favus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/mocks3"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockS3Dir     string
+	mockS3Addr    string
+	mockS3Buckets []string
+	mockS3Quiet   bool
+)
+
+var mockS3Cmd = &cobra.Command{
+	Use:   "mock-s3",
+	Short: "Run a local S3-compatible server for development",
+	Long: `Serve the subset of the S3 REST API Favus uses (multipart upload, list, head,
+get, put and delete) from a local directory. Every top-level directory under
+--dir is a bucket. Only path-style addressing is supported, which is what
+Favus uses whenever AWS_ENDPOINT_URL is set. Request signatures are not checked.`,
+	Example: `
+  favus mock-s3 --dir ./data --addr :9000 --bucket dev-bucket
+
+  # in another terminal
+  export AWS_ENDPOINT_URL=http://127.0.0.1:9000 AWS_ACCESS_KEY_ID=test AWS_SECRET_ACCESS_KEY=test AWS_REGION=us-east-1
+  favus upload --file ./bigfile.mov --bucket dev-bucket --key path/bigfile.mov`,
+	RunE: runMockS3,
+}
+
+func runMockS3(_ *cobra.Command, _ []string) error {
+	dir, err := filepath.Abs(mockS3Dir)
+	if err != nil {
+		return fmt.Errorf("resolve --dir: %w", err)
+	}
+	store, err := storage.NewFSStore(dir)
+	if err != nil {
+		return err
+	}
+	for _, b := range mockS3Buckets {
+		if err := store.CreateBucket(b); err != nil {
+			return fmt.Errorf("create bucket %s: %w", b, err)
+		}
+	}
+
+	srv := mocks3.New(store)
+	if !mockS3Quiet {
+		srv.Logf = log.New(os.Stderr, "[mock-s3] ", log.LstdFlags).Printf
+	}
+
+	ln, err := net.Listen("tcp", mockS3Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", mockS3Addr, err)
+	}
+	httpSrv := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("🪣 mock-s3 serving %s on http://%s\n", dir, endpointHost(ln.Addr()))
+	fmt.Printf("   export AWS_ENDPOINT_URL=http://%s AWS_ACCESS_KEY_ID=test AWS_SECRET_ACCESS_KEY=test\n", endpointHost(ln.Addr()))
+	fmt.Println("   Press Ctrl+C to stop.")
+
+	if err := httpSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("mock-s3 server: %w", err)
+	}
+	fmt.Println("👋 mock-s3 stopped")
+	return nil
+}
+
+// endpointHost turns a wildcard listen address into something clients can dial.
+func endpointHost(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return fmt.Sprintf("127.0.0.1:%d", tcp.Port)
+}
+
+func init() {
+	mockS3Cmd.Flags().StringVar(&mockS3Dir, "dir", "./data", "Directory holding the buckets")
+	mockS3Cmd.Flags().StringVar(&mockS3Addr, "addr", ":9000", "Listen address (host:port)")
+	mockS3Cmd.Flags().StringSliceVar(&mockS3Buckets, "bucket", nil, "Bucket(s) to create on startup (repeatable)")
+	mockS3Cmd.Flags().BoolVar(&mockS3Quiet, "quiet", false, "Do not log requests")
+	rootCmd.AddCommand(mockS3Cmd)
+}
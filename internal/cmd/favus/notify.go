@@ -0,0 +1,96 @@
+package favus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/notify"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/spf13/cobra"
+)
+
+var (
+	notifyTestEvent string
+
+	// activeNotifier 는 runIn: cli 일 때 이 프로세스에서 웹훅을 보내는 notifier
+	activeNotifier *notify.Notifier
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Webhook notifications for upload sessions (notify: in the config)",
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a sample event to the configured webhooks",
+	Example: `
+  favus notify test -c config.yaml
+  favus notify test -c config.yaml --event stalled`,
+	RunE: runNotifyTest,
+}
+
+// startNotifier wires webhook notifications into this process when
+// notify.runIn is cli (the default). With runIn: agent, favus ui does it.
+func startNotifier(conf *config.Config) error {
+	n, err := notify.New(conf.Notify)
+	if err != nil {
+		return err
+	}
+	if n == nil || conf.Notify.RunIn == config.NotifyInAgent {
+		return nil
+	}
+	activeNotifier = n
+	uploader.EventSink = n.Handle
+	return nil
+}
+
+// closeNotifier gives pending webhook deliveries a moment before the process exits.
+func closeNotifier() {
+	if activeNotifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	activeNotifier.Close(ctx)
+}
+
+func runNotifyTest(_ *cobra.Command, _ []string) error {
+	conf := GetLoadedConfig()
+	if conf == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	n, err := notify.New(conf.Notify)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return fmt.Errorf("no webhooks configured (notify.webhooks in the config file)")
+	}
+	defer n.Close(context.Background())
+
+	switch notifyTestEvent {
+	case notify.EventSessionDone, notify.EventError, notify.EventStalled:
+	default:
+		return fmt.Errorf("--event must be %s, %s or %s", notify.EventSessionDone, notify.EventError, notify.EventStalled)
+	}
+	msg := notify.Sample(notifyTestEvent)
+	sent, err := n.Deliver(context.Background(), msg)
+	if sent == 0 {
+		fmt.Printf("ℹ️  No webhook subscribes to %s\n", notifyTestEvent)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Sample %s sent to %d webhook(s): %s\n", notifyTestEvent, sent, msg.Summary)
+	return nil
+}
+
+func init() {
+	notifyTestCmd.Flags().StringVar(&notifyTestEvent, "event", notify.EventSessionDone, "Event to simulate: session_done, error or stalled")
+	notifyCmd.AddCommand(notifyTestCmd)
+	rootCmd.AddCommand(notifyCmd)
+}
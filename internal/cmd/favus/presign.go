@@ -0,0 +1,251 @@
+package favus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/presign"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+var (
+	presignBucket      string
+	presignKey         string
+	presignExpires     time.Duration
+	presignContentType string
+	presignParts       int
+	presignOut         string
+	presignManifest    string
+	presignJSON        bool
+)
+
+var presignCmd = &cobra.Command{
+	Use:   "presign",
+	Short: "Create time-limited S3 URLs for people without AWS credentials",
+	Long: `Presign S3 requests with the configured AWS credentials (profile, ENV or prompt).
+'get' and 'put' sign single-object downloads and uploads. 'multipart' starts a
+multipart upload and signs one URL per part; the uploader PUTs each part, fills
+the returned ETag headers into the manifest and hands it back for 'complete'.`,
+	Example: `
+  favus presign get --bucket my-bucket --key reports/q3.pdf --expires 24h
+  favus presign put --bucket my-bucket --key inbox/data.csv --content-type text/csv
+  favus presign multipart --bucket my-bucket --key inbox/huge.iso --parts 200 --out huge.manifest.json
+  favus presign complete --manifest huge.manifest.json
+  favus presign abort --manifest huge.manifest.json`,
+}
+
+var presignGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Presign a download (GetObject) URL",
+	RunE:  runPresignSingle,
+}
+
+var presignPutCmd = &cobra.Command{
+	Use:   "put",
+	Short: "Presign a single-request upload (PutObject) URL, up to 5 GB",
+	RunE:  runPresignSingle,
+}
+
+var presignMultipartCmd = &cobra.Command{
+	Use:   "multipart",
+	Short: "Start a multipart upload and presign its UploadPart URLs",
+	RunE:  runPresignMultipart,
+}
+
+var presignCompleteCmd = &cobra.Command{
+	Use:   "complete",
+	Short: "Complete a presigned multipart upload from its manifest",
+	Long: `Completes the multipart upload in --manifest. Parts listed with an "etag" use it;
+parts without one are looked up with ListParts, so a manifest returned without
+ETags still works as long as every listed part was uploaded.`,
+	RunE: runPresignComplete,
+}
+
+var presignAbortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Abort a presigned multipart upload and discard its parts",
+	RunE:  runPresignAbort,
+}
+
+// openSigner returns a Signer for an S3 bucket. Local backends have nothing to presign.
+func openSigner() (*presign.Signer, error) {
+	loc, err := storage.ParseLocation(presignBucket)
+	if err != nil {
+		return nil, err
+	}
+	if loc.IsLocal() {
+		return nil, fmt.Errorf("%s is not an S3 bucket; presigned URLs need S3", loc)
+	}
+	presignBucket = loc.Bucket
+
+	awsCfg, err := awsutils.LoadAWSConfig(profile)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	return presign.New(storage.NewS3Store(awsCfg))
+}
+
+func requirePresignTarget() error {
+	conf, err := LoadConfigWithOverrides(presignBucket, presignKey, "")
+	if err != nil {
+		return err
+	}
+	PromptForMissingConfig(NewConfigValidator(conf).RequireBucket().RequireKey())
+	presignBucket, presignKey = conf.Bucket, conf.Key
+	return presign.ValidateExpires(presignExpires)
+}
+
+func runPresignSingle(cmd *cobra.Command, _ []string) error {
+	if err := requirePresignTarget(); err != nil {
+		return err
+	}
+	signer, err := openSigner()
+	if err != nil {
+		return err
+	}
+
+	var u *presign.URL
+	put := cmd.Name() == "put"
+	if put {
+		u, err = signer.Put(context.Background(), presignBucket, presignKey, presignContentType, presignExpires)
+	} else {
+		u, err = signer.Get(context.Background(), presignBucket, presignKey, presignExpires)
+	}
+	if err != nil {
+		return err
+	}
+
+	if presignJSON {
+		return writeJSON(planOut, u)
+	}
+	fmt.Printf("🔗 %s s3://%s/%s (expires %s)\n", u.Method, presignBucket, presignKey, u.Expires.Format(time.RFC3339))
+	fmt.Println(u.URL)
+	if put {
+		hint := "curl -X PUT --upload-file <file>"
+		for k, vs := range u.Header {
+			for _, v := range vs {
+				hint += fmt.Sprintf(" -H '%s: %s'", k, v)
+			}
+		}
+		fmt.Printf("ℹ️  %s '<url>'\n", hint)
+	}
+	return nil
+}
+
+func runPresignMultipart(_ *cobra.Command, _ []string) error {
+	if err := requirePresignTarget(); err != nil {
+		return err
+	}
+	signer, err := openSigner()
+	if err != nil {
+		return err
+	}
+
+	m, err := signer.CreateMultipart(context.Background(), presignBucket, presignKey, presignParts, presignExpires)
+	if err != nil {
+		if m != nil {
+			_ = presign.Abort(context.Background(), signer.Store, m)
+		}
+		return err
+	}
+
+	if presignOut == "" {
+		return writeJSON(planOut, m)
+	}
+	if err := m.Save(presignOut); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Multipart upload started: s3://%s/%s (UploadID: %s)\n", m.Bucket, m.Key, m.UploadID)
+	fmt.Printf("📝 Manifest with %d part URL(s) written to %s (expires %s)\n", len(m.Parts), presignOut, m.Parts[0].Expires.Format(time.RFC3339))
+	fmt.Println("ℹ️  Every part except the last must be at least 5 MiB. PUT each part to its URL,")
+	fmt.Println("   store the ETag response header as \"etag\" in the manifest, then run:")
+	fmt.Printf("   favus presign complete --manifest %s\n", presignOut)
+	return nil
+}
+
+func loadManifestStore() (*presign.Manifest, *presign.Signer, error) {
+	m, err := presign.LoadManifest(presignManifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	presignBucket = m.Bucket
+	signer, err := openSigner()
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, signer, nil
+}
+
+func runPresignComplete(_ *cobra.Command, _ []string) error {
+	m, signer, err := loadManifestStore()
+	if err != nil {
+		return err
+	}
+	out, err := presign.Complete(context.Background(), signer.Store, m)
+	if err != nil {
+		return err
+	}
+	fmt.Println(FormatSuccessMessage("Upload complete", "s3://"+m.Bucket, m.Key))
+	if etag := aws.ToString(out.ETag); etag != "" {
+		fmt.Printf("   ETag: %s\n", etag)
+	}
+	return nil
+}
+
+func runPresignAbort(_ *cobra.Command, _ []string) error {
+	m, signer, err := loadManifestStore()
+	if err != nil {
+		return err
+	}
+	if err := presign.Abort(context.Background(), signer.Store, m); err != nil {
+		return err
+	}
+	fmt.Printf("🗑  Aborted multipart upload %s for s3://%s/%s\n", m.UploadID, m.Bucket, m.Key)
+	return nil
+}
+
+// presignWritesJSON reports whether cmd prints a JSON document on stdout.
+func presignWritesJSON(cmd *cobra.Command) bool {
+	switch cmd.CommandPath() {
+	case "favus presign get", "favus presign put":
+		return presignJSON
+	case "favus presign multipart":
+		return presignOut == ""
+	}
+	return false
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func init() {
+	rootCmd.AddCommand(presignCmd)
+	presignCmd.AddCommand(presignGetCmd, presignPutCmd, presignMultipartCmd, presignCompleteCmd, presignAbortCmd)
+
+	for _, c := range []*cobra.Command{presignGetCmd, presignPutCmd, presignMultipartCmd} {
+		c.Flags().StringVarP(&presignBucket, "bucket", "b", "", "S3 bucket (overrides config/ENV)")
+		c.Flags().StringVarP(&presignKey, "key", "k", "", "S3 object key (overrides config/ENV)")
+		c.Flags().DurationVar(&presignExpires, "expires", time.Hour, "URL lifetime (max 168h)")
+	}
+	for _, c := range []*cobra.Command{presignGetCmd, presignPutCmd} {
+		c.Flags().BoolVar(&presignJSON, "json", false, "Print method, URL, signed headers and expiry as JSON")
+	}
+	presignPutCmd.Flags().StringVar(&presignContentType, "content-type", "", "Content-Type the uploader must send")
+	presignMultipartCmd.Flags().IntVar(&presignParts, "parts", 0, "Number of part URLs to sign (1-10000, required)")
+	presignMultipartCmd.Flags().StringVarP(&presignOut, "out", "o", "", "Write the manifest here instead of stdout")
+	_ = presignMultipartCmd.MarkFlagRequired("parts")
+
+	for _, c := range []*cobra.Command{presignCompleteCmd, presignAbortCmd} {
+		c.Flags().StringVarP(&presignManifest, "manifest", "m", "", "Manifest written by 'favus presign multipart' (required)")
+		_ = c.MarkFlagRequired("manifest")
+	}
+}
@@ -0,0 +1,442 @@
+package favus
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/keytemplate"
+	"github.com/GoCOMA/Favus/internal/queue"
+	"github.com/GoCOMA/Favus/internal/scheduler"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queueFile        string
+	queueBucket      string
+	queueKey         string
+	queuePriority    int
+	queueCompress    string
+	queueAll         bool
+	queueMaxJobs     int
+	queueListPending bool
+	queueKeyTemplate string
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage the persistent upload queue (~/.favus/queue)",
+	Long: `Enqueue uploads from scripts and let one background daemon work through them.
+Jobs are stored on disk, survive restarts and run with Uploader.UploadFile.
+The daemon runs with 'favus queue run' or alongside the UI agent ('favus ui --foreground').`,
+	Example: `
+  favus queue add --file ./a.bin --bucket my-bucket --key backups/a.bin --priority 10
+  favus queue add --file ./logs --bucket my-bucket --key-template 'raw/{yyyy}/{mm}/{dd}/{reldir}/{basename}'
+  favus queue list
+  favus queue pause 1a2b3c4d
+  favus queue retry 1a2b3c4d
+  favus queue cancel 1a2b3c4d
+  favus queue run --max-jobs 2`,
+}
+
+var queueAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an upload job to the queue",
+	RunE:  runQueueAdd,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued jobs (highest priority first)",
+	RunE:  runQueueList,
+}
+
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <job-id>...",
+	Short: "Cancel pending or paused jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return forEachJob(args, "canceled", (*queue.Store).Cancel)
+	},
+}
+
+var queueRetryCmd = &cobra.Command{
+	Use:   "retry <job-id>...",
+	Short: "Re-queue failed, canceled or paused jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		return forEachJob(args, "re-queued", (*queue.Store).Retry)
+	},
+}
+
+var queuePauseCmd = &cobra.Command{
+	Use:   "pause [job-id...]",
+	Short: "Pause pending jobs, or the whole queue with --all",
+	RunE:  runQueuePause,
+}
+
+var queueResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a queue paused with 'favus queue pause --all'",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		store, err := queue.NewStore("")
+		if err != nil {
+			return err
+		}
+		if err := store.SetPaused(false); err != nil {
+			return err
+		}
+		fmt.Println("▶️  Queue resumed")
+		return nil
+	},
+}
+
+var queueRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the queue daemon in the foreground",
+	RunE:  runQueueDaemon,
+}
+
+func runQueueAdd(_ *cobra.Command, _ []string) error {
+	conf, err := LoadConfigWithOverrides(queueBucket, queueKey, "")
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(queueFile)
+	if err != nil {
+		return fmt.Errorf("resolve file path: %w", err)
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("file not found: %s", queueFile)
+	}
+	if conf.Bucket == "" {
+		return fmt.Errorf("bucket is required (use --bucket or config/ENV)")
+	}
+	// queue add 는 config 의 key 를 쓰지 않으므로 --key 플래그만 템플릿을 끈다
+	tmpl, err := resolveKeyTemplate(queueKeyTemplate, queueKey, conf)
+	if err != nil {
+		return err
+	}
+	key := conf.Key
+	if queueKey == "" {
+		key = filepath.Base(abs)
+	}
+	if tmpl != nil {
+		key = tmpl.String()
+	}
+
+	if queueCompress != "" {
+		enabled, format, err := config.ParseCompress(queueCompress)
+		if err != nil {
+			return err
+		}
+		conf.Compress = enabled
+		if format != "" {
+			conf.CompressFormat = format
+		}
+	}
+	if conf.Compress {
+		if err := conf.ValidateCompression(); err != nil {
+			return err
+		}
+	}
+
+	// 디렉터리는 안의 일반 파일마다 잡 하나 (키는 템플릿으로만 정할 수 있다)
+	files := []string{abs}
+	if fi.IsDir() {
+		if tmpl == nil {
+			return fmt.Errorf("%s is a directory; give --key-template (e.g. '{reldir}/{basename}') to queue its files", queueFile)
+		}
+		if files, err = regularFiles(abs); err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no files to queue under %s", queueFile)
+		}
+	}
+
+	store, err := queue.NewStore("")
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		job := &queue.Job{
+			FilePath:       f,
+			Bucket:         conf.Bucket,
+			Key:            key,
+			Region:         conf.Region,
+			PartSizeMB:     conf.PartSizeMB,
+			MaxConcurrency: conf.MaxConcurrency,
+			Compress:       conf.Compress,
+			CompressFormat: conf.CompressFormat,
+			CompressLevel:  conf.CompressLevel,
+			Priority:       queuePriority,
+		}
+		if tmpl != nil {
+			job.KeyTemplate = tmpl.String()
+			if fi.IsDir() {
+				rel, _ := filepath.Rel(abs, filepath.Dir(f))
+				job.RelDir = filepath.ToSlash(rel)
+			}
+		}
+		if err := store.Add(job); err != nil {
+			return err
+		}
+		fmt.Printf("➕ Queued job %s: %s → s3://%s/%s (priority %d)\n", job.ShortID(), job.FilePath, job.Bucket, job.Key, job.Priority)
+	}
+	return nil
+}
+
+// regularFiles lists the regular files under dir (symlinks are not followed).
+func regularFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+func runQueueList(_ *cobra.Command, _ []string) error {
+	store, err := queue.NewStore("")
+	if err != nil {
+		return err
+	}
+	jobs, err := store.List()
+	if err != nil {
+		return err
+	}
+	if store.IsPaused() {
+		fmt.Println("⏸  Queue is paused (run 'favus queue resume' to continue)")
+	}
+	if len(jobs) == 0 {
+		fmt.Println("(queue is empty)")
+		return nil
+	}
+	fmt.Printf("%-8s  %-8s  %4s  %3s  %-20s  %s\n", "ID", "State", "Prio", "Try", "Created(UTC)", "Target")
+	for _, j := range jobs {
+		if queueListPending && j.State != queue.StatePending {
+			continue
+		}
+		fmt.Printf("%-8s  %-8s  %4d  %3d  %-20s  %s → s3://%s/%s\n",
+			j.ShortID(), j.State, j.Priority, j.Attempts, j.CreatedAt.UTC().Format(time.RFC3339),
+			j.FilePath, j.Bucket, j.Key)
+		if j.Error != "" {
+			fmt.Printf("          └ %s\n", j.Error)
+		}
+	}
+	return nil
+}
+
+func runQueuePause(_ *cobra.Command, args []string) error {
+	store, err := queue.NewStore("")
+	if err != nil {
+		return err
+	}
+	if queueAll {
+		if err := store.SetPaused(true); err != nil {
+			return err
+		}
+		fmt.Println("⏸  Queue paused; running jobs will finish, no new jobs start")
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("give one or more job IDs, or --all to pause the whole queue")
+	}
+	return forEachJob(args, "paused", (*queue.Store).Pause)
+}
+
+func forEachJob(ids []string, verb string, fn func(*queue.Store, string) (*queue.Job, error)) error {
+	store, err := queue.NewStore("")
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, id := range ids {
+		job, err := fn(store, id)
+		if err != nil {
+			failed++
+			fmt.Printf("❌ %s: %v\n", id, err)
+			continue
+		}
+		fmt.Printf("✅ job %s %s\n", job.ShortID(), verb)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d job(s) could not be %s", failed, verb)
+	}
+	return nil
+}
+
+// newQueueRunner builds the Runner used by the daemon: every job runs the
+// regular Uploader.UploadFile with the loaded config plus the job's overrides.
+// A job's key template is rendered here, so dates and {runId} belong to the
+// actual upload (a retried job gets a fresh key). The jobs running at once
+// share one part scheduler, so maxConcurrency and maxInFlightMB of the loaded
+// config apply across all of them. Once a job's multipart upload exists its
+// upload ID and status file are saved on the job, and a job interrupted by a
+// shutdown or crash resumes that upload instead of starting a new one.
+func newQueueRunner(store *queue.Store) queue.Runner {
+	var (
+		schedOnce sync.Once
+		sched     *scheduler.Scheduler
+	)
+	return func(ctx context.Context, job *queue.Job) error {
+		base := GetLoadedConfig()
+		if base == nil {
+			return fmt.Errorf("config not loaded")
+		}
+		schedOnce.Do(func() {
+			sched = scheduler.New(base.MaxConcurrency, base.MaxInFlightBytes())
+			fmt.Printf("🚦 queue: %d part slot(s) shared by all jobs, at most %d MB in flight\n",
+				sched.Slots(), base.MaxInFlightBytes()>>20)
+		})
+		conf := *base
+		conf.Bucket = job.Bucket
+		conf.Key = job.Key
+		if job.Region != "" {
+			conf.Region = job.Region
+		}
+		if job.PartSizeMB > 0 {
+			conf.PartSizeMB = job.PartSizeMB
+		}
+		if job.MaxConcurrency > 0 {
+			conf.MaxConcurrency = job.MaxConcurrency
+		}
+		conf.Compress = job.Compress
+		conf.CompressFormat = job.CompressFormat
+		conf.CompressLevel = job.CompressLevel
+
+		if job.StatusFile != "" {
+			if st, err := uploader.LoadStatus(job.StatusFile); err == nil {
+				up, err := CreateUploaderWithAWS(&conf)
+				if err != nil {
+					return err
+				}
+				job.Key = st.Key // 렌더링된 키는 상태 파일에 있다
+				fmt.Printf("🔁 queue: job %s resuming upload %s\n", job.ShortID(), st.UploadID)
+				return up.ResumeUploadContext(ctx, job.StatusFile)
+			}
+			// 상태 파일이 없다 — 업로드가 끝났거나 정리됐으니 처음부터 올린다
+		}
+
+		var runID string
+		if job.KeyTemplate != "" {
+			tmpl, err := keytemplate.Parse(job.KeyTemplate)
+			if err != nil {
+				return err
+			}
+			if tmpl.Uses("runId") {
+				runID = uuid.NewString()
+			}
+			key, err := tmpl.Render(keytemplate.Vars{Path: job.FilePath, RelDir: job.RelDir, RunID: runID})
+			if err != nil {
+				return err
+			}
+			job.Key, conf.Key = key, key
+			fmt.Printf("🏷  queue: job %s key %s\n", job.ShortID(), key)
+		}
+
+		up, err := CreateUploaderWithAWS(&conf)
+		if err != nil {
+			return err
+		}
+		up.RunID = runID
+		up.Scheduler = sched
+		up.OnStart = func(uploadID, statusFile string) {
+			_, err := store.Update(job.ID, func(cur *queue.Job) error {
+				cur.Key = job.Key
+				cur.UploadID, cur.StatusFile = uploadID, statusFile
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warn: queue: save upload of job %s: %v\n", job.ShortID(), err)
+			}
+		}
+		return up.UploadFileContext(ctx, job.FilePath, job.Key)
+	}
+}
+
+// startQueueDaemon runs the queue daemon in the background until ctx ends.
+// The returned channel is closed once the daemon has stopped its jobs.
+func startQueueDaemon(ctx context.Context, maxJobs int) (<-chan struct{}, error) {
+	store, err := queue.NewStore("")
+	if err != nil {
+		return nil, err
+	}
+	d := queue.NewDaemon(store, newQueueRunner(store), maxJobs)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		errc <- d.Start(ctx)
+	}()
+
+	// lock 실패 등 즉시 반환되는 에러만 잡아낸다
+	select {
+	case err := <-errc:
+		if err != nil {
+			return nil, err
+		}
+		return done, nil
+	case <-time.After(200 * time.Millisecond):
+		return done, nil
+	}
+}
+
+func runQueueDaemon(_ *cobra.Command, _ []string) error {
+	store, err := queue.NewStore("")
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// 첫 신호는 실행 중인 업로드를 멈추고 정리될 때까지 기다린다 — 두 번째 Ctrl+C 는 바로 종료
+		<-ctx.Done()
+		stop()
+	}()
+
+	fmt.Printf("🗂  Queue daemon started (dir %s, max jobs %d). Press Ctrl+C to stop.\n", store.Dir, queueMaxJobs)
+	d := queue.NewDaemon(store, newQueueRunner(store), queueMaxJobs)
+	if err := d.Start(ctx); err != nil {
+		return err
+	}
+	fmt.Println("👋 Queue daemon stopped; interrupted jobs will resume on next start.")
+	return nil
+}
+
+func init() {
+	queueAddCmd.Flags().StringVarP(&queueFile, "file", "f", "", "Path to the local file to upload, or a directory to queue all its files (required)")
+	queueAddCmd.Flags().StringVarP(&queueBucket, "bucket", "b", "", "Target S3 bucket name (overrides config/ENV)")
+	queueAddCmd.Flags().StringVarP(&queueKey, "key", "k", "", "S3 object key (default: file name)")
+	queueAddCmd.Flags().StringVar(&queueKeyTemplate, "key-template", "", "Build each key from a template when the job runs, e.g. raw/{yyyy}/{mm}/{dd}/{reldir}/{basename}")
+	queueAddCmd.Flags().IntVarP(&queuePriority, "priority", "p", 0, "Job priority (higher runs first)")
+	queueAddCmd.Flags().StringVar(&queueCompress, "compress", "", "Compress while uploading: gzip, zstd, or false (bare --compress means gzip)")
+	queueAddCmd.Flags().Lookup("compress").NoOptDefVal = config.CompressGzip
+	_ = queueAddCmd.MarkFlagRequired("file")
+
+	queueListCmd.Flags().BoolVar(&queueListPending, "pending", false, "Only show pending jobs")
+	queuePauseCmd.Flags().BoolVar(&queueAll, "all", false, "Pause the whole queue instead of single jobs")
+	queueRunCmd.Flags().IntVar(&queueMaxJobs, "max-jobs", 1, "Maximum number of jobs running at once")
+
+	queueCmd.AddCommand(queueAddCmd, queueListCmd, queueCancelCmd, queueRetryCmd, queuePauseCmd, queueResumeCmd, queueRunCmd)
+	rootCmd.AddCommand(queueCmd)
+}
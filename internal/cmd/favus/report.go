@@ -0,0 +1,192 @@
+package favus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/report"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportPath    string // upload/resume --report
+	reportShowTop int
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Inspect upload performance reports (upload/resume --report)",
+}
+
+var reportShowCmd = &cobra.Command{
+	Use:   "show <report.json>",
+	Short: "Summarize a report: totals, part percentiles and the slowest parts",
+	Example: `
+  favus upload -f ./db.dump -k backups/db.dump --report db-upload.json
+  favus report show db-upload.json
+  favus report show db-upload.json --top 20 -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportShow,
+}
+
+// startReport makes up record the next upload when --report is given.
+func startReport(up *uploader.Uploader, command string, conf *config.Config) *report.Recorder {
+	if reportPath == "" {
+		return nil
+	}
+	up.Report = report.New(command, conf)
+	return up.Report
+}
+
+// finishReport writes the report of a session that ended with err. A report
+// that cannot be written does not fail the upload.
+func finishReport(rec *report.Recorder, err error) {
+	if rec == nil {
+		return
+	}
+	if werr := report.Write(reportPath, rec.Finish(err)); werr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", werr)
+		return
+	}
+	fmt.Printf("📊 Report written to %s (favus report show %s)\n", reportPath, reportPath)
+}
+
+func runReportShow(_ *cobra.Command, args []string) error {
+	rep, err := report.Load(args[0])
+	if err != nil {
+		return err
+	}
+	s := report.Summarize(rep, reportShowTop)
+	return printOutput(s, func(tw *tabwriter.Writer) { printReportTable(tw, &s) })
+}
+
+func printReportTable(tw *tabwriter.Writer, s *report.Summary) {
+	ss := s.Session
+	result := "success"
+	switch {
+	case ss.Skipped != "":
+		result = "skipped: " + ss.Skipped
+	case !ss.Success:
+		result = "failed: " + ss.Error
+	}
+	tableRow(tw, "Session", fmt.Sprintf("%s s3://%s/%s", ss.Command, ss.Bucket, ss.Key))
+	tableRow(tw, "File", ss.File)
+	tableRow(tw, "Upload ID", ss.UploadID)
+	tableRow(tw, "Result", result)
+	tableRow(tw, "Started", ss.StartedAt.Local().Format(time.RFC3339))
+	tableRow(tw, "Duration", seconds(ss.DurationSeconds))
+	t := ss.Totals
+	sent := fmt.Sprintf("%s (file %s)", reportBytes(float64(t.SentBytes)), reportBytes(float64(t.FileBytes)))
+	if t.SourceBytes != t.FileBytes {
+		sent += fmt.Sprintf(", these parts cover %s of it", reportBytes(float64(t.SourceBytes)))
+	}
+	tableRow(tw, "Sent", sent)
+	tableRow(tw, "Throughput", reportBytes(t.ThroughputBps)+"/s")
+	parts := fmt.Sprintf("%d uploaded, %d failed, %d retries, %d worker(s)", t.Parts, t.PartsFailed, t.Retries, t.Workers)
+	if t.PartsSkipped > 0 {
+		parts += fmt.Sprintf(", %d already on S3", t.PartsSkipped)
+	}
+	tableRow(tw, "Parts", parts)
+	if c := ss.Compression; c != nil {
+		if c.Codec == "" {
+			tableRow(tw, "Compression", "skipped: "+c.Reason)
+		} else {
+			tableRow(tw, "Compression", fmt.Sprintf("%s, ratio %.3f (%s → %s), %s",
+				c.Codec, c.Ratio, reportBytes(float64(c.OriginalBytes)), reportBytes(float64(c.CompressedBytes)), c.Reason))
+		}
+	}
+	if d := ss.Duplicate; d != nil {
+		v := "upload"
+		if !d.Upload {
+			v = "skip"
+		}
+		v += " (" + d.Reason + ")"
+		if d.Error != "" {
+			v += ", error: " + d.Error
+		}
+		tableRow(tw, "Duplicate check", v)
+	}
+	cfg := ss.Config
+	settings := fmt.Sprintf("region=%s partSizeMB=%d maxConcurrency=%d", cfg.Region, cfg.PartSizeMB, cfg.MaxConcurrency)
+	if cfg.Compress {
+		settings += fmt.Sprintf(" compress=%s", cfg.CompressFormat)
+	}
+	tableRow(tw, "Config", settings)
+
+	// 표마다 열 너비를 따로 맞춘다
+	_ = tw.Flush()
+	fmt.Fprintln(tw)
+	tableRow(tw, "PER PART", "P50", "P90", "P95", "P99", "MIN", "MAX")
+	d, b := s.PartSeconds, s.PartThroughput
+	tableRow(tw, "duration", seconds(d.P50), seconds(d.P90), seconds(d.P95), seconds(d.P99), seconds(d.Min), seconds(d.Max))
+	tableRow(tw, "throughput/s", reportBytes(b.P50), reportBytes(b.P90), reportBytes(b.P95), reportBytes(b.P99), reportBytes(b.Min), reportBytes(b.Max))
+
+	if len(s.Slowest) > 0 {
+		_ = tw.Flush()
+		fmt.Fprintln(tw)
+		tableRow(tw, "SLOWEST PART", "WORKER", "DURATION", "ATTEMPTS", "BYTES", "THROUGHPUT", "ERRORS")
+		for _, p := range s.Slowest {
+			errs := ""
+			if n := len(p.Errors); n > 0 {
+				// 전체 메시지는 JSON 에 있다 — 표에는 마지막 것만 짧게
+				errs = shorten(p.Errors[n-1], 100)
+				if n > 1 {
+					errs = fmt.Sprintf("%d errors, last: %s", n, errs)
+				}
+			} else if !p.Success {
+				errs = "unfinished"
+			}
+			tput := "-"
+			if p.Success {
+				tput = reportBytes(p.ThroughputBps) + "/s"
+			}
+			tableRow(tw, p.Number, p.Worker, seconds(p.DurationSeconds), p.Attempts,
+				reportBytes(float64(p.Bytes)), tput, errs)
+		}
+	}
+
+	if len(s.Workers) > 0 {
+		_ = tw.Flush()
+		fmt.Fprintln(tw)
+		tableRow(tw, "WORKER", "PARTS", "BYTES", "BUSY")
+		for _, w := range s.Workers {
+			tableRow(tw, w.Worker, w.Parts, reportBytes(float64(w.Bytes)), seconds(w.BusySeconds))
+		}
+	}
+}
+
+func shorten(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func seconds(v float64) string {
+	return time.Duration(v * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+func reportBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", b), ".0") + " " + units[i]
+}
+
+func init() {
+	reportShowCmd.Flags().IntVar(&reportShowTop, "top", 5, "Number of slowest parts to list")
+	addOutputFlag(reportShowCmd)
+	reportCmd.AddCommand(reportShowCmd)
+	rootCmd.AddCommand(reportCmd)
+}
@@ -1,6 +1,7 @@
 package favus
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/GoCOMA/Favus/internal/uploader"
@@ -9,10 +10,11 @@ import (
 
 // Flags
 var (
-	resumeFilePath string // status file path generated during an interrupted upload
-	resumeBucket   string
-	resumeKey      string
-	uploadID       string
+	resumeFilePath  string // status file path generated during an interrupted upload
+	resumeBucket    string
+	resumeKey       string
+	uploadID        string
+	resumeBreakLock bool
 )
 
 var resumeCmd = &cobra.Command{
@@ -43,6 +45,9 @@ func runResume(_ *cobra.Command, _ []string) error {
 	bucketName := resumeBucket
 	if bucketName == "" {
 		bucketName = status.Bucket
+		if status.Location != "" {
+			bucketName = status.Location // file:// / mem:// backend recorded by upload
+		}
 	}
 	keyName := resumeKey
 	if keyName == "" {
@@ -76,12 +81,29 @@ func runResume(_ *cobra.Command, _ []string) error {
 	}
 
 	// Create uploader and resume upload
-	up, err := CreateUploaderWithAWS(conf)
+	// Fan-out destinations may live in another region (recorded in the status file)
+	up, err := createUploaderInRegion(conf, status.Region)
 	if err != nil {
 		return err
 	}
 
-	if err := up.ResumeUpload(resumeFilePath); err != nil {
+	if dryRun {
+		plan, err := up.PlanResume(resumeFilePath)
+		if err != nil {
+			return fmt.Errorf("plan resume: %w", err)
+		}
+		return printPlan(plan, func() { printUploadPlan(plan) })
+	}
+
+	up.BreakLock = resumeBreakLock
+	rec := startReport(up, "resume", conf)
+	err = up.ResumeUpload(resumeFilePath)
+	finishReport(rec, err)
+	var locked *uploader.LockedError
+	if errors.As(err, &locked) {
+		return fmt.Errorf("resume failed: %w\n   wait for that process to finish, or use --break-lock if it is gone", err)
+	}
+	if err != nil {
 		return fmt.Errorf("resume failed: %w", err)
 	}
 
@@ -96,6 +118,8 @@ func init() {
 	resumeCmd.Flags().StringVarP(&resumeBucket, "bucket", "b", "", "S3 bucket name (overrides config/ENV)")
 	resumeCmd.Flags().StringVarP(&resumeKey, "key", "k", "", "S3 object key (overrides config/ENV)")
 	resumeCmd.Flags().StringVarP(&uploadID, "upload-id", "u", "", "Upload ID (overrides config/ENV)")
+	resumeCmd.Flags().BoolVar(&resumeBreakLock, "break-lock", false, "Resume even if the status file is locked by a process that still looks alive")
+	resumeCmd.Flags().StringVar(&reportPath, "report", "", "Write a per-part performance report (JSON) to this file; see favus report show")
 
 	_ = resumeCmd.MarkFlagRequired("file")
 }
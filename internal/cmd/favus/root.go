@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/faults"
 	"github.com/spf13/cobra"
 )
 
@@ -38,10 +39,18 @@ const (
 	CmdVersion     CommandType = "version"
 	CmdHelp        CommandType = "help"
 	CmdCompletion  CommandType = "completion"
+	CmdMockS3      CommandType = "mock-s3"
+	CmdDownload    CommandType = "download"
+	CmdCat         CommandType = "cat"
+	CmdExtract     CommandType = "extract"
+
+	CmdLsObjects      CommandType = "ls-objects"
+	CmdDuplicateStats CommandType = "duplicate-stats"
+	CmdBench          CommandType = "bench"
 )
 
 func shouldSkipConfigLoading(cmdName string) bool {
-	skipCommands := []string{string(CmdVersion), string(CmdHelp), string(CmdCompletion)}
+	skipCommands := []string{string(CmdVersion), string(CmdHelp), string(CmdCompletion), string(CmdMockS3)}
 	for _, skip := range skipCommands {
 		if cmdName == skip {
 			return true
@@ -50,6 +59,18 @@ func shouldSkipConfigLoading(cmdName string) bool {
 	return false
 }
 
+// writesDataToStdout reports whether cmd streams object data (or a listing
+// meant for pipes) to stdout.
+func writesDataToStdout(cmd *cobra.Command) bool {
+	switch CommandType(cmd.Name()) {
+	case CmdCat:
+		return true
+	case CmdExtract:
+		return extractList || extractOutput == "-"
+	}
+	return false
+}
+
 func loadConfigFromFile(cfgPath string) (*config.Config, error) {
 	if cfgPath == "" {
 		return config.LoadConfig("")
@@ -67,9 +88,7 @@ func applyCommandSpecificOverrides(cmd *cobra.Command, cfg *config.Config) {
 		if objectKey != "" {
 			cfg.Key = objectKey
 		}
-		if cmd.Flags().Changed("compress") {
-			cfg.Compress = uploadCompress
-		}
+		_ = applyCompressFlags(cmd, cfg) // 잘못된 값은 runUpload 에서 에러로 보고
 	case CmdResume:
 		if resumeBucket != "" {
 			cfg.Bucket = resumeBucket
@@ -93,7 +112,11 @@ func applyCommandSpecificOverrides(cmd *cobra.Command, cfg *config.Config) {
 func requiresInteractiveConfig(cmdName string, cfg *config.Config) bool {
 	switch CommandType(cmdName) {
 	case CmdUpload:
-		return cfg.Bucket == "" || cfg.Key == ""
+		if len(uploadDests) > 0 {
+			return false // fan-out: bucket/key come from --dest
+		}
+		hasKey := cfg.Key != "" || uploadKeyTemplate != "" || cfg.KeyTemplate != ""
+		return cfg.Bucket == "" || !hasKey
 	case CmdLsOrphans:
 		return cfg.Bucket == "" || cfg.Region == ""
 	case CmdDelete:
@@ -172,7 +195,19 @@ Use 'favus --help' to see available commands.
 
 func setupConfigForCommand(cmd *cobra.Command, _ []string) error {
 	if debug {
-		fmt.Println("[Favus] Debug mode enabled")
+		fmt.Fprintln(os.Stderr, "[Favus] Debug mode enabled") // stdout 은 -o json 등의 출력 전용
+	}
+
+	if err := checkDryRunSupport(cmd); err != nil {
+		return err
+	}
+	if writesStructuredOutput(cmd) {
+		if err := checkOutputFormat(); err != nil {
+			return err
+		}
+	}
+	if presignWritesJSON(cmd) || writesDataToStdout(cmd) || writesStructuredOutput(cmd) {
+		reserveStdout()
 	}
 
 	// Skip config loading for informational commands
@@ -180,12 +215,21 @@ func setupConfigForCommand(cmd *cobra.Command, _ []string) error {
 		return nil
 	}
 
+	// Fail fast on a broken FAVUS_FAULTS spec instead of silently running without faults
+	if _, err := faults.Active(); err != nil {
+		return err
+	}
+
 	// Load config from file if specified, otherwise from ENV
 	cfg, err := loadConfigFromFile(cfgPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] effective config: %+v\n", *cfg)
+	}
+
 	// Apply command-specific flag overrides
 	applyCommandSpecificOverrides(cmd, cfg)
 
@@ -201,12 +245,19 @@ func setupConfigForCommand(cmd *cobra.Command, _ []string) error {
 		loadedConfig = cfg
 	}
 
-	return nil
+	if err := startTracing(cfg); err != nil {
+		return err
+	}
+	// notify.runIn: cli → 이 프로세스의 업로드 이벤트로 웹훅을 보낸다
+	return startNotifier(cfg)
 }
 
 // Execute runs the root command and handles any errors
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	closeNotifier() // 실패한 실행의 알림도 보내고 끝낸다
+	stopTracing()
+	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
 		os.Exit(1)
 	}
@@ -217,6 +268,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgPath, "config", "c", "", "Path to config file (YAML). If omitted, ENV is used and may fall back to prompts.")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
 	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "AWS named profile to use")
+	rootCmd.PersistentFlags().StringVar(&traceDest, "trace", "", "Export OTLP/JSON spans to stdout, stderr, a file or an OTLP/HTTP URL (…/v1/traces)")
 
 	// version
 	rootCmd.AddCommand(&cobra.Command{
@@ -0,0 +1,38 @@
+package favus
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/tracing"
+)
+
+// traceDest 는 --trace 값 (없으면 FAVUS_TRACE, 그다음 config 의 trace:)
+var traceDest string
+
+// startTracing enables OTLP/JSON span export when --trace, FAVUS_TRACE or
+// trace: in the config names a destination.
+func startTracing(conf *config.Config) error {
+	dest := traceDest
+	if dest == "" {
+		dest = os.Getenv("FAVUS_TRACE")
+	}
+	if dest == "" {
+		dest = conf.Trace
+	}
+	if dest == "" {
+		return nil
+	}
+	if err := tracing.Enable(dest, "favus", version); err != nil {
+		return fmt.Errorf("--trace: %w", err)
+	}
+	return nil
+}
+
+// stopTracing exports spans that are still pending before the process exits.
+func stopTracing() {
+	if err := tracing.Shutdown(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "⚠️  trace export failed: %v\n", err)
+	}
+}
@@ -15,6 +15,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/GoCOMA/Favus/internal/awsutils"
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/directupload"
+	"github.com/GoCOMA/Favus/internal/notify"
+	"github.com/GoCOMA/Favus/internal/storage"
 	"github.com/GoCOMA/Favus/internal/wsagent"
 	"github.com/spf13/cobra"
 
@@ -32,6 +37,14 @@ var (
 
 	uiForeground bool
 
+	uiQueue     bool // 에이전트와 함께 업로드 큐 데몬 실행
+	uiQueueJobs int
+
+	uiUploadsAPI    bool     // 브라우저 → S3 직접 업로드 API (/api/uploads)
+	uiUploadsBucket string   // 요청에 bucket 이 없을 때 사용할 기본 버킷
+	uiUploadsAllow  []string // 기본 버킷 외에 요청이 지정할 수 있는 버킷
+	uiCORSOrigins   []string // /api/uploads 를 호출할 수 있는 Origin
+
 	// favus stop-ui 플래그
 	stopAddrFlag string
 )
@@ -45,6 +58,7 @@ func init() {
 - accepts HTTP events at /event from Favus commands
 - forwards them to the upstream WebSocket server (WSS/WS)
 - exposes /healthz and /shutdown for control
+- serves /api/uploads so the Web UI can upload straight to S3 with presigned part URLs
 
 Run this once (foreground or background), then use other favus commands (upload/resume) in the same terminal.`,
 		RunE: runUI,
@@ -56,6 +70,12 @@ Run this once (foreground or background), then use other favus commands (upload/
 	uiCmd.Flags().BoolVar(&uiOpenBrowser, "open", false, "Open the Web UI in your browser after connecting")
 	uiCmd.Flags().StringVar(&uiOpenURL, "open-url", "", "Explicit URL to open (overrides auto-derived one)")
 	uiCmd.Flags().BoolVar(&uiForeground, "foreground", false, "Run in foreground (block until Ctrl+C)")
+	uiCmd.Flags().BoolVar(&uiQueue, "queue", false, "Also run the upload queue daemon (see 'favus queue')")
+	uiCmd.Flags().IntVar(&uiQueueJobs, "queue-jobs", 1, "Maximum number of queued jobs running at once")
+	uiCmd.Flags().BoolVar(&uiUploadsAPI, "uploads-api", true, "Serve the browser direct-to-S3 upload API at /api/uploads")
+	uiCmd.Flags().StringVar(&uiUploadsBucket, "uploads-bucket", "", "Default bucket for browser uploads (default: config/ENV bucket)")
+	uiCmd.Flags().StringSliceVar(&uiUploadsAllow, "uploads-allow-bucket", nil, "Other buckets browser uploads may name (the default bucket is always allowed)")
+	uiCmd.Flags().StringSliceVar(&uiCORSOrigins, "cors-origin", []string{"http://localhost:3000", "http://127.0.0.1:3000"}, "Origins allowed to call /api/uploads (\"*\" for any)")
 
 	rootCmd.AddCommand(uiCmd)
 
@@ -119,6 +139,31 @@ func runUI(cmd *cobra.Command, args []string) error {
 		if uiOpenURL != "" {
 			args = append(args, "--open-url", uiOpenURL)
 		}
+		if uiQueue {
+			args = append(args, "--queue")
+		}
+		args = append(args, "--queue-jobs", fmt.Sprint(uiQueueJobs))
+		if !uiUploadsAPI {
+			args = append(args, "--uploads-api=false")
+		}
+		if uiUploadsBucket != "" {
+			args = append(args, "--uploads-bucket", uiUploadsBucket)
+		}
+		if len(uiUploadsAllow) > 0 {
+			args = append(args, "--uploads-allow-bucket", strings.Join(uiUploadsAllow, ","))
+		}
+		if cmd.Flags().Changed("cors-origin") {
+			args = append(args, "--cors-origin", strings.Join(uiCORSOrigins, ","))
+		}
+		if traceDest != "" {
+			args = append(args, "--trace", traceDest)
+		}
+		if cfgPath != "" {
+			// notify.runIn: agent 등 설정 파일을 쓰는 기능을 위해 그대로 넘긴다
+			if abs, err := filepath.Abs(cfgPath); err == nil {
+				args = append(args, "--config", abs)
+			}
+		}
 
 		// 로그 파일로 리디렉션
 		logDir := filepath.Join(os.Getenv("HOME"), ".favus")
@@ -188,11 +233,60 @@ func runUI(cmd *cobra.Command, args []string) error {
 		APIKey:     uiAPIKey,
 	}
 
+	// 3-0) 브라우저 직접 업로드 API — 이벤트는 에이전트가 뜬 뒤 같은 WS 로 전달.
+	// 핸들러는 에이전트보다 먼저 만들어야 하므로, 그 사이의 요청은 agReady 를 기다린다
+	var (
+		ag      *wsagent.Agent
+		agReady = make(chan struct{})
+	)
+	if uiUploadsAPI {
+		api, err := newUploadsAPI(func(ev wsagent.Event) error {
+			<-agReady
+			if ag == nil {
+				return nil
+			}
+			return ag.Publish(ev)
+		})
+		if err != nil {
+			return fmt.Errorf("uploads API: %w", err)
+		}
+		cfg.Handlers = map[string]http.Handler{"/api/uploads": api, "/api/uploads/": api}
+	}
+
+	// notify.runIn: agent → 에이전트를 지나는 이벤트로 웹훅을 보낸다
+	if conf := GetLoadedConfig(); conf != nil && conf.Notify.RunIn == config.NotifyInAgent {
+		n, err := notify.New(conf.Notify)
+		if err != nil {
+			return err
+		}
+		if n != nil {
+			cfg.OnEvent = n.Handle
+			defer n.Close(context.Background())
+			fmt.Printf("🔔 Webhook notifications: %d webhook(s)\n", len(conf.Notify.Webhooks))
+		}
+	}
+
 	ag, err := wsagent.Start(cfg)
+	close(agReady)
 	if err != nil {
 		return fmt.Errorf("failed to start UI agent: %w", err)
 	}
 	fmt.Printf("✅ UI agent started: http://%s  → %s\n", cfg.Addr, cfg.WSEndpoint)
+	if uiUploadsAPI {
+		fmt.Printf("📤 Browser upload API: http://%s/api/uploads (origins: %s)\n", cfg.Addr, strings.Join(uiCORSOrigins, ", "))
+	}
+
+	// 3-0) 업로드 큐 데몬 (에이전트와 같은 프로세스에서 실행)
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	defer stopQueue()
+	var queueDone <-chan struct{}
+	if uiQueue {
+		if queueDone, err = startQueueDaemon(queueCtx, uiQueueJobs); err != nil {
+			fmt.Fprintf(os.Stderr, "warn: queue daemon not started: %v\n", err)
+		} else {
+			fmt.Printf("🗂  Queue daemon running (max jobs %d)\n", uiQueueJobs)
+		}
+	}
 
 	// 3-a) Next.js 프론트엔드(dev 서버) 실행
 	frontendDir := filepath.Join("internal", "web", "ui")
@@ -253,6 +347,14 @@ func runUI(cmd *cobra.Command, args []string) error {
 	fmt.Println("🔌 Press Ctrl+C to stop (or run `favus stop-ui`).")
 	<-sigc
 
+	stopQueue()
+	if queueDone != nil {
+		// 실행 중인 작업이 pending 으로 돌아갈 때까지 잠깐 기다린다 (못 기다려도 다음 시작 때 복구된다)
+		select {
+		case <-queueDone:
+		case <-time.After(10 * time.Second):
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	_ = ag.Stop(ctx)
@@ -326,3 +428,38 @@ func openBrowserSafe(u string) error {
 	fmt.Printf("🌐 Opening UI: %s\n", u)
 	return nil
 }
+
+// newUploadsAPI builds the /api/uploads handler. AWS 인증 정보는 시작할 때 한 번,
+// 프롬프트 없이 로드한다 — 핸들러 안에서 터미널 입력을 기다리면 안 된다.
+func newUploadsAPI(emit func(wsagent.Event) error) (*directupload.Handler, error) {
+	awsCfg, awsErr := awsutils.LoadAWSConfigNoPrompt(profile)
+	if awsErr != nil {
+		fmt.Fprintf(os.Stderr, "warn: browser uploads to S3 will fail: %v\n", awsErr)
+	}
+	cfg := directupload.Config{
+		DefaultBucket:  uiUploadsBucket,
+		AllowedBuckets: uiUploadsAllow,
+		AllowedOrigins: uiCORSOrigins,
+		Emit:           emit,
+		OpenStore: func(bucket string) (storage.ObjectStore, error) {
+			loc, err := storage.ParseLocation(bucket)
+			if err != nil {
+				return nil, err
+			}
+			if loc.IsLocal() {
+				return nil, fmt.Errorf("%s is not an S3 bucket; browser uploads need presigned S3 URLs", loc)
+			}
+			if awsErr != nil {
+				return nil, awsErr
+			}
+			return storage.NewS3Store(awsCfg), nil
+		},
+	}
+	if conf := GetLoadedConfig(); conf != nil {
+		if cfg.DefaultBucket == "" {
+			cfg.DefaultBucket = conf.Bucket
+		}
+		cfg.PartSizeBytes = int64(conf.PartSizeMB) * 1024 * 1024
+	}
+	return directupload.New(cfg)
+}
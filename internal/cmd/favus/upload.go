@@ -2,16 +2,27 @@ package favus
 
 import (
 	"fmt"
+	"path/filepath"
 
+	"github.com/GoCOMA/Favus/internal/archive"
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/keytemplate"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
 // CLI flags
 var (
-	filePath       string
-	bucket         string
-	objectKey      string
-	uploadCompress bool
+	filePath             string
+	bucket               string
+	objectKey            string
+	uploadCompress       string
+	uploadCompressLevel  int
+	uploadCompressAlways bool
+	uploadDests          []string
+	uploadArchiveDir     string
+	uploadKeyTemplate    string
 )
 
 var uploadCmd = &cobra.Command{
@@ -21,7 +32,13 @@ var uploadCmd = &cobra.Command{
 Handles chunking, retries, resume support, and progress visualization automatically.`,
 	Example: `
   favus upload --file ./bigfile.mp4 --bucket my-bucket --key uploads/bigfile.mp4
-  favus upload -f ./bigfile.mp4 -c config.yaml`,
+  favus upload -f ./bigfile.mp4 -c config.yaml
+  favus upload -f ./bigfile.mp4 --key uploads/bigfile.mp4 --compress --dry-run --plan-format json
+  favus upload -f ./db.dump --key backups/db.dump --compress=zstd --compress-level 9
+  favus upload --archive ./photos --key snapshots/photos-2024.tar.zst
+  favus upload -f ./app.log --key-template 'raw/{yyyy}/{mm}/{dd}/{hostname}/{basename}'
+  favus upload -f ./db.dump --key backups/db.dump --report db-upload.json
+  favus upload -f ./release.tar --dest s3://artifacts-kr/v1/release.tar --dest s3://artifacts-us/v1/release.tar?region=us-east-1`,
 	RunE: runUpload,
 }
 
@@ -32,9 +49,57 @@ func runUpload(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	// Prompt for missing required fields
-	validator := NewConfigValidator(conf).RequireBucket().RequireKey()
-	PromptForMissingConfig(validator)
+	switch {
+	case filePath == "" && uploadArchiveDir == "":
+		return fmt.Errorf("--file or --archive is required")
+	case filePath != "" && uploadArchiveDir != "":
+		return fmt.Errorf("--file and --archive cannot be combined")
+	case uploadArchiveDir != "" && len(uploadDests) > 0:
+		return fmt.Errorf("--archive cannot be combined with --dest")
+	case reportPath != "" && (uploadArchiveDir != "" || len(uploadDests) > 0):
+		return fmt.Errorf("--report is only supported for single-file uploads (not with --archive or --dest)")
+	}
+
+	// --key-template / keyTemplate → conf.Key, checked before any prompt
+	tmpl, err := resolveKeyTemplate(uploadKeyTemplate, objectKey, conf)
+	if err != nil {
+		return err
+	}
+	if tmpl != nil {
+		switch {
+		case len(uploadDests) > 0 && uploadKeyTemplate != "":
+			return fmt.Errorf("--key-template cannot be combined with --dest")
+		case len(uploadDests) > 0:
+			tmpl = nil // 각 --dest 가 키를 정한다
+		case uploadArchiveDir != "" && tmpl.Uses("sha256"):
+			return fmt.Errorf("{sha256} is not available for --archive uploads (the tar stream is built while uploading)")
+		}
+	}
+	var runID string
+	if tmpl != nil {
+		src := filePath
+		if uploadArchiveDir != "" {
+			src = filepath.Clean(uploadArchiveDir)
+		}
+		if tmpl.Uses("runId") {
+			runID = uuid.NewString()
+		}
+		if conf.Key, err = tmpl.Render(keytemplate.Vars{Path: src, RunID: runID}); err != nil {
+			return err
+		}
+		fmt.Printf("🏷  Key from template %s: %s\n", tmpl, conf.Key)
+	}
+
+	// Fan-out: bucket/key come from each --dest instead
+	if len(uploadDests) > 0 {
+		if bucket != "" || objectKey != "" {
+			return fmt.Errorf("--dest cannot be combined with --bucket/--key")
+		}
+	} else {
+		// Prompt for missing required fields
+		validator := NewConfigValidator(conf).RequireBucket().RequireKey()
+		PromptForMissingConfig(validator)
+	}
 
 	// Prompt for upload parameters with proper defaults
 	defaultPartSize := conf.PartSizeMB
@@ -51,37 +116,145 @@ func runUpload(cmd *cobra.Command, _ []string) error {
 	conf.MaxConcurrency = PromptIntWithValidation("🔁 Enter max concurrency", defaultConcurrency, MinConcurrency)
 
 	// Compression prompt (unless explicitly set via flag)
-	if cmd.Flags().Changed("compress") {
-		conf.Compress = uploadCompress
-	} else {
+	if err := applyCompressFlags(cmd, conf); err != nil {
+		return err
+	}
+	if uploadArchiveDir != "" {
+		return runArchiveUpload(conf, tmpl, runID)
+	}
+	if !cmd.Flags().Changed("compress") {
 		conf.Compress = PromptYesNoDefault("🗜  압축해서 업로드할까요?", conf.Compress)
 	}
+	if conf.Compress {
+		if err := conf.ValidateCompression(); err != nil {
+			return err
+		}
+	}
 
 	// Validate local file
 	if err := ValidateFile(filePath); err != nil {
 		return err
 	}
 
+	if len(uploadDests) > 0 {
+		return runFanOut(conf, uploadDests)
+	}
+
 	// Create uploader and perform upload
 	up, err := CreateUploaderWithAWS(conf)
 	if err != nil {
 		return err
 	}
+	up.RunID = runID
 
-	if err := up.UploadFile(filePath, conf.Key); err != nil {
+	if dryRun {
+		plan, err := up.PlanUpload(filePath, conf.Key)
+		if err != nil {
+			return fmt.Errorf("plan upload: %w", err)
+		}
+		noteKeyTemplate(plan, tmpl)
+		return printPlan(plan, func() { printUploadPlan(plan) })
+	}
+
+	rec := startReport(up, "upload", conf)
+	err = up.UploadFile(filePath, conf.Key)
+	finishReport(rec, err)
+	if err != nil {
 		return fmt.Errorf("upload failed: %w", err)
 	}
 
-	fmt.Println(FormatSuccessMessage("Upload complete", conf.Bucket, conf.Key))
+	fmt.Println(FormatSuccessMessage("Upload complete", up.LocationURL(), conf.Key))
+	return nil
+}
+
+// runArchiveUpload uploads --archive as one tar object. The key's extension
+// (.tar, .tar.gz/.tgz, .tar.zst/.tzst) picks the compression; a key without
+// one follows --compress and gets the matching extension.
+func runArchiveUpload(conf *config.Config, tmpl *keytemplate.Template, runID string) error {
+	compression, ok := archive.CompressionForKey(conf.Key)
+	if !ok {
+		if conf.Compress {
+			compression = conf.CompressionFormat()
+		}
+		conf.Key += archive.Ext(compression)
+		fmt.Printf("ℹ️  Archive key: %s\n", conf.Key)
+	}
+	if compression != "" {
+		conf.CompressFormat = compression
+		if err := conf.ValidateCompression(); err != nil {
+			return err
+		}
+	}
+
+	up, err := CreateUploaderWithAWS(conf)
+	if err != nil {
+		return err
+	}
+	up.RunID = runID
+
+	if dryRun {
+		plan, err := up.PlanArchive(uploadArchiveDir, conf.Key, compression)
+		if err != nil {
+			return fmt.Errorf("plan archive upload: %w", err)
+		}
+		noteKeyTemplate(plan, tmpl)
+		return printPlan(plan, func() { printUploadPlan(plan) })
+	}
+
+	if err := up.UploadArchive(uploadArchiveDir, conf.Key, compression); err != nil {
+		return fmt.Errorf("archive upload failed: %w", err)
+	}
+	fmt.Println()
+	fmt.Println(FormatSuccessMessage("Archive upload complete", up.LocationURL(), conf.Key))
+	fmt.Printf("📇 Index: %s/%s\n", up.LocationURL(), archive.IndexKey(conf.Key))
+	return nil
+}
+
+// noteKeyTemplate records in the plan which template produced its key.
+func noteKeyTemplate(plan *uploader.UploadPlan, tmpl *keytemplate.Template) {
+	if tmpl == nil {
+		return
+	}
+	plan.Notes = append(plan.Notes, fmt.Sprintf("key rendered from template %s", tmpl))
+	if tmpl.Uses("runId") {
+		plan.Notes = append(plan.Notes, "{runId} is new on every run, so the real upload gets a different key")
+	}
+}
+
+// applyCompressFlags copies --compress, --compress-level and --compress-always
+// into conf when they were given.
+func applyCompressFlags(cmd *cobra.Command, conf *config.Config) error {
+	flags := cmd.Flags()
+	if flags.Changed("compress") {
+		enabled, format, err := config.ParseCompress(uploadCompress)
+		if err != nil {
+			return err
+		}
+		conf.Compress = enabled
+		if format != "" {
+			conf.CompressFormat = format
+		}
+	}
+	if flags.Changed("compress-level") {
+		conf.CompressLevel = uploadCompressLevel
+	}
+	if flags.Changed("compress-always") {
+		conf.CompressAlways = uploadCompressAlways
+	}
 	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(uploadCmd)
-	uploadCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to the local file to upload (required)")
+	uploadCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to the local file to upload (required unless --archive)")
 	uploadCmd.Flags().StringVarP(&bucket, "bucket", "b", "", "Target S3 bucket name (overrides config/ENV)")
 	uploadCmd.Flags().StringVarP(&objectKey, "key", "k", "", "S3 object key (overrides config/ENV)")
-	uploadCmd.Flags().BoolVar(&uploadCompress, "compress", false, "Compress the file with gzip before uploading")
-	uploadCmd.Flags().Lookup("compress").NoOptDefVal = "true"
-	_ = uploadCmd.MarkFlagRequired("file")
+	uploadCmd.Flags().StringVar(&uploadCompress, "compress", "", "Compress while uploading: gzip, zstd, or false (bare --compress means gzip)")
+	uploadCmd.Flags().Lookup("compress").NoOptDefVal = config.CompressGzip
+	uploadCmd.Flags().IntVar(&uploadCompressLevel, "compress-level", 0, "Compression level (gzip 1-9, zstd 1-22; default: format default)")
+	uploadCmd.Flags().BoolVar(&uploadCompressAlways, "compress-always", false, "Compress even if the input looks incompressible (.mp4, .zip, trial ratio)")
+	uploadCmd.Flags().StringArrayVar(&uploadDests, "dest", nil, "Fan-out destination s3://bucket/key[?region=...] (repeatable; replaces --bucket/--key)")
+	uploadCmd.Flags().StringVar(&uploadKeyTemplate, "key-template", "", "Build the key from a template, e.g. raw/{yyyy}/{mm}/{dd}/{hostname}/{basename} (see README)")
+	uploadCmd.Flags().StringVar(&uploadArchiveDir, "archive", "", "Upload this directory as one tar object (.tar, .tar.gz or .tar.zst by key) with an index sidecar")
+	uploadCmd.Flags().StringVar(&reportPath, "report", "", "Write a per-part performance report (JSON) to this file; see favus report show")
 }
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/spf13/viper"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -27,10 +30,130 @@ type Config struct {
 	Region         string `mapstructure:"region"`
 	PartSizeMB     int    `mapstructure:"partSizeMB"`
 	MaxConcurrency int    `mapstructure:"maxConcurrency"`
+	MaxInFlightMB  int    `mapstructure:"maxInFlightMB"` // queue daemon: part bytes in flight across all jobs (0: 2 × maxConcurrency × partSizeMB)
 	Compress       bool   `mapstructure:"compress"`
+	CompressFormat string `mapstructure:"compressFormat"` // gzip (default) | zstd
+	CompressLevel  int    `mapstructure:"compressLevel"`  // 0: format default
+	CompressAlways bool   `mapstructure:"compressAlways"` // skip incompressible-input detection
+	KeyTemplate    string `mapstructure:"keyTemplate"`    // e.g. raw/{yyyy}/{mm}/{dd}/{basename}; see keytemplate
+	Hooks          Hooks  `mapstructure:"hooks"`
+	Notify         Notify `mapstructure:"notify"`
+	Trace          string `mapstructure:"trace"` // stdout | stderr | file path | http(s)://collector:4318/v1/traces
 	UploadID       string
 }
 
+// Notify configures webhook notifications for upload sessions (see package notify).
+type Notify struct {
+	RunIn      string        `mapstructure:"runIn"`      // cli (default) | agent
+	StallAfter time.Duration `mapstructure:"stallAfter"` // no progress for this long → stalled (default 2m)
+	Webhooks   []Webhook     `mapstructure:"webhooks"`
+}
+
+// Webhook is one notification target.
+type Webhook struct {
+	URL     string            `mapstructure:"url"`
+	Events  []string          `mapstructure:"events"`  // session_done, error, stalled (default: session_done, error)
+	Body    string            `mapstructure:"body"`    // Go text/template producing JSON; default: the event itself
+	Secret  string            `mapstructure:"secret"`  // HMAC-SHA256 key for X-Favus-Signature
+	Headers map[string]string `mapstructure:"headers"` // extra request headers
+	Retries int               `mapstructure:"retries"` // attempts after the first (default 3)
+	Timeout time.Duration     `mapstructure:"timeout"` // per request (default 10s)
+}
+
+// String prints the webhook with its secret, header values and URL path
+// masked, so logging a Config (e.g. the debug line of LoadConfig) never leaks
+// credentials. Webhook URLs such as Slack's carry the token in the path.
+func (w Webhook) String() string {
+	target := w.URL
+	if u, err := url.Parse(w.URL); err == nil && u.Host != "" {
+		target = u.Scheme + "://" + u.Host
+		if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+			target += "/***"
+		}
+	} else if w.URL != "" {
+		target = "***"
+	}
+	secret := ""
+	if w.Secret != "" {
+		secret = "***"
+	}
+	headers := make([]string, 0, len(w.Headers))
+	for k := range w.Headers {
+		headers = append(headers, k+":***")
+	}
+	sort.Strings(headers)
+	return fmt.Sprintf("{URL:%s Events:%v Body:%d bytes Secret:%s Headers:[%s] Retries:%d Timeout:%s}",
+		target, w.Events, len(w.Body), secret, strings.Join(headers, " "), w.Retries, w.Timeout)
+}
+
+// Notify.RunIn values.
+const (
+	NotifyInCLI   = "cli"
+	NotifyInAgent = "agent"
+)
+
+// Hook is an external command run at one point of an upload session. It gets
+// the session as JSON on stdin (see package hooks).
+type Hook struct {
+	Command string        `mapstructure:"command"` // run with sh -c (cmd /C on Windows)
+	Timeout time.Duration `mapstructure:"timeout"` // 0: hooks.DefaultTimeout
+}
+
+// Hooks are the commands run around every upload and resume.
+type Hooks struct {
+	PreUpload  Hook `mapstructure:"preUpload"`  // non-zero exit or timeout vetoes the upload
+	PostUpload Hook `mapstructure:"postUpload"` // after the object is complete
+	OnFailure  Hook `mapstructure:"onFailure"`  // after an upload failed (exit code only logged)
+}
+
+// Compression formats for CompressFormat.
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// ParseCompress interprets a --compress / FAVUS_COMPRESS value. true, gzip and
+// zstd enable compression (true means gzip); false, none and off disable it.
+func ParseCompress(v string) (enabled bool, format string, err error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "gzip", "gz":
+		return true, CompressGzip, nil
+	case "zstd", "zst":
+		return true, CompressZstd, nil
+	case "none", "off":
+		return false, "", nil
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return false, "", fmt.Errorf("invalid compression %q (expected gzip, zstd, true or false)", v)
+	}
+	return b, "", nil
+}
+
+// CompressionFormat returns the configured format, gzip when unset.
+func (c *Config) CompressionFormat() string {
+	if c.CompressFormat == "" {
+		return CompressGzip
+	}
+	return c.CompressFormat
+}
+
+// ValidateCompression checks the format and level combination.
+func (c *Config) ValidateCompression() error {
+	lo, hi := 1, 9
+	switch c.CompressionFormat() {
+	case CompressGzip:
+	case CompressZstd:
+		hi = 22
+	default:
+		return fmt.Errorf("unknown compression format %q (expected gzip or zstd)", c.CompressFormat)
+	}
+	if c.CompressLevel != 0 && (c.CompressLevel < lo || c.CompressLevel > hi) {
+		return fmt.Errorf("%s compression level must be between %d and %d, got %d", c.CompressionFormat(), lo, hi, c.CompressLevel)
+	}
+	return nil
+}
+
 // --- File Loader + ENV Overlay (develop compatibility) ---
 func LoadConfig(path string) (*Config, error) {
 	// Default values
@@ -79,7 +202,6 @@ func LoadConfig(path string) (*Config, error) {
 	// Keep develop's global in sync (bytes)
 	DefaultChunkSize = int64(conf.PartSizeMB) * 1024 * 1024
 
-	fmt.Printf("[DEBUG] effective config: %+v\n", *conf)
 	return conf, nil
 }
 
@@ -106,10 +228,20 @@ func applyEnvOverrides(c *Config) {
 		}
 	}
 	if v := os.Getenv("FAVUS_COMPRESS"); v != "" {
-		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+		if b, format, err := ParseCompress(v); err == nil {
 			c.Compress = b
+			if format != "" {
+				c.CompressFormat = format
+			}
 		} else {
-			fmt.Printf("Warning: invalid FAVUS_COMPRESS '%s'. Expected true/false.\n", v)
+			fmt.Printf("Warning: invalid FAVUS_COMPRESS '%s'. Expected true/false/gzip/zstd.\n", v)
+		}
+	}
+	if v := os.Getenv("FAVUS_COMPRESS_LEVEL"); v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			c.CompressLevel = n
+		} else {
+			fmt.Printf("Warning: invalid FAVUS_COMPRESS_LEVEL '%s'. Expected a number.\n", v)
 		}
 	}
 }
@@ -123,6 +255,15 @@ func (c *Config) PartSizeBytes() int64 {
 	return int64(mb) * 1024 * 1024
 }
 
+// MaxInFlightBytes is the cap on part bytes being uploaded at once across the
+// files of one process (see package scheduler).
+func (c *Config) MaxInFlightBytes() int64 {
+	if c.MaxInFlightMB > 0 {
+		return int64(c.MaxInFlightMB) * 1024 * 1024
+	}
+	return 2 * int64(max(c.MaxConcurrency, 1)) * c.PartSizeBytes()
+}
+
 // --- Upload Prompt (main branch logic) ---
 func PromptForUploadConfig(existingBucket, existingKey string) *Config {
 	reader := bufio.NewReader(os.Stdin)
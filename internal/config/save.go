@@ -0,0 +1,78 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetValues sets top-level keys of the YAML config file at path, creating the
+// file if needed. Other keys and comments are kept. The file is replaced
+// atomically.
+func SetValues(path string, values map[string]any) error {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	b, err := os.ReadFile(path)
+	switch {
+	case err == nil && len(bytes.TrimSpace(b)) > 0:
+		if err := yaml.Unmarshal(b, doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", path)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var v yaml.Node
+		if err := v.Encode(values[k]); err != nil {
+			return fmt.Errorf("encode %s: %w", k, err)
+		}
+		replaced := false
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == k {
+				v.LineComment = root.Content[i+1].LineComment
+				root.Content[i+1] = &v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, &v)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	_ = enc.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write temp config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("install config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,660 @@
+// Package directupload serves the HTTP API behind browser uploads in the Web
+// UI. The browser never sends file bytes to Favus: it asks for a multipart
+// upload, PUTs each part to a presigned S3 URL and reports the ETag back.
+// Favus keeps an UploadStatus file per upload (~/.favus/browser), so an
+// interrupted browser upload can be resumed and unfinished ones stay visible
+// to ls-orphans/kill-orphans like any CLI upload.
+//
+//	POST   /api/uploads                  create (or resume) an upload
+//	GET    /api/uploads                  list unfinished browser uploads
+//	GET    /api/uploads/{id}             status, reconciled with ListParts
+//	POST   /api/uploads/{id}/parts       presign part URLs
+//	PUT    /api/uploads/{id}/parts/{n}   record the ETag of an uploaded part
+//	POST   /api/uploads/{id}/complete    complete the multipart upload
+//	DELETE /api/uploads/{id}             abort it
+package directupload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/presign"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/uploader"
+	"github.com/GoCOMA/Favus/internal/wsagent"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	minPartSize     = 5 << 20
+	defaultPartSize = 8 << 20
+	statusSuffix    = ".upload_status"
+)
+
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+
+// Config configures the handler.
+type Config struct {
+	// OpenStore returns the S3 store for a bucket. Required.
+	OpenStore func(bucket string) (storage.ObjectStore, error)
+	// StatusDir holds one UploadStatus file per upload (default ~/.favus/browser).
+	StatusDir string
+	// DefaultBucket is used when a create request has no bucket.
+	DefaultBucket string
+	// AllowedBuckets may also be named by a create request. DefaultBucket is
+	// always allowed; any other bucket is rejected.
+	AllowedBuckets []string
+	// PartSizeBytes is the default part size (min 5 MiB, default 8 MiB).
+	PartSizeBytes int64
+	// Expires is the lifetime of presigned part URLs (default 1h).
+	Expires time.Duration
+	// AllowedOrigins may call the API (CORS). Requests without an Origin header
+	// are rejected too, unless the list has "*", which allows any caller.
+	AllowedOrigins []string
+	// Emit, when set, forwards progress events to the Web UI.
+	Emit func(ev wsagent.Event) error
+}
+
+// Handler implements the API. Mount it at /api/uploads and /api/uploads/.
+type Handler struct {
+	cfg Config
+	mux *http.ServeMux
+
+	mu      sync.Mutex
+	signers map[string]*presign.Signer
+	locks   map[string]*sync.Mutex // one per upload: status file read-modify-write
+}
+
+// New validates cfg and builds the handler.
+func New(cfg Config) (*Handler, error) {
+	if cfg.OpenStore == nil {
+		return nil, errors.New("directupload: OpenStore is required")
+	}
+	if cfg.StatusDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("directupload: resolve home: %w", err)
+		}
+		cfg.StatusDir = filepath.Join(home, ".favus", "browser")
+	}
+	if err := os.MkdirAll(cfg.StatusDir, 0o755); err != nil {
+		return nil, fmt.Errorf("directupload: create %s: %w", cfg.StatusDir, err)
+	}
+	if cfg.PartSizeBytes <= 0 {
+		cfg.PartSizeBytes = defaultPartSize
+	}
+	if cfg.PartSizeBytes < minPartSize {
+		cfg.PartSizeBytes = minPartSize
+	}
+	if cfg.Expires <= 0 {
+		cfg.Expires = time.Hour
+	}
+	if err := presign.ValidateExpires(cfg.Expires); err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		signers: make(map[string]*presign.Signer),
+		locks:   make(map[string]*sync.Mutex),
+	}
+	h.mux.HandleFunc("POST /api/uploads", h.handleCreate)
+	h.mux.HandleFunc("GET /api/uploads", h.handleList)
+	h.mux.HandleFunc("GET /api/uploads/{id}", h.handleGet)
+	h.mux.HandleFunc("POST /api/uploads/{id}/parts", h.handleSignParts)
+	h.mux.HandleFunc("PUT /api/uploads/{id}/parts/{n}", h.handleRecordPart)
+	h.mux.HandleFunc("POST /api/uploads/{id}/complete", h.handleComplete)
+	h.mux.HandleFunc("DELETE /api/uploads/{id}", h.handleAbort)
+	return h, nil
+}
+
+// ServeHTTP applies CORS and dispatches to the API routes. Any local process
+// or web page can reach the agent address, so only the allowed origins get in.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if !h.originAllowed(origin) {
+		if origin == "" {
+			writeError(w, http.StatusForbidden, "an Origin header is required")
+		} else {
+			writeError(w, http.StatusForbidden, "origin %s is not allowed", origin)
+		}
+		return
+	}
+	if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Max-Age", "600")
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) originAllowed(origin string) bool {
+	for _, o := range h.cfg.AllowedOrigins {
+		if o == "*" || origin != "" && strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) bucketAllowed(bucket string) bool {
+	if bucket == h.cfg.DefaultBucket {
+		return true
+	}
+	for _, b := range h.cfg.AllowedBuckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// ===================== API types =====================
+
+type createRequest struct {
+	Bucket        string `json:"bucket"`
+	Key           string `json:"key"`
+	FileName      string `json:"fileName"`
+	FileSize      int64  `json:"fileSize"`
+	PartSizeBytes int64  `json:"partSizeBytes"`
+	ContentType   string `json:"contentType"`
+	// Resume returns an unfinished upload of the same bucket/key/fileName/fileSize
+	// instead of starting a new one.
+	Resume bool `json:"resume"`
+}
+
+// Upload is the API view of an UploadStatus.
+type Upload struct {
+	UploadID       string         `json:"uploadId"`
+	Bucket         string         `json:"bucket"`
+	Key            string         `json:"key"`
+	FileName       string         `json:"fileName"`
+	FileSize       int64          `json:"fileSize"`
+	PartSizeBytes  int64          `json:"partSizeBytes"`
+	TotalParts     int            `json:"totalParts"`
+	CompletedParts map[int]string `json:"completedParts"`
+	MissingParts   []int          `json:"missingParts"`
+	Resumed        bool           `json:"resumed,omitempty"`
+}
+
+type signRequest struct {
+	Parts []int `json:"parts"` // empty: every missing part
+}
+
+type signedPart struct {
+	PartNumber int       `json:"partNumber"`
+	URL        string    `json:"url"`
+	Offset     int64     `json:"offset"`
+	Size       int64     `json:"size"`
+	Expires    time.Time `json:"expires"`
+}
+
+type recordRequest struct {
+	ETag string `json:"etag"`
+}
+
+// ===================== handlers =====================
+
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = h.cfg.DefaultBucket
+	}
+	if req.Key == "" {
+		req.Key = req.FileName
+	}
+	switch {
+	case req.Bucket == "":
+		writeError(w, http.StatusBadRequest, "bucket is required")
+		return
+	case !h.bucketAllowed(req.Bucket):
+		writeError(w, http.StatusForbidden, "bucket %s is not allowed for browser uploads", req.Bucket)
+		return
+	case req.Key == "":
+		writeError(w, http.StatusBadRequest, "key or fileName is required")
+		return
+	case req.FileSize <= 0:
+		writeError(w, http.StatusBadRequest, "fileSize must be positive")
+		return
+	}
+
+	if req.Resume {
+		if st := h.findResumable(req); st != nil {
+			up, err := h.reconcile(r.Context(), st)
+			if err == nil {
+				up.Resumed = true
+				h.emitStart(st, true)
+				writeJSON(w, http.StatusOK, up)
+				return
+			}
+			// 서버에서 이미 사라진 업로드면 새로 시작
+			_ = os.Remove(h.statusPath(st.UploadID))
+		}
+	}
+
+	partSize := req.PartSizeBytes
+	if partSize <= 0 {
+		partSize = h.cfg.PartSizeBytes
+	}
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if floor := (req.FileSize + presign.MaxParts - 1) / presign.MaxParts; partSize < floor {
+		partSize = floor // 10,000 파트 제한을 넘지 않도록 파트 크기를 키움
+	}
+	totalParts := int((req.FileSize + partSize - 1) / partSize)
+
+	signer, err := h.signer(req.Bucket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	in := &s3.CreateMultipartUploadInput{Bucket: aws.String(req.Bucket), Key: aws.String(req.Key)}
+	if req.ContentType != "" {
+		in.ContentType = aws.String(req.ContentType)
+	}
+	out, err := signer.Store.CreateMultipartUpload(r.Context(), in)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "create multipart upload: %v", err)
+		return
+	}
+
+	uploadID := aws.ToString(out.UploadId)
+	st := uploader.NewUploadStatus(req.FileName, req.Bucket, req.Key, uploadID, totalParts, partSize)
+	st.FileSize = req.FileSize
+	if err := h.save(st); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	h.emitStart(st, false)
+	writeJSON(w, http.StatusCreated, toUpload(st))
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, _ *http.Request) {
+	entries, err := os.ReadDir(h.cfg.StatusDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "read %s: %v", h.cfg.StatusDir, err)
+		return
+	}
+	uploads := []Upload{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), statusSuffix) {
+			continue
+		}
+		st, err := uploader.LoadStatus(filepath.Join(h.cfg.StatusDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		uploads = append(uploads, toUpload(st))
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Key < uploads[j].Key })
+	writeJSON(w, http.StatusOK, uploads)
+}
+
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	st, unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+	up, err := h.reconcile(r.Context(), st)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, up)
+}
+
+func (h *Handler) handleSignParts(w http.ResponseWriter, r *http.Request) {
+	st, unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	var req signRequest
+	if r.ContentLength != 0 {
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+	}
+	parts := req.Parts
+	if len(parts) == 0 {
+		parts = toUpload(st).MissingParts
+	}
+
+	signer, err := h.signer(st.Bucket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	signed := make([]signedPart, 0, len(parts))
+	for _, n := range parts {
+		if n < 1 || n > st.TotalParts {
+			writeError(w, http.StatusBadRequest, "part %d out of range 1..%d", n, st.TotalParts)
+			return
+		}
+		u, err := signer.Part(r.Context(), st.Bucket, st.Key, st.UploadID, int32(n), h.cfg.Expires)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "%v", err)
+			return
+		}
+		off, size := partRange(st, n)
+		signed = append(signed, signedPart{PartNumber: n, URL: u.URL, Offset: off, Size: size, Expires: u.Expires})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"uploadId": st.UploadID, "parts": signed})
+}
+
+func (h *Handler) handleRecordPart(w http.ResponseWriter, r *http.Request) {
+	st, unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 1 || n > st.TotalParts {
+		writeError(w, http.StatusBadRequest, "invalid part number %q", r.PathValue("n"))
+		return
+	}
+	var req recordRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if strings.TrimSpace(req.ETag) == "" {
+		writeError(w, http.StatusBadRequest, "etag is required")
+		return
+	}
+
+	st.AddCompletedPart(n, req.ETag)
+	if err := h.save(st); err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	_, size := partRange(st, n)
+	h.emit(st.UploadID, "part_done", map[string]any{"part": n, "size": size, "etag": req.ETag})
+	writeJSON(w, http.StatusOK, toUpload(st))
+}
+
+func (h *Handler) handleComplete(w http.ResponseWriter, r *http.Request) {
+	st, unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	signer, err := h.signer(st.Bucket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	m := &presign.Manifest{Bucket: st.Bucket, Key: st.Key, UploadID: st.UploadID}
+	for n := 1; n <= st.TotalParts; n++ {
+		m.Parts = append(m.Parts, presign.Part{PartNumber: int32(n), ETag: st.CompletedParts[n]})
+	}
+	out, err := presign.Complete(r.Context(), signer.Store, m)
+	if err != nil {
+		h.emit(st.UploadID, "error", map[string]any{"message": err.Error()})
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	_ = os.Remove(h.statusPath(st.UploadID))
+	h.emitDone(st, true)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"uploadId": st.UploadID,
+		"bucket":   st.Bucket,
+		"key":      st.Key,
+		"etag":     aws.ToString(out.ETag),
+		"location": aws.ToString(out.Location),
+	})
+}
+
+func (h *Handler) handleAbort(w http.ResponseWriter, r *http.Request) {
+	st, unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	signer, err := h.signer(st.Bucket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	m := &presign.Manifest{Bucket: st.Bucket, Key: st.Key, UploadID: st.UploadID}
+	if err := presign.Abort(r.Context(), signer.Store, m); err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	_ = os.Remove(h.statusPath(st.UploadID))
+	h.emitDone(st, false)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===================== helpers =====================
+
+func (h *Handler) signer(bucket string) (*presign.Signer, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.signers[bucket]; ok {
+		return s, nil
+	}
+	store, err := h.cfg.OpenStore(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("open bucket %s: %w", bucket, err)
+	}
+	s, err := presign.New(store)
+	if err != nil {
+		return nil, err
+	}
+	h.signers[bucket] = s
+	return s, nil
+}
+
+func (h *Handler) statusPath(uploadID string) string {
+	return filepath.Join(h.cfg.StatusDir, uploadID+statusSuffix)
+}
+
+func (h *Handler) save(st *uploader.UploadStatus) error {
+	return st.SaveStatus(h.statusPath(st.UploadID))
+}
+
+// lockUpload loads the upload named in the path and holds its lock until
+// unlock is called. It writes the error response itself when ok is false.
+func (h *Handler) lockUpload(w http.ResponseWriter, r *http.Request) (st *uploader.UploadStatus, unlock func(), ok bool) {
+	id := r.PathValue("id")
+	if !uploadIDPattern.MatchString(id) || strings.Trim(id, ".") == "" {
+		writeError(w, http.StatusBadRequest, "invalid upload id")
+		return nil, nil, false
+	}
+	h.mu.Lock()
+	l, exists := h.locks[id]
+	if !exists {
+		l = &sync.Mutex{}
+		h.locks[id] = l
+	}
+	h.mu.Unlock()
+
+	l.Lock()
+	st, err := uploader.LoadStatus(h.statusPath(id))
+	if err != nil {
+		l.Unlock()
+		writeError(w, http.StatusNotFound, "upload %s not found", id)
+		return nil, nil, false
+	}
+	return st, l.Unlock, true
+}
+
+// reconcile merges the parts S3 already has into the status (S3 wins), like
+// `favus resume` does for CLI uploads.
+func (h *Handler) reconcile(ctx context.Context, st *uploader.UploadStatus) (Upload, error) {
+	signer, err := h.signer(st.Bucket)
+	if err != nil {
+		return Upload{}, err
+	}
+	p := s3.NewListPartsPaginator(signer.Store, &s3.ListPartsInput{
+		Bucket:   aws.String(st.Bucket),
+		Key:      aws.String(st.Key),
+		UploadId: aws.String(st.UploadID),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return Upload{}, fmt.Errorf("list parts: %w", err)
+		}
+		for _, part := range page.Parts {
+			st.AddCompletedPart(int(aws.ToInt32(part.PartNumber)), aws.ToString(part.ETag))
+		}
+	}
+	if err := h.save(st); err != nil {
+		return Upload{}, err
+	}
+	return toUpload(st), nil
+}
+
+func (h *Handler) findResumable(req createRequest) *uploader.UploadStatus {
+	entries, err := os.ReadDir(h.cfg.StatusDir)
+	if err != nil {
+		return nil
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), statusSuffix) {
+			continue
+		}
+		st, err := uploader.LoadStatus(filepath.Join(h.cfg.StatusDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		if st.Bucket == req.Bucket && st.Key == req.Key && st.FilePath == req.FileName && st.FileSize == req.FileSize {
+			return st
+		}
+	}
+	return nil
+}
+
+func partRange(st *uploader.UploadStatus, n int) (offset, size int64) {
+	offset = int64(n-1) * st.PartSizeBytes
+	size = st.PartSizeBytes
+	if offset+size > st.FileSize {
+		size = st.FileSize - offset
+	}
+	return offset, size
+}
+
+func toUpload(st *uploader.UploadStatus) Upload {
+	st.Mu.Lock()
+	defer st.Mu.Unlock()
+	up := Upload{
+		UploadID:       st.UploadID,
+		Bucket:         st.Bucket,
+		Key:            st.Key,
+		FileName:       st.FilePath,
+		FileSize:       st.FileSize,
+		PartSizeBytes:  st.PartSizeBytes,
+		TotalParts:     st.TotalParts,
+		CompletedParts: make(map[int]string, len(st.CompletedParts)),
+		MissingParts:   []int{},
+	}
+	for n := 1; n <= st.TotalParts; n++ {
+		if et, ok := st.CompletedParts[n]; ok {
+			up.CompletedParts[n] = et
+		} else {
+			up.MissingParts = append(up.MissingParts, n)
+		}
+	}
+	return up
+}
+
+// ===================== UI events =====================
+
+func (h *Handler) emit(runID, evType string, payload any) {
+	if h.cfg.Emit == nil {
+		return
+	}
+	b, _ := json.Marshal(payload)
+	if err := h.cfg.Emit(wsagent.Event{Type: evType, RunID: runID, Timestamp: time.Now(), Payload: b}); err != nil {
+		fmt.Fprintf(os.Stderr, "warn: directupload: event %q not delivered: %v\n", evType, err)
+	}
+}
+
+func (h *Handler) emitStart(st *uploader.UploadStatus, resumed bool) {
+	up := toUpload(st)
+	p := map[string]any{
+		"bucket":   st.Bucket,
+		"key":      st.Key,
+		"uploadId": st.UploadID,
+		"partMB":   float64(st.PartSizeBytes) / (1024.0 * 1024.0),
+		"total":    st.FileSize,
+		"source":   "browser",
+		"fileName": st.FilePath,
+	}
+	if resumed {
+		pre := make([]map[string]any, 0, len(up.CompletedParts))
+		for n, et := range up.CompletedParts {
+			_, size := partRange(st, n)
+			pre = append(pre, map[string]any{"part": n, "size": size, "etag": et})
+		}
+		p["resumed"] = true
+		p["preCompleted"] = pre
+		p["totalParts"] = st.TotalParts
+	}
+	h.emit(st.UploadID, "session_start", p)
+}
+
+func (h *Handler) emitDone(st *uploader.UploadStatus, success bool) {
+	var bytes int64
+	for n := range toUpload(st).CompletedParts {
+		_, size := partRange(st, n)
+		bytes += size
+	}
+	h.emit(st.UploadID, "session_done", map[string]any{
+		"success":  success,
+		"uploadId": st.UploadID,
+		"bytes":    bytes,
+		"total":    st.FileSize,
+	})
+}
+
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
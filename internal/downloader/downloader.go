@@ -0,0 +1,176 @@
+// Package downloader reads objects back. Objects that favus upload --compress
+// produced (gzip members or zstd frames, favus-original-* metadata) are
+// decompressed while streaming and checked against their original size.
+package downloader
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+)
+
+// favus upload --compress 가 남기는 표식 (uploader.compressionMetadata 참고)
+const (
+	metaOriginalName = "favus-original-name"
+	metaOriginalSize = "favus-original-size"
+	metaCompression  = "favus-compression"
+)
+
+// ErrSizeMismatch means the bytes read do not add up to the expected size
+// (favus-original-size, or Content-Length with --raw).
+var ErrSizeMismatch = errors.New("size mismatch")
+
+// Object is an opened object. Body yields the original bytes unless the
+// object was opened raw or was not compressed by favus.
+type Object struct {
+	Bucket   string
+	Key      string
+	Name     string // file name to restore: favus-original-name, else the key's base name
+	Encoding string // compression undone while reading ("" when none)
+	Size     int64  // bytes Body will yield, -1 when unknown
+	Body     io.ReadCloser
+}
+
+// Open starts a GetObject and, unless raw, decompresses gzip/zstd objects
+// while streaming. Body returns ErrSizeMismatch instead of io.EOF when the
+// result does not match the expected size.
+func Open(ctx context.Context, store storage.ObjectStore, bucket, key string, raw bool) (*Object, error) {
+	out, err := store.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("get s3://%s/%s: %w", bucket, key, err)
+	}
+	obj := &Object{Bucket: bucket, Key: key, Name: path.Base(key), Size: -1}
+	if out.ContentLength != nil {
+		obj.Size = *out.ContentLength
+	}
+
+	encoding := compressionOf(out)
+	if raw || encoding == "" {
+		obj.Body = &sizeCheckReader{r: out.Body, c: out.Body, want: obj.Size}
+		return obj, nil
+	}
+
+	dec, err := NewDecoder(out.Body, encoding)
+	if err != nil {
+		out.Body.Close()
+		return nil, fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+	}
+
+	obj.Encoding = encoding
+	obj.Size = -1
+	if v := metadata(out.Metadata, metaOriginalSize); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			obj.Size = n
+		}
+	}
+	if v := metadata(out.Metadata, metaOriginalName); v != "" {
+		obj.Name = path.Base(strings.ReplaceAll(v, `\`, "/"))
+	} else {
+		obj.Name = trimCompressedExt(obj.Name, encoding)
+	}
+	obj.Body = &sizeCheckReader{r: dec, c: closerFunc(func() error {
+		_ = dec.Close()
+		return out.Body.Close()
+	}), want: obj.Size}
+	return obj, nil
+}
+
+// NewDecoder decompresses a gzip or zstd stream made of one or more members
+// or frames. Close releases the decoder, not r.
+func NewDecoder(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip":
+		// gzip.Reader 는 기본으로 여러 member 를 이어 읽는다 (파트마다 member)
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("read gzip header: %w", err)
+		}
+		return zr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("zstd reader: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q (use --raw)", encoding)
+}
+
+// compressionOf returns gzip or zstd when the object was compressed, looking
+// at favus-compression first and Content-Encoding second.
+func compressionOf(out *s3.GetObjectOutput) string {
+	switch v := strings.ToLower(metadata(out.Metadata, metaCompression)); v {
+	case "none":
+		return ""
+	case "":
+	default:
+		return v
+	}
+	switch v := strings.ToLower(strings.TrimSpace(aws.ToString(out.ContentEncoding))); v {
+	case "", "identity":
+		return ""
+	case "x-gzip":
+		return "gzip"
+	default:
+		return v
+	}
+}
+
+// metadata looks up a user metadata key case-insensitively (S3 lowercases
+// them, other backends may not).
+func metadata(md map[string]string, key string) string {
+	if v, ok := md[key]; ok {
+		return v
+	}
+	for k, v := range md {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func trimCompressedExt(name, encoding string) string {
+	ext := map[string]string{"gzip": ".gz", "zstd": ".zst"}[encoding]
+	if trimmed := strings.TrimSuffix(name, ext); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
+
+// sizeCheckReader turns io.EOF into ErrSizeMismatch when fewer or more than
+// want bytes were read (want < 0 disables the check).
+type sizeCheckReader struct {
+	r    io.Reader
+	c    io.Closer
+	want int64
+	n    int64
+}
+
+func (s *sizeCheckReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += int64(n)
+	if s.want >= 0 && s.n > s.want {
+		return n, fmt.Errorf("%w: got more than %d bytes", ErrSizeMismatch, s.want)
+	}
+	if errors.Is(err, io.EOF) && s.want >= 0 && s.n != s.want {
+		return n, fmt.Errorf("%w: got %d bytes, expected %d", ErrSizeMismatch, s.n, s.want)
+	}
+	return n, err
+}
+
+func (s *sizeCheckReader) Close() error { return s.c.Close() }
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
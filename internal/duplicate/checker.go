@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 
 	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/faults"
+	"github.com/GoCOMA/Favus/internal/storage"
 	"github.com/GoCOMA/Favus/pkg/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsv2cfg "github.com/aws/aws-sdk-go-v2/config"
@@ -17,12 +19,23 @@ import (
 
 // DuplicateChecker handles duplicate file detection using Redis-based Cuckoo Filter and Count-Min Sketch
 type DuplicateChecker struct {
-	rdb      *redis.Client
-	s3Client *s3.Client
+	rdb   *redis.Client
+	store storage.ObjectStore
 }
 
-// NewDuplicateChecker creates a new duplicate checker instance
+// NewDuplicateChecker creates a new duplicate checker instance backed by S3
 func NewDuplicateChecker(cfg *config.Config) (*DuplicateChecker, error) {
+	// Create S3 client
+	s3Client, err := createS3Client(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return NewDuplicateCheckerWithStore(&storage.S3Store{Client: s3Client})
+}
+
+// NewDuplicateCheckerWithStore creates a duplicate checker that checks object
+// existence against the given store (S3, in-memory or local directory)
+func NewDuplicateCheckerWithStore(store storage.ObjectStore) (*DuplicateChecker, error) {
 	redisURL := os.Getenv("REDIS_URL")
 	if redisURL == "" {
 		redisURL = "redis://localhost:6379"
@@ -42,15 +55,9 @@ func NewDuplicateChecker(cfg *config.Config) (*DuplicateChecker, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	// Create S3 client
-	s3Client, err := createS3Client(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 client: %w", err)
-	}
-
 	dc := &DuplicateChecker{
-		rdb:      rdb,
-		s3Client: s3Client,
+		rdb:   rdb,
+		store: store,
 	}
 
 	// Initialize Redis data structures
@@ -211,7 +218,7 @@ func (dc *DuplicateChecker) checkS3Exists(ctx context.Context, cfg *config.Confi
 	}
 
 	// Perform HEAD request to check if object exists
-	_, err := dc.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+	_, err := dc.store.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: &cfg.Bucket,
 		Key:    &key,
 	})
@@ -285,7 +292,7 @@ func createS3Client(cfg *config.Config) (*s3.Client, error) {
 		if endpoint != "" {
 			o.UsePathStyle = true // For LocalStack or custom S3-compatible endpoints
 		}
-	})
+	}, faults.S3Options)
 
 	return cli, nil
 }
@@ -0,0 +1,308 @@
+// Package faults injects failures into outgoing HTTP requests so retries and
+// resume can be exercised repeatably. It is opt-in: nothing happens unless
+// FAVUS_FAULTS is set.
+//
+// FAVUS_FAULTS is either a path to a YAML file:
+//
+//	seed: 42
+//	rules:
+//	  - op: UploadPart
+//	    parts: "3"
+//	    action: slowdown
+//	    times: 2
+//	  - op: UploadPart
+//	    action: kill
+//	    after: 5
+//
+// or the same rules inline, separated by ';':
+//
+//	FAVUS_FAULTS="op=UploadPart,parts=3,action=slowdown,times=2; op=UploadPart,action=kill,after=5"
+package faults
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvVar holds the fault spec (YAML file path or inline rules).
+const EnvVar = "FAVUS_FAULTS"
+
+// Action is what a matching rule does to the request.
+type Action string
+
+const (
+	ActionError    Action = "error"    // synthetic S3 error response (default 500 InternalError)
+	ActionSlowDown Action = "slowdown" // 503 SlowDown
+	ActionReset    Action = "reset"    // connection reset before a response arrives
+	ActionLatency  Action = "latency"  // sleep Delay, then send the request
+	ActionTruncate Action = "truncate" // cut the request body (uploads) or response body (downloads) short
+	ActionKill     Action = "kill"     // kill the process once After matching requests have completed
+)
+
+// Rule selects requests and the fault to inject into them.
+type Rule struct {
+	// Op is the S3 operation name (UploadPart, CompleteMultipartUpload, ...),
+	// "SendEvent" for wsagent events, or "*" / empty for every request.
+	Op string `yaml:"op"`
+	// Parts limits the rule to part numbers: "3", "2-4" or "1,5-6".
+	Parts  string `yaml:"parts"`
+	Action Action `yaml:"action"`
+
+	Delay  time.Duration `yaml:"delay"`  // latency (default 1s)
+	Status int           `yaml:"status"` // error: HTTP status (default 500)
+	Code   string        `yaml:"code"`   // error: S3 error code (default InternalError)
+	Bytes  int64         `yaml:"bytes"`  // truncate: bytes to let through (default half)
+
+	// After skips the first N matching requests. For kill it is the number
+	// of matching requests that must complete before the process dies.
+	After int `yaml:"after"`
+	// Times caps how often the rule fires (0 = unlimited).
+	Times int `yaml:"times"`
+	// Probability fires the rule randomly (0 or 1 = always).
+	Probability float64 `yaml:"probability"`
+
+	parts     []partRange
+	seen      int
+	fired     int
+	completed int
+}
+
+// Config is the parsed fault spec.
+type Config struct {
+	Seed  int64  `yaml:"seed"`
+	Rules []Rule `yaml:"rules"`
+}
+
+type partRange struct{ from, to int }
+
+func (r *Rule) matchesPart(n int) bool {
+	if len(r.parts) == 0 {
+		return true
+	}
+	if n <= 0 {
+		return false
+	}
+	for _, pr := range r.parts {
+		if n >= pr.from && n <= pr.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesOp(op string) bool {
+	return r.Op == "" || r.Op == "*" || strings.EqualFold(r.Op, op)
+}
+
+func (r *Rule) String() string {
+	s := fmt.Sprintf("%s on %s", r.Action, defaultString(r.Op, "*"))
+	if r.Parts != "" {
+		s += " parts " + r.Parts
+	}
+	return s
+}
+
+func parseParts(spec string) ([]partRange, error) {
+	var out []partRange
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || f == "*" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(f, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("bad part %q", f)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || to < from {
+				return nil, fmt.Errorf("bad part range %q", f)
+			}
+		}
+		out = append(out, partRange{from, to})
+	}
+	return out, nil
+}
+
+// validate fills defaults and checks each rule.
+func (c *Config) validate() error {
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("faults: no rules defined")
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		parts, err := parseParts(r.Parts)
+		if err != nil {
+			return fmt.Errorf("faults: rule %d: %w", i+1, err)
+		}
+		r.parts = parts
+		switch r.Action {
+		case ActionError:
+			if r.Status == 0 {
+				r.Status = 500
+			}
+			if r.Code == "" {
+				r.Code = "InternalError"
+			}
+		case ActionSlowDown:
+			r.Status, r.Code = 503, "SlowDown"
+		case ActionLatency:
+			if r.Delay <= 0 {
+				r.Delay = time.Second
+			}
+		case ActionKill:
+			if r.After <= 0 {
+				r.After = 1
+			}
+		case ActionReset, ActionTruncate:
+		case "":
+			return fmt.Errorf("faults: rule %d: action is required", i+1)
+		default:
+			return fmt.Errorf("faults: rule %d: unknown action %q (error|slowdown|reset|latency|truncate|kill)", i+1, r.Action)
+		}
+		if r.Probability < 0 || r.Probability > 1 {
+			return fmt.Errorf("faults: rule %d: probability must be between 0 and 1", i+1)
+		}
+	}
+	return nil
+}
+
+// Parse reads a spec: a YAML file path or inline rules.
+func Parse(spec string) (*Config, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("faults: empty spec")
+	}
+	if fi, err := os.Stat(spec); err == nil && !fi.IsDir() {
+		return LoadFile(spec)
+	}
+	if !strings.Contains(spec, "=") {
+		return nil, fmt.Errorf("faults: %q is neither a file nor an inline rule list", spec)
+	}
+	cfg := &Config{}
+	for _, seg := range strings.Split(spec, ";") {
+		seg = strings.TrimSpace(seg)
+		if seg == "" {
+			continue
+		}
+		var r Rule
+		isRule := false
+		for _, kv := range splitFields(seg) {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("faults: %q is not key=value", kv)
+			}
+			k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+			var err error
+			switch k {
+			case "seed":
+				if cfg.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+					return nil, fmt.Errorf("faults: seed=%q: %w", v, err)
+				}
+				continue
+			case "op":
+				r.Op = v
+			case "parts", "part":
+				r.Parts = v
+			case "action":
+				r.Action = Action(strings.ToLower(v))
+			case "delay":
+				r.Delay, err = time.ParseDuration(v)
+			case "status":
+				r.Status, err = strconv.Atoi(v)
+			case "code":
+				r.Code = v
+			case "bytes":
+				r.Bytes, err = strconv.ParseInt(v, 10, 64)
+			case "after":
+				r.After, err = strconv.Atoi(v)
+			case "times":
+				r.Times, err = strconv.Atoi(v)
+			case "probability", "p":
+				r.Probability, err = strconv.ParseFloat(v, 64)
+			default:
+				return nil, fmt.Errorf("faults: unknown field %q", k)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("faults: %s=%q: %w", k, v, err)
+			}
+			isRule = true
+		}
+		if isRule {
+			cfg.Rules = append(cfg.Rules, r)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// splitFields splits "op=UploadPart,parts=1,3-4,action=error" on commas that
+// start a new key=value pair, so part lists may contain commas.
+func splitFields(seg string) []string {
+	var out []string
+	for _, f := range strings.Split(seg, ",") {
+		if len(out) > 0 && !strings.Contains(f, "=") {
+			out[len(out)-1] += "," + f
+			continue
+		}
+		out = append(out, strings.TrimSpace(f))
+	}
+	return out
+}
+
+// LoadFile reads a YAML fault spec.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("faults: read %s: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("faults: parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+var (
+	activeOnce sync.Once
+	active     *Injector
+	activeErr  error
+)
+
+// Active returns the process-wide injector configured by FAVUS_FAULTS,
+// or nil when fault injection is off.
+func Active() (*Injector, error) {
+	activeOnce.Do(func() {
+		spec := os.Getenv(EnvVar)
+		if strings.TrimSpace(spec) == "" {
+			return
+		}
+		cfg, err := Parse(spec)
+		if err != nil {
+			activeErr = err
+			return
+		}
+		active = New(cfg)
+		fmt.Fprintf(os.Stderr, "⚠️  fault injection enabled (%s): %d rule(s)\n", EnvVar, len(cfg.Rules))
+	})
+	return active, activeErr
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,280 @@
+package faults
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Doer is the HTTP client shape the AWS SDK uses (s3.HTTPClient).
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Injector applies a Config to requests. Rule counters are shared by every
+// client wrapped with the same injector.
+type Injector struct {
+	mu    sync.Mutex
+	rules []*Rule
+	rnd   *rand.Rand
+}
+
+// New builds an injector from a validated Config.
+func New(cfg *Config) *Injector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	in := &Injector{rnd: rand.New(rand.NewSource(seed))}
+	for i := range cfg.Rules {
+		in.rules = append(in.rules, &cfg.Rules[i])
+	}
+	return in
+}
+
+type opKey struct{}
+
+// WithOperation names requests made with ctx for rule matching. SDK requests
+// carry their operation name already; this is for other clients (wsagent).
+func WithOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey{}, op)
+}
+
+func operationOf(req *http.Request) string {
+	if op := awsmiddleware.GetOperationName(req.Context()); op != "" {
+		return op
+	}
+	if op, ok := req.Context().Value(opKey{}).(string); ok {
+		return op
+	}
+	return req.Method + " " + req.URL.Path
+}
+
+// S3Options wraps the client's HTTP transport with the active injector.
+// It is a no-op when FAVUS_FAULTS is unset or invalid (Active reports the error).
+func S3Options(o *s3.Options) {
+	in, err := Active()
+	if err != nil || in == nil {
+		return
+	}
+	next := o.HTTPClient
+	if next == nil {
+		next = http.DefaultClient
+	}
+	o.HTTPClient = in.WrapDoer(next)
+}
+
+// Transport wraps rt with the active injector, or returns rt unchanged.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	in, err := Active()
+	if err != nil || in == nil {
+		return rt
+	}
+	return in.WrapTransport(rt)
+}
+
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error)        { return f(req) }
+func (f doerFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// WrapDoer returns a Doer that injects faults before delegating to next.
+func (in *Injector) WrapDoer(next Doer) Doer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) { return in.do(req, next.Do) })
+}
+
+// WrapTransport returns a RoundTripper that injects faults before delegating to next.
+func (in *Injector) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	return doerFunc(func(req *http.Request) (*http.Response, error) { return in.do(req, next.RoundTrip) })
+}
+
+// pick returns the rule firing for this request (first match wins) and the
+// kill rules that should count its completion.
+func (in *Injector) pick(op string, part int) (*Rule, []*Rule) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	var fire *Rule
+	var kills []*Rule
+	for _, r := range in.rules {
+		if !r.matchesOp(op) || !r.matchesPart(part) {
+			continue
+		}
+		if r.Action == ActionKill {
+			kills = append(kills, r)
+			continue
+		}
+		if fire != nil {
+			continue
+		}
+		r.seen++
+		if r.seen <= r.After || (r.Times > 0 && r.fired >= r.Times) {
+			continue
+		}
+		if r.Probability > 0 && r.Probability < 1 && in.rnd.Float64() >= r.Probability {
+			continue
+		}
+		r.fired++
+		fire = r
+	}
+	return fire, kills
+}
+
+func (in *Injector) do(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	op := operationOf(req)
+	part, _ := strconv.Atoi(req.URL.Query().Get("partNumber"))
+	rule, kills := in.pick(op, part)
+
+	var resp *http.Response
+	var err error
+	if rule == nil {
+		resp, err = next(req)
+	} else {
+		logf("%s%s: injecting %s", op, partLabel(part), rule.Action)
+		resp, err = in.apply(rule, req, next)
+	}
+
+	if err == nil && resp != nil && resp.StatusCode < 300 && len(kills) > 0 {
+		in.countCompletion(op, kills)
+	}
+	return resp, err
+}
+
+func (in *Injector) apply(r *Rule, req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	switch r.Action {
+	case ActionLatency:
+		select {
+		case <-time.After(r.Delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return next(req)
+
+	case ActionError, ActionSlowDown:
+		closeBody(req)
+		return errorResponse(req, r.Status, r.Code), nil
+
+	case ActionReset:
+		closeBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	case ActionTruncate:
+		if req.Body != nil && req.Body != http.NoBody && req.ContentLength > 0 {
+			limit := r.Bytes
+			if limit <= 0 || limit >= req.ContentLength {
+				limit = req.ContentLength / 2
+			}
+			clone := req.Clone(req.Context())
+			clone.Body = &truncatedReader{r: req.Body, left: limit, c: req.Body}
+			return next(clone)
+		}
+		resp, err := next(req)
+		if err != nil || resp.Body == nil {
+			return resp, err
+		}
+		limit := r.Bytes
+		if limit <= 0 && resp.ContentLength > 0 {
+			limit = resp.ContentLength / 2
+		}
+		resp.Body = &truncatedReader{r: resp.Body, left: limit, c: resp.Body}
+		return resp, nil
+	}
+	return next(req)
+}
+
+// countCompletion kills the process once a kill rule has seen After successes.
+func (in *Injector) countCompletion(op string, kills []*Rule) {
+	in.mu.Lock()
+	var hit *Rule
+	for _, r := range kills {
+		r.completed++
+		if hit == nil && r.completed == r.After && (r.Times == 0 || r.fired < r.Times) {
+			r.fired++
+			hit = r
+		}
+	}
+	in.mu.Unlock()
+	if hit == nil {
+		return
+	}
+	logf("killing process after %d completed %s request(s)", hit.After, op)
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Kill()
+	}
+	select {} // Kill은 비동기로 전달되므로 다음 요청이 나가지 않도록 대기
+}
+
+func errorResponse(req *http.Request, status int, code string) *http.Response {
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>`+
+		`<Error><Code>%s</Code><Message>injected by FAVUS_FAULTS</Message><RequestId>favus-fault</RequestId></Error>`, code)
+	h := make(http.Header)
+	h.Set("Content-Type", "application/xml")
+	h.Set("x-amz-request-id", "favus-fault")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// truncatedReader lets left bytes through, then fails like a dropped connection.
+type truncatedReader struct {
+	r    io.Reader
+	left int64
+	c    io.Closer
+}
+
+func (t *truncatedReader) Read(p []byte) (int, error) {
+	if t.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > t.left {
+		p = p[:t.left]
+	}
+	n, err := t.r.Read(p)
+	t.left -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (t *truncatedReader) Close() error { return t.c.Close() }
+
+func partLabel(part int) string {
+	if part <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" part %d", part)
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[FAULT] "+format+"\n", args...)
+}
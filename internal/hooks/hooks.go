@@ -0,0 +1,100 @@
+// Package hooks runs the user's hooks.preUpload / postUpload / onFailure
+// commands around an upload session. Every hook gets the session as JSON on
+// stdin; a preUpload hook that exits non-zero or times out vetoes the upload.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/pkg/utils"
+)
+
+// DefaultTimeout applies to hooks without a timeout.
+const DefaultTimeout = time.Minute
+
+// Hook events, also passed as FAVUS_HOOK_EVENT.
+const (
+	PreUpload  = "preUpload"
+	PostUpload = "postUpload"
+	OnFailure  = "onFailure"
+)
+
+// Session is the JSON document a hook reads from stdin.
+type Session struct {
+	Event      string `json:"event"`
+	Bucket     string `json:"bucket"`
+	Key        string `json:"key"`
+	UploadID   string `json:"uploadId,omitempty"`
+	RunID      string `json:"runId,omitempty"`
+	File       string `json:"file"`
+	Bytes      int64  `json:"bytes"` // size of the local file (or tar stream)
+	ETag       string `json:"etag,omitempty"`
+	DurationMs int64  `json:"durationMs"` // since the session started
+	Resumed    bool   `json:"resumed,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Run runs h for s.Event. It returns an error when the command exits
+// non-zero, cannot be started or runs longer than its timeout; an empty
+// command is a no-op.
+func Run(h config.Hook, s Session) error {
+	if strings.TrimSpace(h.Command) == "" {
+		return nil
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("%s hook: encode session: %w", s.Event, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, h.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	// 훅 출력은 favus 출력과 같은 곳으로 (cat/JSON 모드에선 os.Stdout 이 stderr)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "FAVUS_HOOK_EVENT="+s.Event)
+	cmd.WaitDelay = 2 * time.Second
+
+	utils.Info(fmt.Sprintf("Running %s hook: %s", s.Event, h.Command))
+	started := time.Now()
+	err = cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("%s hook timed out after %s", s.Event, timeout)
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = fmt.Errorf("%s hook exited with status %d", s.Event, exitErr.ExitCode())
+		} else {
+			err = fmt.Errorf("%s hook: %w", s.Event, err)
+		}
+	}
+	if err != nil {
+		utils.Error(err.Error())
+		return err
+	}
+	utils.Info(fmt.Sprintf("%s hook finished in %s", s.Event, time.Since(started).Round(time.Millisecond)))
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
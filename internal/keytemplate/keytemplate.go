@@ -0,0 +1,269 @@
+// Package keytemplate builds object keys from --key-template / keyTemplate
+// patterns such as raw/{yyyy}/{mm}/{dd}/{hostname}/{basename}. Templates are
+// parsed (and rejected) before any upload starts and rendered once per file.
+package keytemplate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholders lists the supported placeholders with a short description,
+// in the order they are shown in help texts. Dates and times are UTC.
+var Placeholders = [][2]string{
+	{"yyyy", "year, 4 digits"},
+	{"yy", "year, 2 digits"},
+	{"mm", "month, 01-12"},
+	{"dd", "day, 01-31"},
+	{"hh", "hour, 00-23"},
+	{"min", "minute, 00-59"},
+	{"ss", "second, 00-59"},
+	{"date", "yyyy-mm-dd"},
+	{"time", "hhmmss"},
+	{"unix", "Unix timestamp in seconds"},
+	{"hostname", "host name of this machine"},
+	{"user", "local user name"},
+	{"basename", "file name with extension"},
+	{"name", "file name without extension"},
+	{"ext", "extension without the dot (empty if none)"},
+	{"reldir", "directory of the file relative to the upload root (empty for single files)"},
+	{"sha256", "SHA-256 of the file; {sha256:N} keeps the first N hex digits"},
+	{"runId", "ID of the upload run (the one shown in the UI)"},
+}
+
+// Vars are the inputs of one rendering. Hostname and User are looked up from
+// the OS when empty; Time defaults to now.
+type Vars struct {
+	Path     string // local file; basename, name, ext and sha256 come from it
+	RelDir   string // slash-separated, "" for single-file uploads
+	Time     time.Time
+	RunID    string
+	Hostname string
+	User     string
+}
+
+// Template is a parsed key template.
+type Template struct {
+	src   string
+	parts []part
+}
+
+type part struct {
+	lit  string
+	name string // placeholder name; empty for literal text
+	n    int    // {sha256:N} prefix length, 0 for the full digest
+}
+
+// Parse validates tmpl: every {placeholder} must be known and closed, and
+// only sha256 takes an argument (1-64).
+func Parse(tmpl string) (*Template, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return nil, fmt.Errorf("key template is empty")
+	}
+	t := &Template{src: tmpl}
+	rest := tmpl
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			t.parts = append(t.parts, part{lit: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("key template %q: unexpected '}'", tmpl)
+		}
+		if open > 0 {
+			t.parts = append(t.parts, part{lit: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("key template %q: unclosed '{'", tmpl)
+		}
+		p, err := parsePlaceholder(rest[open+1 : open+end])
+		if err != nil {
+			return nil, fmt.Errorf("key template %q: %w", tmpl, err)
+		}
+		t.parts = append(t.parts, p)
+		rest = rest[open+end+1:]
+	}
+	return t, nil
+}
+
+func parsePlaceholder(s string) (part, error) {
+	name, arg, hasArg := strings.Cut(s, ":")
+	known := false
+	for _, p := range Placeholders {
+		if p[0] == name {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return part{}, fmt.Errorf("unknown placeholder {%s}", s)
+	}
+	if !hasArg {
+		return part{name: name}, nil
+	}
+	if name != "sha256" {
+		return part{}, fmt.Errorf("{%s} takes no argument", name)
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > 64 {
+		return part{}, fmt.Errorf("{%s}: prefix length must be 1-64", s)
+	}
+	return part{name: name, n: n}, nil
+}
+
+// String returns the template as given.
+func (t *Template) String() string { return t.src }
+
+// Uses reports whether the template contains the placeholder name.
+func (t *Template) Uses(name string) bool {
+	for _, p := range t.parts {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Render fills in the placeholders. Empty path segments (e.g. from an empty
+// {reldir}) are dropped, so raw/{reldir}/{basename} gives raw/a.bin for a
+// single file.
+func (t *Template) Render(v Vars) (string, error) {
+	if v.Time.IsZero() {
+		v.Time = time.Now()
+	}
+	ts := v.Time.UTC()
+	base := filepath.Base(v.Path)
+	ext := strings.TrimPrefix(filepath.Ext(base), ".")
+
+	var sum string
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.name == "" {
+			b.WriteString(p.lit)
+			continue
+		}
+		var val string
+		switch p.name {
+		case "yyyy":
+			val = ts.Format("2006")
+		case "yy":
+			val = ts.Format("06")
+		case "mm":
+			val = ts.Format("01")
+		case "dd":
+			val = ts.Format("02")
+		case "hh":
+			val = ts.Format("15")
+		case "min":
+			val = ts.Format("04")
+		case "ss":
+			val = ts.Format("05")
+		case "date":
+			val = ts.Format("2006-01-02")
+		case "time":
+			val = ts.Format("150405")
+		case "unix":
+			val = strconv.FormatInt(ts.Unix(), 10)
+		case "hostname":
+			val = v.Hostname
+			if val == "" {
+				val = hostname()
+			}
+		case "user":
+			val = v.User
+			if val == "" {
+				val = userName()
+			}
+		case "basename":
+			val = base
+		case "name":
+			val = strings.TrimSuffix(base, filepath.Ext(base))
+		case "ext":
+			val = ext
+		case "reldir":
+			val = filepath.ToSlash(v.RelDir)
+			if val == "." {
+				val = ""
+			}
+		case "sha256":
+			if sum == "" {
+				var err error
+				if sum, err = fileSHA256(v.Path); err != nil {
+					return "", fmt.Errorf("key template {sha256}: %w", err)
+				}
+			}
+			val = sum
+			if p.n > 0 {
+				val = sum[:p.n]
+			}
+		case "runId":
+			if v.RunID == "" {
+				return "", fmt.Errorf("key template: {runId} is not available here")
+			}
+			val = v.RunID
+		}
+		b.WriteString(val)
+	}
+
+	key := cleanKey(b.String())
+	if key == "" {
+		return "", fmt.Errorf("key template %q rendered an empty key", t.src)
+	}
+	return key, nil
+}
+
+// cleanKey drops empty segments (leading, trailing and doubled slashes).
+// "." and ".." are kept as they are because S3 keys are not paths.
+func cleanKey(k string) string {
+	segs := strings.Split(k, "/")
+	out := segs[:0]
+	for _, s := range segs {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil || h == "" {
+		return "unknown-host"
+	}
+	return h
+}
+
+func userName() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if name == "" {
+		return "unknown-user"
+	}
+	// Windows 는 DOMAIN\user 형태라 키 경로가 갈라지지 않게 바꾼다
+	return strings.ReplaceAll(name, `\`, "_")
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,166 @@
+// Package lockfile implements the advisory lock files that keep two favus
+// processes off the same resource (an upload session, the queue daemon, a
+// queued job). A lock is a file created with O_CREATE|O_EXCL whose JSON
+// records the holder. A lock left behind by a process of this host that no
+// longer runs is stale and taken over.
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// grace is how long an unreadable lock file is assumed to be one that is
+// still being written.
+const grace = 10 * time.Second
+
+// Holder identifies the process holding a lock. Lock files may carry more
+// fields; Holder's are the ones this package reads.
+type Holder struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	StartedAt time.Time `json:"startedAt"`
+	Command   string    `json:"command,omitempty"`
+}
+
+// Self describes the current process.
+func Self() Holder {
+	host, _ := os.Hostname()
+	return Holder{
+		PID:       os.Getpid(),
+		Hostname:  host,
+		StartedAt: time.Now().UTC(),
+		Command:   commandName(),
+	}
+}
+
+// Owner describes the holder, e.g. "pid 4242 on build-01 since 2024-05-17T10:00:00Z".
+func (h Holder) Owner() string {
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Hostname, h.StartedAt.UTC().Format(time.RFC3339))
+}
+
+// Stale reports whether the holder is known to be gone: a process of this
+// host that no longer runs. A lock of another host is never stale, since
+// its process cannot be checked from here.
+func (h Holder) Stale() bool {
+	host, _ := os.Hostname()
+	return h.Hostname == host && !processAlive(h.PID)
+}
+
+// Same reports whether h and o are the same acquisition.
+func (h Holder) Same(o Holder) bool {
+	return h.PID == o.PID && h.Hostname == o.Hostname && h.StartedAt.Equal(o.StartedAt)
+}
+
+// BusyError is returned when a live process holds the lock. Data is the
+// holder's lock file; Holder is zero if the file was still being written.
+type BusyError struct {
+	Path   string
+	Holder Holder
+	Data   []byte
+}
+
+func (e *BusyError) Error() string {
+	if e.Holder.PID == 0 {
+		return fmt.Sprintf("lock %s is being written by another process, try again", e.Path)
+	}
+	return fmt.Sprintf("%s is locked by %s", e.Path, e.Holder.Owner())
+}
+
+// Acquire creates the lock file at path with data, a JSON object that
+// includes the Holder fields of the caller. A stale lock is replaced; a live
+// one fails with *BusyError unless force is set.
+func Acquire(path string, data []byte, force bool) error {
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := f.Write(data)
+			if werr == nil {
+				werr = f.Sync()
+			}
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return fmt.Errorf("write lock %s: %w", path, werr)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("create lock %s: %w", path, err)
+		}
+
+		holder, held, rerr := Read(path)
+		if attempt > 0 {
+			// 낡은 잠금을 지운 사이에 다른 프로세스가 잡았다
+			if rerr == nil {
+				return &BusyError{Path: path, Holder: holder, Data: held}
+			}
+			return &BusyError{Path: path}
+		}
+		switch {
+		case errors.Is(rerr, os.ErrNotExist):
+			// 방금 풀렸다 — 다시 시도
+		case rerr != nil:
+			// 쓰는 도중이거나 깨진 잠금 — 충분히 오래됐으면 버린다
+			if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) < grace && !force {
+				return &BusyError{Path: path}
+			}
+		case !holder.Stale() && !force:
+			return &BusyError{Path: path, Holder: holder, Data: held}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove stale lock %s: %w", path, err)
+		}
+	}
+}
+
+// Release removes the lock file at path if self still holds it.
+func Release(path string, self Holder) {
+	if cur, _, err := Read(path); err == nil && !cur.Same(self) {
+		return // --force 로 다른 프로세스가 가져갔다
+	}
+	_ = os.Remove(path)
+}
+
+// Read returns the holder of the lock file at path and the file's contents.
+func Read(path string) (Holder, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Holder{}, nil, err
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil || h.PID <= 0 {
+		return Holder{}, nil, fmt.Errorf("invalid lock file %s", path)
+	}
+	return h, data, nil
+}
+
+// commandName is the favus subcommand, for the lock file's readers.
+func commandName() string {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		return filepath.Base(os.Args[0]) + " " + os.Args[1]
+	}
+	return filepath.Base(os.Args[0])
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true // FindProcess 가 성공했다면 살아 있다
+	}
+	// EPERM: 다른 사용자의 프로세스지만 살아 있다
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,90 @@
+package mocks3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// requestBody returns the payload of r, stripping aws-chunked framing when the
+// SDK streamed the body with a signed or trailing-checksum encoding.
+func requestBody(r *http.Request) io.Reader {
+	sha := r.Header.Get("X-Amz-Content-Sha256")
+	if strings.HasPrefix(sha, "STREAMING-") || strings.Contains(strings.ToLower(r.Header.Get("Content-Encoding")), "aws-chunked") {
+		return &chunkedReader{br: bufio.NewReader(r.Body)}
+	}
+	return r.Body
+}
+
+// chunkedReader decodes the aws-chunked format:
+//
+//	<hex-size>[;chunk-signature=...]\r\n<data>\r\n ... 0[;...]\r\n[trailers]\r\n
+//
+// Signatures and trailing checksums are ignored.
+type chunkedReader struct {
+	br        *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.br.Read(p)
+	c.remaining -= int64(n)
+	if err == io.EOF && c.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if c.remaining == 0 && err == nil {
+		err = c.skipCRLF()
+	}
+	return n, err
+}
+
+func (c *chunkedReader) nextChunk() error {
+	line, err := c.br.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("aws-chunked: read chunk header: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	sizeHex, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("aws-chunked: bad chunk size %q", line)
+	}
+	if size > 0 {
+		c.remaining = size
+		return nil
+	}
+	// 마지막 청크: trailer 헤더(x-amz-checksum-*)는 빈 줄까지 읽고 버린다
+	c.done = true
+	for {
+		line, err := c.br.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == "" || err != nil {
+			return nil
+		}
+	}
+}
+
+func (c *chunkedReader) skipCRLF() error {
+	line, err := c.br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimRight(line, "\r\n") != "" {
+		return fmt.Errorf("aws-chunked: missing CRLF after chunk data")
+	}
+	return nil
+}
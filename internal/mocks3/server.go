@@ -0,0 +1,566 @@
+// Package mocks3 serves the subset of the S3 REST API Favus uses, backed by a
+// storage.LocalStore. It only understands path-style addressing
+// (http://host:port/<bucket>/<key>) and ignores request signatures, which is
+// all the SDK needs when AWS_ENDPOINT_URL points at it.
+package mocks3
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
+)
+
+// Server is an http.Handler speaking path-style S3.
+type Server struct {
+	Store *storage.LocalStore
+	// Logf, if set, receives one line per request.
+	Logf func(format string, args ...any)
+}
+
+// New returns a server backed by store.
+func New(store *storage.LocalStore) *Server {
+	return &Server{Store: store}
+}
+
+// ServeHTTP routes a request by method, path depth and sub-resource query.
+//
+//	GET    /                                  ListBuckets
+//	PUT    /b                                 CreateBucket
+//	HEAD   /b                                 HeadBucket
+//	GET    /b?list-type=2                     ListObjectsV2
+//	GET    /b?uploads                         ListMultipartUploads
+//	POST   /b/k?uploads                       CreateMultipartUpload
+//	PUT    /b/k?partNumber=N&uploadId=U       UploadPart
+//	GET    /b/k?uploadId=U                    ListParts
+//	POST   /b/k?uploadId=U                    CompleteMultipartUpload
+//	DELETE /b/k?uploadId=U                    AbortMultipartUpload
+//	PUT    /b/k                               PutObject
+//	HEAD   /b/k                               HeadObject
+//	GET    /b/k                               GetObject
+//	DELETE /b/k                               DeleteObject
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		if s.Logf != nil {
+			s.Logf("%s %s → %d (%s)", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+		}
+	}()
+
+	bucket, key := splitPath(r.URL.Path)
+	q := r.URL.Query()
+	var err error
+	switch {
+	case bucket == "":
+		if r.Method != http.MethodGet {
+			err = errMethod(r)
+			break
+		}
+		err = s.listBuckets(rec, r)
+	case key == "":
+		err = s.bucketOp(rec, r, bucket, q)
+	default:
+		err = s.objectOp(rec, r, bucket, key, q)
+	}
+	if err != nil {
+		writeError(rec, r, err)
+	}
+}
+
+func (s *Server) bucketOp(w http.ResponseWriter, r *http.Request, bucket string, q url.Values) error {
+	switch r.Method {
+	case http.MethodPut:
+		if err := s.Store.CreateBucket(bucket); err != nil {
+			return err
+		}
+		w.Header().Set("Location", "/"+bucket)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	case http.MethodHead:
+		if _, err := s.Store.HeadBucket(r.Context(), &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	case http.MethodGet:
+		if _, ok := q["uploads"]; ok {
+			return s.listMultipartUploads(w, r, bucket)
+		}
+		return s.listObjectsV2(w, r, bucket)
+	default:
+		return errMethod(r)
+	}
+}
+
+func (s *Server) objectOp(w http.ResponseWriter, r *http.Request, bucket, key string, q url.Values) error {
+	_, uploads := q["uploads"]
+	uploadID := q.Get("uploadId")
+	switch r.Method {
+	case http.MethodPost:
+		if uploads {
+			return s.createMultipartUpload(w, r, bucket, key)
+		}
+		if uploadID != "" {
+			return s.completeMultipartUpload(w, r, bucket, key, uploadID)
+		}
+	case http.MethodPut:
+		if uploadID != "" {
+			return s.uploadPart(w, r, bucket, key, uploadID)
+		}
+		return s.putObject(w, r, bucket, key)
+	case http.MethodGet:
+		if uploadID != "" {
+			return s.listParts(w, r, bucket, key, uploadID)
+		}
+		return s.getObject(w, r, bucket, key)
+	case http.MethodHead:
+		return s.headObject(w, r, bucket, key)
+	case http.MethodDelete:
+		if uploadID != "" {
+			_, err := s.Store.AbortMultipartUpload(r.Context(), &s3.AbortMultipartUploadInput{
+				Bucket: aws.String(bucket), Key: aws.String(key), UploadId: aws.String(uploadID),
+			})
+			if err != nil {
+				return err
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		if _, err := s.Store.DeleteObject(r.Context(), &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errMethod(r)
+}
+
+// ===================== buckets =====================
+
+func (s *Server) listBuckets(w http.ResponseWriter, r *http.Request) error {
+	out, err := s.Store.ListBuckets(r.Context(), &s3.ListBucketsInput{})
+	if err != nil {
+		return err
+	}
+	res := listAllMyBucketsResult{Xmlns: s3Namespace, Owner: owner{ID: "favus", DisplayName: "favus"}}
+	for _, b := range out.Buckets {
+		res.Buckets = append(res.Buckets, bucketInfo{Name: aws.ToString(b.Name), CreationDate: xmlTime(aws.ToTime(b.CreationDate))})
+	}
+	return writeXML(w, http.StatusOK, res)
+}
+
+func (s *Server) listObjectsV2(w http.ResponseWriter, r *http.Request, bucket string) error {
+	q := r.URL.Query()
+	in := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(bucket),
+		Prefix:     optString(q.Get("prefix")),
+		Delimiter:  optString(q.Get("delimiter")),
+		StartAfter: optString(q.Get("start-after")),
+	}
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return apiError(http.StatusBadRequest, "InvalidArgument", "invalid max-keys %q", v)
+		}
+		in.MaxKeys = aws.Int32(int32(n))
+	}
+	if tok := q.Get("continuation-token"); tok != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(tok)
+		if err != nil {
+			return apiError(http.StatusBadRequest, "InvalidArgument", "invalid continuation token")
+		}
+		in.ContinuationToken = aws.String(string(raw))
+	}
+	out, err := s.Store.ListObjectsV2(r.Context(), in)
+	if err != nil {
+		return err
+	}
+	res := listBucketResult{
+		Xmlns:             s3Namespace,
+		Name:              bucket,
+		Prefix:            q.Get("prefix"),
+		Delimiter:         q.Get("delimiter"),
+		StartAfter:        q.Get("start-after"),
+		ContinuationToken: q.Get("continuation-token"),
+		MaxKeys:           aws.ToInt32(out.MaxKeys),
+		KeyCount:          aws.ToInt32(out.KeyCount),
+		IsTruncated:       aws.ToBool(out.IsTruncated),
+	}
+	// 내부 토큰은 XML에 넣을 수 없는 바이트를 포함할 수 있어 base64로 감싼다
+	if tok := aws.ToString(out.NextContinuationToken); tok != "" {
+		res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(tok))
+	}
+	for _, o := range out.Contents {
+		res.Contents = append(res.Contents, objectInfo{
+			Key:          aws.ToString(o.Key),
+			LastModified: xmlTime(aws.ToTime(o.LastModified)),
+			ETag:         aws.ToString(o.ETag),
+			Size:         aws.ToInt64(o.Size),
+			StorageClass: string(s3types.ObjectStorageClassStandard),
+		})
+	}
+	for _, p := range out.CommonPrefixes {
+		res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{Prefix: aws.ToString(p.Prefix)})
+	}
+	return writeXML(w, http.StatusOK, res)
+}
+
+func (s *Server) listMultipartUploads(w http.ResponseWriter, r *http.Request, bucket string) error {
+	q := r.URL.Query()
+	in := &s3.ListMultipartUploadsInput{
+		Bucket:         aws.String(bucket),
+		Prefix:         optString(q.Get("prefix")),
+		KeyMarker:      optString(q.Get("key-marker")),
+		UploadIdMarker: optString(q.Get("upload-id-marker")),
+	}
+	if v := q.Get("max-uploads"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return apiError(http.StatusBadRequest, "InvalidArgument", "invalid max-uploads %q", v)
+		}
+		in.MaxUploads = aws.Int32(int32(n))
+	}
+	out, err := s.Store.ListMultipartUploads(r.Context(), in)
+	if err != nil {
+		return err
+	}
+	res := listMultipartUploadsResult{
+		Xmlns:          s3Namespace,
+		Bucket:         bucket,
+		KeyMarker:      q.Get("key-marker"),
+		UploadIDMarker: q.Get("upload-id-marker"),
+		Prefix:         q.Get("prefix"),
+		MaxUploads:     aws.ToInt32(out.MaxUploads),
+		IsTruncated:    aws.ToBool(out.IsTruncated),
+	}
+	if res.IsTruncated {
+		res.NextKeyMarker = aws.ToString(out.NextKeyMarker)
+		res.NextUploadIDMarker = aws.ToString(out.NextUploadIdMarker)
+	}
+	for _, u := range out.Uploads {
+		res.Uploads = append(res.Uploads, uploadInfo{
+			Key:          aws.ToString(u.Key),
+			UploadID:     aws.ToString(u.UploadId),
+			Initiated:    xmlTime(aws.ToTime(u.Initiated)),
+			StorageClass: string(s3types.StorageClassStandard),
+		})
+	}
+	return writeXML(w, http.StatusOK, res)
+}
+
+// ===================== multipart =====================
+
+func (s *Server) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	out, err := s.Store.CreateMultipartUpload(r.Context(), &s3.CreateMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		ContentType:     optString(r.Header.Get("Content-Type")),
+		ContentEncoding: optString(contentEncoding(r)),
+		Metadata:        userMetadata(r.Header),
+	})
+	if err != nil {
+		return err
+	}
+	return writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: aws.ToString(out.UploadId),
+	})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	n, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || n < 1 || n > 10000 {
+		return apiError(http.StatusBadRequest, "InvalidArgument", "partNumber must be an integer between 1 and 10000")
+	}
+	out, err := s.Store.UploadPart(r.Context(), &s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int32(int32(n)),
+		Body:       requestBody(r),
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", aws.ToString(out.ETag))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) listParts(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	q := r.URL.Query()
+	in := &s3.ListPartsInput{
+		Bucket:           aws.String(bucket),
+		Key:              aws.String(key),
+		UploadId:         aws.String(uploadID),
+		PartNumberMarker: optString(q.Get("part-number-marker")),
+	}
+	if v := q.Get("max-parts"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return apiError(http.StatusBadRequest, "InvalidArgument", "invalid max-parts %q", v)
+		}
+		in.MaxParts = aws.Int32(int32(n))
+	}
+	out, err := s.Store.ListParts(r.Context(), in)
+	if err != nil {
+		return err
+	}
+	res := listPartsResult{
+		Xmlns:                s3Namespace,
+		Bucket:               bucket,
+		Key:                  key,
+		UploadID:             uploadID,
+		PartNumberMarker:     q.Get("part-number-marker"),
+		NextPartNumberMarker: aws.ToString(out.NextPartNumberMarker),
+		MaxParts:             aws.ToInt32(out.MaxParts),
+		IsTruncated:          aws.ToBool(out.IsTruncated),
+		StorageClass:         string(s3types.StorageClassStandard),
+	}
+	if res.PartNumberMarker == "" {
+		res.PartNumberMarker = "0"
+	}
+	for _, p := range out.Parts {
+		res.Parts = append(res.Parts, partInfo{
+			PartNumber:   aws.ToInt32(p.PartNumber),
+			LastModified: xmlTime(aws.ToTime(p.LastModified)),
+			ETag:         aws.ToString(p.ETag),
+			Size:         aws.ToInt64(p.Size),
+		})
+	}
+	return writeXML(w, http.StatusOK, res)
+}
+
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) error {
+	var body completeMultipartUpload
+	if err := xml.NewDecoder(requestBody(r)).Decode(&body); err != nil {
+		return apiError(http.StatusBadRequest, "MalformedXML", "parse CompleteMultipartUpload body: %v", err)
+	}
+	parts := make([]s3types.CompletedPart, 0, len(body.Parts))
+	for _, p := range body.Parts {
+		parts = append(parts, s3types.CompletedPart{ETag: aws.String(p.ETag), PartNumber: aws.Int32(p.PartNumber)})
+	}
+	out, err := s.Store.CompleteMultipartUpload(r.Context(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return err
+	}
+	return writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: fmt.Sprintf("http://%s/%s/%s", r.Host, bucket, key),
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     aws.ToString(out.ETag),
+	})
+}
+
+// ===================== objects =====================
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	out, err := s.Store.PutObject(r.Context(), &s3.PutObjectInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		Body:            requestBody(r),
+		ContentType:     optString(r.Header.Get("Content-Type")),
+		ContentEncoding: optString(contentEncoding(r)),
+		Metadata:        userMetadata(r.Header),
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", aws.ToString(out.ETag))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) headObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	out, err := s.Store.HeadObject(r.Context(), &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return err
+	}
+	setObjectHeaders(w.Header(), out.ETag, out.LastModified, out.ContentType, out.ContentEncoding, out.Metadata)
+	w.Header().Set("Content-Length", strconv.FormatInt(aws.ToInt64(out.ContentLength), 10))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	out, err := s.Store.GetObject(r.Context(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  optString(r.Header.Get("Range")),
+	})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	h := w.Header()
+	setObjectHeaders(h, out.ETag, out.LastModified, out.ContentType, out.ContentEncoding, out.Metadata)
+	h.Set("Content-Length", strconv.FormatInt(aws.ToInt64(out.ContentLength), 10))
+	status := http.StatusOK
+	if cr := aws.ToString(out.ContentRange); cr != "" {
+		h.Set("Content-Range", cr)
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+	_, _ = io.Copy(w, out.Body)
+	return nil
+}
+
+// ===================== helpers =====================
+
+// splitPath turns /bucket/some/key into ("bucket", "some/key").
+func splitPath(p string) (string, string) {
+	p = strings.TrimPrefix(p, "/")
+	bucket, key, _ := strings.Cut(p, "/")
+	return bucket, key
+}
+
+func optString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return aws.String(v)
+}
+
+// contentEncoding returns the Content-Encoding header without the aws-chunked
+// transfer marker the SDK adds for streaming uploads.
+func contentEncoding(r *http.Request) string {
+	var out []string
+	for _, enc := range strings.Split(r.Header.Get("Content-Encoding"), ",") {
+		enc = strings.TrimSpace(enc)
+		if enc != "" && !strings.EqualFold(enc, "aws-chunked") {
+			out = append(out, enc)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+func userMetadata(h http.Header) map[string]string {
+	var md map[string]string
+	for name, vals := range h {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "x-amz-meta-") || len(vals) == 0 {
+			continue
+		}
+		if md == nil {
+			md = make(map[string]string)
+		}
+		md[strings.TrimPrefix(lower, "x-amz-meta-")] = vals[0]
+	}
+	return md
+}
+
+func setObjectHeaders(h http.Header, etag *string, modified *time.Time, ctype, cenc *string, md map[string]string) {
+	h.Set("Accept-Ranges", "bytes")
+	h.Set("ETag", aws.ToString(etag))
+	h.Set("Last-Modified", aws.ToTime(modified).UTC().Format(http.TimeFormat))
+	if v := aws.ToString(ctype); v != "" {
+		h.Set("Content-Type", v)
+	} else {
+		h.Set("Content-Type", "binary/octet-stream")
+	}
+	if v := aws.ToString(cenc); v != "" {
+		h.Set("Content-Encoding", v)
+	}
+	for k, v := range md {
+		h.Set("x-amz-meta-"+k, v)
+	}
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header)
+	_, _ = w.Write(data)
+	return nil
+}
+
+// httpError carries an explicit status for errors raised by the server itself.
+type httpError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *httpError) Error() string { return e.code + ": " + e.msg }
+
+func apiError(status int, code, format string, args ...any) error {
+	return &httpError{status: status, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+func errMethod(r *http.Request) error {
+	return apiError(http.StatusMethodNotAllowed, "MethodNotAllowed", "%s %s is not supported by favus mock-s3", r.Method, r.URL.Path)
+}
+
+// statusFor maps store error codes to the HTTP status S3 would use.
+func statusFor(code string) int {
+	switch code {
+	case "NoSuchBucket", "NoSuchKey", "NoSuchUpload", "NotFound":
+		return http.StatusNotFound
+	case "InvalidRange":
+		return http.StatusRequestedRangeNotSatisfiable
+	case "EntityTooSmall", "InvalidPart", "InvalidPartOrder", "IncompleteBody",
+		"MalformedXML", "InvalidBucketName", "InvalidArgument":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, msg := http.StatusInternalServerError, "InternalError", err.Error()
+	var he *httpError
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &he):
+		status, code, msg = he.status, he.code, he.msg
+	case errors.As(err, &apiErr):
+		code, msg = apiErr.ErrorCode(), apiErr.ErrorMessage()
+		status = statusFor(code)
+	}
+
+	reqID := uuid.NewString()
+	w.Header().Set("x-amz-request-id", reqID)
+	if r.Method == http.MethodHead {
+		// HEAD 응답은 본문이 없으므로 상태 코드만 전달된다
+		w.WriteHeader(status)
+		return
+	}
+	_ = writeXML(w, status, errorResponse{Code: code, Message: msg, Resource: r.URL.Path, RequestID: reqID})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,303 @@
+// Package notify posts upload session events (session_done, error and a
+// synthesized stalled) to webhooks. It consumes the wsagent.Event stream the
+// uploader's reporters produce, either inside the CLI process or inside the
+// UI agent (notify.runIn), so no Web UI is needed for notifications.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/wsagent"
+	"github.com/GoCOMA/Favus/pkg/utils"
+)
+
+// Events a webhook can subscribe to.
+const (
+	EventSessionDone = "session_done"
+	EventError       = "error"
+	EventStalled     = "stalled"
+)
+
+// DefaultStallAfter is used when notify.stallAfter is not set.
+const DefaultStallAfter = 2 * time.Minute
+
+// Message is what a webhook receives by default, and what body templates see.
+type Message struct {
+	Event     string         `json:"event"`
+	RunID     string         `json:"runId"`
+	Timestamp time.Time      `json:"ts"`
+	Summary   string         `json:"summary"`           // one line for chat, e.g. "✅ favus upload s3://b/k finished in 3m2s (4.0 GiB)"
+	Payload   map[string]any `json:"payload"`           // the event payload as the reporter sent it
+	Session   map[string]any `json:"session,omitempty"` // session_start payload: bucket, key, uploadId, total, ...
+}
+
+// Notifier turns events into webhook deliveries. Handle never blocks on the
+// network; Close waits for deliveries still in flight.
+type Notifier struct {
+	targets    []*target
+	stallAfter time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session // by run ID
+
+	wg       sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type session struct {
+	start     map[string]any
+	lastSeen  time.Time // last session_start / progress / part event
+	bytes     float64
+	total     float64
+	lastError string
+	stalled   bool
+}
+
+// New builds a notifier from cfg. It returns nil (and no error) when no
+// webhook is configured; templates and URLs are checked here, up front.
+func New(cfg config.Notify) (*Notifier, error) {
+	switch cfg.RunIn {
+	case "", config.NotifyInCLI, config.NotifyInAgent:
+	default:
+		return nil, fmt.Errorf("notify.runIn must be %q or %q, got %q", config.NotifyInCLI, config.NotifyInAgent, cfg.RunIn)
+	}
+	if len(cfg.Webhooks) == 0 {
+		return nil, nil
+	}
+	n := &Notifier{
+		stallAfter: cfg.StallAfter,
+		sessions:   make(map[string]*session),
+		stop:       make(chan struct{}),
+	}
+	if n.stallAfter <= 0 {
+		n.stallAfter = DefaultStallAfter
+	}
+	for i, w := range cfg.Webhooks {
+		t, err := newTarget(w)
+		if err != nil {
+			return nil, fmt.Errorf("notify.webhooks[%d]: %w", i, err)
+		}
+		n.targets = append(n.targets, t)
+	}
+	go n.watchStalls()
+	return n, nil
+}
+
+// Handle feeds one reporter event into the notifier. Safe for concurrent use.
+func (n *Notifier) Handle(ev wsagent.Event) {
+	var payload map[string]any
+	if len(ev.Payload) > 0 {
+		_ = json.Unmarshal(ev.Payload, &payload)
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+
+	n.mu.Lock()
+	s := n.sessions[ev.RunID]
+	if ev.Type == "session_start" {
+		s = &session{}
+		n.sessions[ev.RunID] = s
+	} else if s == nil {
+		s = &session{} // 시작을 못 본 세션 (에이전트가 나중에 뜬 경우 등)은 추적하지 않는다
+	}
+	var deliver *Message
+	switch ev.Type {
+	case "session_start":
+		s.start = payload
+		s.total = number(payload["total"])
+		s.lastSeen = time.Now()
+	case "total_progress":
+		s.bytes = number(payload["bytes"])
+		s.lastSeen, s.stalled = time.Now(), false
+	case "part_start", "part_progress", "part_done":
+		s.lastSeen, s.stalled = time.Now(), false
+	case EventError:
+		// 워커 여러 개가 같이 실패해도 세션당 한 번만 알린다
+		first := s.lastError == ""
+		s.lastError, _ = payload["message"].(string)
+		if first {
+			deliver = newMessage(EventError, ev.RunID, ev.Timestamp, payload, s)
+		}
+	case EventSessionDone:
+		deliver = newMessage(EventSessionDone, ev.RunID, ev.Timestamp, payload, s)
+		delete(n.sessions, ev.RunID)
+	}
+	n.mu.Unlock()
+
+	if deliver != nil {
+		n.dispatch(*deliver)
+	}
+}
+
+// Close stops stall detection and waits for pending deliveries until ctx ends.
+func (n *Notifier) Close(ctx context.Context) {
+	n.stopOnce.Do(func() { close(n.stop) })
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		utils.Error("notify: gave up waiting for webhook deliveries")
+	}
+}
+
+// Deliver posts msg to every webhook subscribed to msg.Event and waits for
+// the results (used by favus notify test). Retries apply as usual.
+func (n *Notifier) Deliver(ctx context.Context, msg Message) (sent int, err error) {
+	var errs []error
+	for _, t := range n.targets {
+		if !t.events[msg.Event] {
+			continue
+		}
+		sent++
+		if err := t.deliver(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Sample returns a made-up message for event, for favus notify test.
+func Sample(event string) Message {
+	start := map[string]any{"bucket": "example-bucket", "key": "backups/db.dump", "uploadId": "example-upload-id", "total": 52499330}
+	payload := map[string]any{}
+	s := &session{start: start, total: 52499330, bytes: 52499330}
+	switch event {
+	case EventSessionDone:
+		payload = map[string]any{"success": true, "uploadId": "example-upload-id", "duration": "3m2s", "bytes": 52499330, "total": 52499330}
+	case EventError:
+		payload = map[string]any{"message": "upload part 3 failed after retries: example error"}
+	case EventStalled:
+		payload = map[string]any{"idleFor": DefaultStallAfter.String(), "bytes": 26249665, "total": 52499330}
+		s.bytes = 26249665
+	}
+	return *newMessage(event, "example-run-id", time.Now(), payload, s)
+}
+
+func (n *Notifier) dispatch(msg Message) {
+	for _, t := range n.targets {
+		if !t.events[msg.Event] {
+			continue
+		}
+		n.wg.Add(1)
+		go func(t *target) {
+			defer n.wg.Done()
+			if err := t.deliver(context.Background(), msg); err != nil {
+				utils.Error(fmt.Sprintf("notify: %v", err))
+			}
+		}(t)
+	}
+}
+
+// watchStalls reports sessions without progress for stallAfter, once per stall.
+func (n *Notifier) watchStalls() {
+	tick := time.NewTicker(max(n.stallAfter/4, time.Second))
+	defer tick.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case now := <-tick.C:
+			var stalled []Message
+			n.mu.Lock()
+			for runID, s := range n.sessions {
+				if s.start == nil || s.stalled || now.Sub(s.lastSeen) < n.stallAfter {
+					continue
+				}
+				s.stalled = true
+				payload := map[string]any{
+					"idleFor": now.Sub(s.lastSeen).Round(time.Second).String(),
+					"bytes":   s.bytes,
+					"total":   s.total,
+				}
+				stalled = append(stalled, *newMessage(EventStalled, runID, now, payload, s))
+			}
+			n.mu.Unlock()
+			for _, m := range stalled {
+				n.dispatch(m)
+			}
+		}
+	}
+}
+
+func newMessage(event, runID string, ts time.Time, payload map[string]any, s *session) *Message {
+	target := "run " + runID
+	if s.start != nil {
+		target = fmt.Sprintf("s3://%v/%v", s.start["bucket"], s.start["key"])
+	}
+	var summary string
+	switch event {
+	case EventSessionDone:
+		// 재시도로 다시 읽은 바이트도 progress 에 섞이므로 크기는 세션 total 기준
+		size := s.total
+		if size <= 0 {
+			size = number(payload["bytes"])
+		}
+		if ok, _ := payload["success"].(bool); ok {
+			summary = fmt.Sprintf("✅ favus upload %s finished in %s (%s)", target, roundDuration(payload["duration"]), humanBytes(size))
+		} else {
+			summary = fmt.Sprintf("❌ favus upload %s failed after %s", target, roundDuration(payload["duration"]))
+			if s.lastError != "" {
+				summary += ": " + s.lastError
+			}
+		}
+	case EventError:
+		summary = fmt.Sprintf("❌ favus upload %s: %v", target, payload["message"])
+	case EventStalled:
+		summary = fmt.Sprintf("⏸ favus upload %s stalled: no progress for %v", target, payload["idleFor"])
+		if s.total > 0 {
+			summary += fmt.Sprintf(" (%.0f%% done)", min(s.bytes/s.total*100, 100))
+		}
+	}
+	return &Message{Event: event, RunID: runID, Timestamp: ts, Summary: summary, Payload: payload, Session: s.start}
+}
+
+func number(v any) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	}
+	return 0
+}
+
+// roundDuration shortens the reporter's duration string ("5.203334351s" → "5.2s").
+func roundDuration(v any) string {
+	str := fmt.Sprint(v)
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return str
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func humanBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", b), ".0") + " " + units[i]
+}
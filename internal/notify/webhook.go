@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/faults"
+	"github.com/GoCOMA/Favus/pkg/utils"
+)
+
+// Headers set on every delivery. The signature is
+// hex(HMAC-SHA256(secret, timestamp + "." + body)) and is only sent when the
+// webhook has a secret.
+const (
+	SignatureHeader = "X-Favus-Signature" // sha256=<hex>
+	TimestampHeader = "X-Favus-Timestamp" // Unix seconds, part of the signed data
+	EventHeader     = "X-Favus-Event"
+)
+
+const (
+	defaultRetries = 3
+	defaultTimeout = 10 * time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+type target struct {
+	url     string
+	events  map[string]bool
+	body    *template.Template // nil: send the Message as JSON
+	secret  string
+	headers map[string]string
+	retries int
+	client  *http.Client
+}
+
+// newTarget validates w. ${ENV} references in the URL, secret and header
+// values are expanded so secrets can stay out of the config file.
+func newTarget(w config.Webhook) (*target, error) {
+	t := &target{
+		url:     os.ExpandEnv(strings.TrimSpace(w.URL)),
+		events:  make(map[string]bool),
+		secret:  os.ExpandEnv(w.Secret),
+		headers: make(map[string]string, len(w.Headers)),
+		retries: w.Retries,
+	}
+	u, err := url.Parse(t.url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q must be an http(s) URL", w.URL)
+	}
+	events := w.Events
+	if len(events) == 0 {
+		events = []string{EventSessionDone, EventError}
+	}
+	for _, e := range events {
+		switch e {
+		case EventSessionDone, EventError, EventStalled:
+			t.events[e] = true
+		default:
+			return nil, fmt.Errorf("unknown event %q (expected %s, %s or %s)", e, EventSessionDone, EventError, EventStalled)
+		}
+	}
+	if strings.TrimSpace(w.Body) != "" {
+		t.body, err = template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(w.Body)
+		if err != nil {
+			return nil, fmt.Errorf("body template: %w", err)
+		}
+	}
+	for k, v := range w.Headers {
+		t.headers[k] = os.ExpandEnv(v)
+	}
+	if t.retries <= 0 {
+		t.retries = defaultRetries
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	t.client = &http.Client{Timeout: timeout, Transport: faults.Transport(http.DefaultTransport)}
+	return t, nil
+}
+
+// deliver renders msg and POSTs it, retrying network errors, 429 and 5xx
+// with exponential backoff (1s, 2s, 4s, ... up to 30s).
+func (t *target) deliver(ctx context.Context, msg Message) error {
+	body, err := t.render(msg)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", t.url, err)
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := t.post(ctx, msg.Event, body)
+		if err == nil {
+			utils.Info(fmt.Sprintf("notify: %s for run %s delivered to %s", msg.Event, msg.RunID, t.url))
+			return nil
+		}
+		if !retry || attempt >= t.retries {
+			return fmt.Errorf("webhook %s: %s not delivered after %d attempt(s): %w", t.url, msg.Event, attempt+1, err)
+		}
+		utils.Info(fmt.Sprintf("notify: webhook %s failed (%v), retrying in %s", t.url, err, backoff))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w", t.url, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (t *target) render(msg Message) ([]byte, error) {
+	if t.body == nil {
+		return json.Marshal(msg)
+	}
+	var buf bytes.Buffer
+	if err := t.body.Execute(&buf, msg); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("body template did not produce valid JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends one request; retry reports whether a failure is worth retrying.
+func (t *target) post(ctx context.Context, event string, body []byte) (retry bool, err error) {
+	ctx = faults.WithOperation(ctx, "Webhook")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "favus-notify")
+	req.Header.Set(EventHeader, event)
+	if t.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(t.secret, ts, body))
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return false, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	err = fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(snippet)))
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp + "." + body, as sent in
+// X-Favus-Signature (after "sha256=").
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// toJSON is the body template's json function: {{ json .Summary }} gives a
+// quoted, escaped JSON string.
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,281 @@
+// Package presign creates time-limited S3 URLs so that someone without AWS
+// credentials can download an object, upload one, or upload the parts of a
+// multipart upload that Favus created and later completes.
+package presign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// MaxExpires is the SigV4 limit for presigned URLs.
+	MaxExpires = 7 * 24 * time.Hour
+	// MaxParts is the S3 limit for parts in one multipart upload.
+	MaxParts = 10000
+)
+
+// URL is a presigned request the holder can send without credentials.
+type URL struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Header  http.Header `json:"header,omitempty"` // headers that must be sent as signed
+	Expires time.Time   `json:"expires"`
+}
+
+// Signer presigns S3 requests with the credentials of an S3 client.
+type Signer struct {
+	Store   storage.ObjectStore
+	presign *s3.PresignClient
+}
+
+// New builds a Signer for an S3 store. file:// and mem:// stores cannot be
+// presigned.
+func New(store storage.ObjectStore) (*Signer, error) {
+	s3s, ok := store.(*storage.S3Store)
+	if !ok {
+		return nil, fmt.Errorf("presign: only S3 buckets can be presigned")
+	}
+	return &Signer{Store: store, presign: s3.NewPresignClient(s3s.Client)}, nil
+}
+
+// ValidateExpires checks d against SigV4 limits.
+func ValidateExpires(d time.Duration) error {
+	if d <= 0 || d > MaxExpires {
+		return fmt.Errorf("presign: expiry must be between 1s and %s, got %s", MaxExpires, d)
+	}
+	return nil
+}
+
+func wrap(method string, expires time.Duration) func(url string, header http.Header) *URL {
+	at := time.Now().Add(expires).UTC().Truncate(time.Second)
+	return func(url string, header http.Header) *URL {
+		header = header.Clone()
+		header.Del("Host") // sent by every client anyway
+		if len(header) == 0 {
+			header = nil
+		}
+		return &URL{Method: method, URL: url, Header: header, Expires: at}
+	}
+}
+
+// Get presigns a GetObject request.
+func (s *Signer) Get(ctx context.Context, bucket, key string, expires time.Duration) (*URL, error) {
+	if err := ValidateExpires(expires); err != nil {
+		return nil, err
+	}
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("presign get %s/%s: %w", bucket, key, err)
+	}
+	return wrap(req.Method, expires)(req.URL, req.SignedHeader), nil
+}
+
+// Put presigns a PutObject request. contentType is optional; when set the
+// uploader must send the same Content-Type header.
+func (s *Signer) Put(ctx context.Context, bucket, key, contentType string, expires time.Duration) (*URL, error) {
+	if err := ValidateExpires(expires); err != nil {
+		return nil, err
+	}
+	in := &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+	if contentType != "" {
+		in.ContentType = aws.String(contentType)
+	}
+	req, err := s.presign.PresignPutObject(ctx, in, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("presign put %s/%s: %w", bucket, key, err)
+	}
+	return wrap(req.Method, expires)(req.URL, req.SignedHeader), nil
+}
+
+// Part presigns one UploadPart request of an existing multipart upload.
+func (s *Signer) Part(ctx context.Context, bucket, key, uploadID string, partNumber int32, expires time.Duration) (*URL, error) {
+	if err := ValidateExpires(expires); err != nil {
+		return nil, err
+	}
+	if partNumber < 1 || partNumber > MaxParts {
+		return nil, fmt.Errorf("presign: part number %d out of range 1..%d", partNumber, MaxParts)
+	}
+	req, err := s.presign.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(key),
+		UploadId:   aws.String(uploadID),
+		PartNumber: aws.Int32(partNumber),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("presign part %d: %w", partNumber, err)
+	}
+	return wrap(req.Method, expires)(req.URL, req.SignedHeader), nil
+}
+
+// Part is one entry of a multipart Manifest. The uploader fills in ETag with
+// the ETag response header of its PUT.
+type Part struct {
+	PartNumber int32     `json:"partNumber"`
+	URL        string    `json:"url,omitempty"`
+	Expires    time.Time `json:"expires,omitempty"`
+	ETag       string    `json:"etag,omitempty"`
+}
+
+// Manifest describes a presigned multipart upload. It is handed out with part
+// URLs and comes back (with ETags) to complete the upload.
+type Manifest struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	UploadID string `json:"uploadId"`
+	Parts    []Part `json:"parts"`
+}
+
+// CreateMultipart starts a multipart upload and presigns parts URLs for it.
+func (s *Signer) CreateMultipart(ctx context.Context, bucket, key string, parts int, expires time.Duration) (*Manifest, error) {
+	if parts < 1 || parts > MaxParts {
+		return nil, fmt.Errorf("presign: parts must be between 1 and %d, got %d", MaxParts, parts)
+	}
+	if err := ValidateExpires(expires); err != nil {
+		return nil, err
+	}
+	out, err := s.Store.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create multipart upload: %w", err)
+	}
+	m := &Manifest{Bucket: bucket, Key: key, UploadID: aws.ToString(out.UploadId), Parts: make([]Part, 0, parts)}
+	for n := int32(1); n <= int32(parts); n++ {
+		u, err := s.Part(ctx, bucket, key, m.UploadID, n, expires)
+		if err != nil {
+			return m, err // the upload exists; caller can abort it
+		}
+		m.Parts = append(m.Parts, Part{PartNumber: n, URL: u.URL, Expires: u.Expires})
+	}
+	return m, nil
+}
+
+// LoadManifest reads a manifest written by Save (or edited by the uploader).
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	if m.Bucket == "" || m.Key == "" || m.UploadID == "" {
+		return nil, fmt.Errorf("manifest %s: bucket, key and uploadId are required", path)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest as indented JSON.
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Complete finishes the upload described by m. Parts without an ETag are
+// looked up with ListParts, so a manifest with only part numbers (or none at
+// all) works as long as the parts were uploaded.
+func Complete(ctx context.Context, store storage.ObjectStore, m *Manifest) (*s3.CompleteMultipartUploadOutput, error) {
+	etags := make(map[int32]string, len(m.Parts))
+	for _, p := range m.Parts {
+		if p.ETag != "" {
+			etags[p.PartNumber] = p.ETag
+		}
+	}
+	uploaded, err := listParts(ctx, store, m)
+	if err != nil {
+		return nil, err
+	}
+
+	want := make([]int32, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		want = append(want, p.PartNumber)
+	}
+	if len(want) == 0 {
+		for n := range uploaded {
+			want = append(want, n)
+		}
+	}
+	if len(want) == 0 {
+		return nil, fmt.Errorf("no parts to complete for upload %s", m.UploadID)
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+	var missing []int32
+	parts := make([]s3types.CompletedPart, 0, len(want))
+	for _, n := range want {
+		et, ok := etags[n]
+		if !ok {
+			et, ok = uploaded[n]
+		}
+		if !ok {
+			missing = append(missing, n)
+			continue
+		}
+		parts = append(parts, s3types.CompletedPart{PartNumber: aws.Int32(n), ETag: aws.String(et)})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("parts not uploaded yet: %v", missing)
+	}
+
+	out, err := store.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(m.Bucket),
+		Key:             aws.String(m.Key),
+		UploadId:        aws.String(m.UploadID),
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("complete multipart upload: %w", err)
+	}
+	return out, nil
+}
+
+// Abort cancels the upload described by m.
+func Abort(ctx context.Context, store storage.ObjectStore, m *Manifest) error {
+	_, err := store.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(m.Bucket),
+		Key:      aws.String(m.Key),
+		UploadId: aws.String(m.UploadID),
+	})
+	if err != nil {
+		return fmt.Errorf("abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+func listParts(ctx context.Context, store storage.ObjectStore, m *Manifest) (map[int32]string, error) {
+	uploaded := make(map[int32]string)
+	p := s3.NewListPartsPaginator(store, &s3.ListPartsInput{
+		Bucket:   aws.String(m.Bucket),
+		Key:      aws.String(m.Key),
+		UploadId: aws.String(m.UploadID),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list parts: %w", err)
+		}
+		for _, part := range page.Parts {
+			uploaded[aws.ToInt32(part.PartNumber)] = aws.ToString(part.ETag)
+		}
+	}
+	return uploaded, nil
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/lockfile"
+)
+
+// interruptedMsg is recorded on jobs re-queued because a daemon stopped.
+const interruptedMsg = "interrupted by daemon shutdown; re-queued"
+
+// errStateChanged means another process moved the job on in the meantime.
+var errStateChanged = errors.New("queue: job state changed")
+
+// Runner executes one job. The daemon records its result on the job.
+type Runner func(ctx context.Context, job *Job) error
+
+// Daemon works through the queue, running at most MaxJobs jobs at a time.
+type Daemon struct {
+	Store        *Store
+	Run          Runner
+	MaxJobs      int
+	PollInterval time.Duration
+
+	mu      sync.Mutex
+	running map[string]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewDaemon creates a daemon with sane defaults (1 job at a time, 2s polling).
+func NewDaemon(store *Store, run Runner, maxJobs int) *Daemon {
+	if maxJobs <= 0 {
+		maxJobs = 1
+	}
+	return &Daemon{
+		Store:        store,
+		Run:          run,
+		MaxJobs:      maxJobs,
+		PollInterval: 2 * time.Second,
+		running:      make(map[string]struct{}),
+	}
+}
+
+// Start acquires the daemon lock, recovers jobs left running by a previous
+// daemon and processes the queue until ctx is canceled. Canceling ctx also
+// cancels the running jobs; Start waits for them to stop and releases the
+// lock. Jobs interrupted by the shutdown go back to pending.
+func (d *Daemon) Start(ctx context.Context) error {
+	release, err := d.lock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if n, err := d.Recover(); err != nil {
+		return err
+	} else if n > 0 {
+		fmt.Printf("🔁 queue: re-queued %d job(s) interrupted by a previous shutdown\n", n)
+	}
+
+	t := time.NewTicker(d.PollInterval)
+	defer t.Stop()
+	for {
+		d.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			if n := d.runningCount(); n > 0 {
+				fmt.Printf("⏳ queue: stopping %d running job(s)...\n", n)
+			}
+			d.wg.Wait()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Recover moves jobs stuck in the running state back to pending. Their
+// UploadID and StatusFile are kept so the next run resumes the upload.
+// It must only be called while holding the daemon lock.
+func (d *Daemon) Recover() (int, error) {
+	jobs, err := d.Store.List()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, j := range jobs {
+		if j.State != StateRunning {
+			continue
+		}
+		_, err := d.Store.Update(j.ID, func(cur *Job) error {
+			if cur.State != StateRunning {
+				return errStateChanged
+			}
+			cur.State = StatePending
+			cur.Error = interruptedMsg
+			return nil
+		})
+		if errors.Is(err, errStateChanged) {
+			continue
+		} else if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Wait blocks until all jobs started by this daemon have finished.
+func (d *Daemon) Wait() { d.wg.Wait() }
+
+func (d *Daemon) dispatch(ctx context.Context) {
+	if ctx.Err() != nil || d.Store.IsPaused() {
+		return
+	}
+	jobs, err := d.Store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: queue: list jobs: %v\n", err)
+		return
+	}
+	for _, j := range jobs {
+		if j.State != StatePending {
+			continue
+		}
+		d.mu.Lock()
+		if len(d.running) >= d.MaxJobs {
+			d.mu.Unlock()
+			return
+		}
+		d.running[j.ID] = struct{}{}
+		d.mu.Unlock()
+
+		// 목록을 읽은 뒤 사용자가 취소/일시정지했을 수 있으니 잠금 아래에서 다시 확인한다
+		claimed, err := d.Store.Update(j.ID, func(cur *Job) error {
+			if cur.State != StatePending {
+				return errStateChanged
+			}
+			cur.State = StateRunning
+			cur.Attempts++
+			cur.StartedAt = time.Now()
+			cur.Error = ""
+			return nil
+		})
+		if err != nil {
+			if !errors.Is(err, errStateChanged) {
+				fmt.Fprintf(os.Stderr, "warn: queue: claim job %s: %v\n", j.ShortID(), err)
+			}
+			d.release(j.ID)
+			continue
+		}
+
+		d.wg.Add(1)
+		go d.execute(ctx, claimed)
+	}
+}
+
+func (d *Daemon) execute(ctx context.Context, job *Job) {
+	defer d.wg.Done()
+	defer d.release(job.ID)
+
+	fmt.Printf("▶️  queue: job %s started: %s → s3://%s/%s\n", job.ShortID(), job.FilePath, job.Bucket, job.Key)
+	err := d.Run(ctx, job)
+
+	_, serr := d.Store.Update(job.ID, func(cur *Job) error {
+		if cur.State != StateRunning {
+			return errStateChanged
+		}
+		cur.Key = job.Key // 키 템플릿은 실행할 때 렌더링된다
+		switch {
+		case err != nil && ctx.Err() != nil:
+			cur.State = StatePending
+			cur.Error = interruptedMsg
+		case err != nil:
+			cur.State = StateFailed
+			cur.Error = err.Error()
+			cur.FinishedAt = time.Now()
+		default:
+			cur.State = StateDone
+			cur.Error = ""
+			cur.FinishedAt = time.Now()
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(serr, errStateChanged):
+		fmt.Printf("ℹ️  queue: job %s was changed while running; keeping its new state\n", job.ShortID())
+	case serr != nil:
+		fmt.Fprintf(os.Stderr, "warn: queue: save job %s: %v\n", job.ShortID(), serr)
+	case err != nil && ctx.Err() != nil:
+		fmt.Printf("⏸️  queue: job %s interrupted by shutdown; re-queued\n", job.ShortID())
+	case err != nil:
+		fmt.Printf("❌ queue: job %s failed: %v\n", job.ShortID(), err)
+	default:
+		fmt.Printf("✅ queue: job %s done\n", job.ShortID())
+	}
+}
+
+func (d *Daemon) release(id string) {
+	d.mu.Lock()
+	delete(d.running, id)
+	d.mu.Unlock()
+}
+
+func (d *Daemon) runningCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.running)
+}
+
+// lock makes sure only one daemon processes a queue directory at a time.
+func (d *Daemon) lock() (func(), error) {
+	path := filepath.Join(d.Store.Dir, "daemon.lock")
+	self := lockfile.Self()
+	data, err := json.Marshal(self)
+	if err != nil {
+		return nil, fmt.Errorf("queue: marshal daemon lock: %w", err)
+	}
+	if err := lockfile.Acquire(path, data, false); err != nil {
+		var busy *lockfile.BusyError
+		if errors.As(err, &busy) && busy.Holder.PID > 0 {
+			return nil, fmt.Errorf("queue: daemon already running (%s)", busy.Holder.Owner())
+		}
+		return nil, fmt.Errorf("queue: daemon lock: %w", err)
+	}
+	return func() { lockfile.Release(path, self) }, nil
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 데몬을 멈추면 실행 중인 작업도 취소되고, 업로드 정보를 가진 채 pending 으로 돌아간다.
+func TestShutdownRequeuesRunningJob(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{FilePath: "/tmp/a.bin", Bucket: "b", Key: "a.bin"}
+	if err := store.Add(job); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	run := func(ctx context.Context, j *Job) error {
+		if _, err := store.Update(j.ID, func(cur *Job) error {
+			cur.UploadID, cur.StatusFile = "upload-1", "/tmp/a.bin_upload-1.upload_status"
+			return nil
+		}); err != nil {
+			t.Errorf("save upload: %v", err)
+		}
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	d := NewDaemon(store, run, 1)
+	d.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- d.Start(ctx) }()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job never started")
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("daemon did not stop the running job")
+	}
+
+	got, err := store.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != StatePending || got.Error != interruptedMsg {
+		t.Fatalf("state %s (%q), want pending (%q)", got.State, got.Error, interruptedMsg)
+	}
+	if got.UploadID != "upload-1" || got.StatusFile == "" {
+		t.Fatalf("upload %q / status %q lost on re-queue", got.UploadID, got.StatusFile)
+	}
+}
+
+// 죽은 데몬이 남긴 running 작업은 업로드 정보를 유지한 채 되돌아가고, retry 는 새로 시작한다.
+func TestRecoverKeepsUpload(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{FilePath: "/tmp/a.bin", Bucket: "b", Key: "a.bin"}
+	if err := store.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	job.State, job.UploadID, job.StatusFile = StateRunning, "upload-1", "/tmp/s.upload_status"
+	if err := store.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDaemon(store, nil, 1)
+	if n, err := d.Recover(); err != nil || n != 1 {
+		t.Fatalf("recover: %d, %v", n, err)
+	}
+	got, _ := store.Get(job.ID)
+	if got.State != StatePending || got.UploadID != "upload-1" || got.StatusFile != "/tmp/s.upload_status" {
+		t.Fatalf("recovered job: state %s, upload %q, status %q", got.State, got.UploadID, got.StatusFile)
+	}
+
+	got.State = StateFailed
+	if err := store.Save(got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.Retry(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UploadID != "" || got.StatusFile != "" {
+		t.Fatalf("retried job still points at aborted upload %q", got.UploadID)
+	}
+}
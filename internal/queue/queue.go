@@ -0,0 +1,325 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/lockfile"
+
+	"github.com/google/uuid"
+)
+
+// State is the lifecycle state of a queued job.
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateRunning  State = "running"
+	StatePaused   State = "paused"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+// jobLockWait bounds how long an update waits for another process holding
+// the same job.
+const jobLockWait = 5 * time.Second
+
+// ErrNotFound is returned when no job matches the given ID (or ID prefix).
+var ErrNotFound = errors.New("queue: job not found")
+
+// Job is one upload request persisted under ~/.favus/queue/jobs.
+type Job struct {
+	ID             string    `json:"id"`
+	FilePath       string    `json:"filePath"`
+	Bucket         string    `json:"bucket"`
+	Key            string    `json:"key"`
+	KeyTemplate    string    `json:"keyTemplate,omitempty"` // rendered into Key when the job runs
+	RelDir         string    `json:"relDir,omitempty"`      // {reldir} for jobs added from a directory
+	Region         string    `json:"region,omitempty"`
+	PartSizeMB     int       `json:"partSizeMB,omitempty"`
+	MaxConcurrency int       `json:"maxConcurrency,omitempty"`
+	Compress       bool      `json:"compress,omitempty"`
+	CompressFormat string    `json:"compressFormat,omitempty"` // gzip | zstd
+	CompressLevel  int       `json:"compressLevel,omitempty"`
+	Priority       int       `json:"priority"`
+	State          State     `json:"state"`
+	Attempts       int       `json:"attempts"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	StartedAt      time.Time `json:"startedAt,omitempty"`
+	FinishedAt     time.Time `json:"finishedAt,omitempty"`
+
+	// UploadID and StatusFile are set once the multipart upload exists, so a
+	// job interrupted by a shutdown or crash resumes it instead of starting over.
+	UploadID   string `json:"uploadId,omitempty"`
+	StatusFile string `json:"statusFile,omitempty"`
+}
+
+// ShortID returns the first 8 characters of the job ID for display.
+func (j *Job) ShortID() string {
+	if len(j.ID) > 8 {
+		return j.ID[:8]
+	}
+	return j.ID
+}
+
+// Store is a directory-backed job queue. Each job lives in its own JSON file,
+// and every write goes through a temp file + rename so a crash never leaves
+// a half-written job behind.
+type Store struct {
+	Dir string
+}
+
+// DefaultDir returns ~/.favus/queue.
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	if home == "" {
+		home = "."
+	}
+	return filepath.Join(home, ".favus", "queue")
+}
+
+// NewStore creates the queue directory layout under dir (DefaultDir if empty).
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "jobs"), 0o755); err != nil {
+		return nil, fmt.Errorf("queue: create dir: %w", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+func (s *Store) jobPath(id string) string {
+	return filepath.Join(s.Dir, "jobs", id+".json")
+}
+
+func (s *Store) pausedPath() string {
+	return filepath.Join(s.Dir, "paused")
+}
+
+// Add assigns an ID to job and persists it in the pending state.
+func (s *Store) Add(job *Job) error {
+	if job.ID == "" {
+		job.ID = uuid.NewString()
+	}
+	job.State = StatePending
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	return s.Save(job)
+}
+
+// Save writes a job atomically.
+func (s *Store) Save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("queue: marshal job %s: %w", job.ID, err)
+	}
+	path := s.jobPath(job.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("queue: write job %s: %w", job.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("queue: install job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Get loads a job by full ID or unique ID prefix.
+func (s *Store) Get(id string) (*Job, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, ErrNotFound
+	}
+	if job, err := s.load(s.jobPath(id)); err == nil {
+		return job, nil
+	}
+
+	jobs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var match *Job
+	for _, j := range jobs {
+		if strings.HasPrefix(j.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("queue: job id %q is ambiguous", id)
+			}
+			match = j
+		}
+	}
+	if match == nil {
+		return nil, ErrNotFound
+	}
+	return match, nil
+}
+
+// List returns all jobs, highest priority first, then oldest first.
+func (s *Store) List() ([]*Job, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Dir, "jobs"))
+	if err != nil {
+		return nil, fmt.Errorf("queue: read jobs dir: %w", err)
+	}
+	jobs := make([]*Job, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		job, err := s.load(filepath.Join(s.Dir, "jobs", e.Name()))
+		if err != nil {
+			// 깨진 파일은 건너뛰고 나머지 큐는 계속 처리
+			fmt.Fprintf(os.Stderr, "warn: skip unreadable job %s: %v\n", e.Name(), err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.SliceStable(jobs, func(i, k int) bool {
+		if jobs[i].Priority != jobs[k].Priority {
+			return jobs[i].Priority > jobs[k].Priority
+		}
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (s *Store) load(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("unmarshal job: %w", err)
+	}
+	return &job, nil
+}
+
+// Update loads a job, applies fn and saves the result. The job's lock file is
+// held from the load to the save, so a change made by another process (the
+// daemon claiming the job, a cancel from the CLI) is never overwritten.
+func (s *Store) Update(id string, fn func(*Job) error) (*Job, error) {
+	job, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockJob(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// 잠금을 잡은 뒤 다시 읽어야 그 사이의 변경을 덮어쓰지 않는다
+	job, err = s.load(s.jobPath(job.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if err := fn(job); err != nil {
+		return nil, err
+	}
+	if err := s.Save(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// lockJob takes the lock file of job id, waiting up to jobLockWait for
+// another process to finish its update.
+func (s *Store) lockJob(id string) (func(), error) {
+	path := filepath.Join(s.Dir, "jobs", id+".lock")
+	self := lockfile.Self()
+	data, err := json.Marshal(self)
+	if err != nil {
+		return nil, fmt.Errorf("queue: marshal job lock: %w", err)
+	}
+	deadline := time.Now().Add(jobLockWait)
+	for {
+		err := lockfile.Acquire(path, data, false)
+		if err == nil {
+			return func() { lockfile.Release(path, self) }, nil
+		}
+		var busy *lockfile.BusyError
+		if !errors.As(err, &busy) || time.Now().After(deadline) {
+			return nil, fmt.Errorf("queue: lock job %s: %w", id, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Cancel marks a pending or paused job as canceled.
+// Running jobs cannot be interrupted mid-upload and are rejected.
+func (s *Store) Cancel(id string) (*Job, error) {
+	return s.Update(id, func(j *Job) error {
+		switch j.State {
+		case StatePending, StatePaused:
+			j.State = StateCanceled
+			j.FinishedAt = time.Now()
+			return nil
+		case StateRunning:
+			return fmt.Errorf("queue: job %s is running and cannot be canceled", j.ShortID())
+		default:
+			return fmt.Errorf("queue: job %s is already %s", j.ShortID(), j.State)
+		}
+	})
+}
+
+// Retry moves a failed, canceled or paused job back to pending.
+func (s *Store) Retry(id string) (*Job, error) {
+	return s.Update(id, func(j *Job) error {
+		switch j.State {
+		case StateFailed, StateCanceled, StatePaused:
+			if j.State != StatePaused {
+				// 실패한 업로드는 abort 됐다 — 처음부터 다시 올린다
+				j.UploadID, j.StatusFile = "", ""
+			}
+			j.State = StatePending
+			j.Error = ""
+			j.FinishedAt = time.Time{}
+			return nil
+		default:
+			return fmt.Errorf("queue: job %s is %s; only failed, canceled or paused jobs can be retried", j.ShortID(), j.State)
+		}
+	})
+}
+
+// Pause holds a pending job so the daemon skips it until it is retried.
+func (s *Store) Pause(id string) (*Job, error) {
+	return s.Update(id, func(j *Job) error {
+		if j.State != StatePending {
+			return fmt.Errorf("queue: job %s is %s; only pending jobs can be paused", j.ShortID(), j.State)
+		}
+		j.State = StatePaused
+		return nil
+	})
+}
+
+// SetPaused pauses or unpauses the whole queue. While paused the daemon
+// finishes running jobs but does not start new ones.
+func (s *Store) SetPaused(paused bool) error {
+	if !paused {
+		if err := os.Remove(s.pausedPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("queue: unpause: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(s.pausedPath(), []byte(time.Now().Format(time.RFC3339)), 0o644); err != nil {
+		return fmt.Errorf("queue: pause: %w", err)
+	}
+	return nil
+}
+
+// IsPaused reports whether the whole queue is paused.
+func (s *Store) IsPaused() bool {
+	_, err := os.Stat(s.pausedPath())
+	return err == nil
+}
@@ -0,0 +1,355 @@
+// Package report records where the time of one upload session went: every
+// part's worker, start/end, attempts, errors and throughput, plus session
+// totals, the effective config, the compression ratio and the duplicate-check
+// verdict. favus upload/resume --report writes it as JSON and
+// favus report show summarizes it.
+//
+// A nil *Recorder is valid and records nothing, so the uploader calls it
+// unconditionally.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+)
+
+// Version is the schema version written to Report.Version.
+const Version = 1
+
+// Report is the --report file: the session and every part it uploaded.
+type Report struct {
+	Session `yaml:",inline"`
+	Parts   []Part `json:"parts" yaml:"parts"`
+}
+
+// Session is everything in a report except the parts.
+type Session struct {
+	Version         int       `json:"version" yaml:"version"`
+	Command         string    `json:"command" yaml:"command"` // upload | resume
+	File            string    `json:"file" yaml:"file"`
+	Bucket          string    `json:"bucket" yaml:"bucket"`
+	Key             string    `json:"key" yaml:"key"`
+	UploadID        string    `json:"uploadId,omitempty" yaml:"uploadId,omitempty"`
+	RunID           string    `json:"runId,omitempty" yaml:"runId,omitempty"`
+	StartedAt       time.Time `json:"startedAt" yaml:"startedAt"`
+	EndedAt         time.Time `json:"endedAt" yaml:"endedAt"`
+	DurationSeconds float64   `json:"durationSeconds" yaml:"durationSeconds"`
+	Success         bool      `json:"success" yaml:"success"`
+	Error           string    `json:"error,omitempty" yaml:"error,omitempty"`
+	Skipped         string    `json:"skipped,omitempty" yaml:"skipped,omitempty"` // 중복 검사로 건너뛴 이유
+
+	Totals      Totals       `json:"totals" yaml:"totals"`
+	Config      Settings     `json:"config" yaml:"config"`
+	Compression *Compression `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Duplicate   *Duplicate   `json:"duplicate,omitempty" yaml:"duplicate,omitempty"` // nil: checker not available
+}
+
+// Totals are summed over the parts uploaded in this run.
+type Totals struct {
+	FileBytes     int64   `json:"fileBytes" yaml:"fileBytes"`
+	SourceBytes   int64   `json:"sourceBytes" yaml:"sourceBytes"` // file bytes covered by the parts
+	SentBytes     int64   `json:"sentBytes" yaml:"sentBytes"`     // bytes sent to S3 (compressed size)
+	Parts         int     `json:"parts" yaml:"parts"`
+	PartsFailed   int     `json:"partsFailed" yaml:"partsFailed"`
+	PartsSkipped  int     `json:"partsSkipped,omitempty" yaml:"partsSkipped,omitempty"` // already uploaded before a resume
+	Attempts      int     `json:"attempts" yaml:"attempts"`
+	Retries       int     `json:"retries" yaml:"retries"`
+	Workers       int     `json:"workers" yaml:"workers"`
+	ThroughputBps float64 `json:"throughputBps" yaml:"throughputBps"` // sentBytes / session duration
+}
+
+// Settings is the effective upload config (secrets such as webhook keys are
+// left out).
+type Settings struct {
+	Region         string `json:"region" yaml:"region"`
+	PartSizeMB     int    `json:"partSizeMB" yaml:"partSizeMB"`
+	MaxConcurrency int    `json:"maxConcurrency" yaml:"maxConcurrency"`
+	Compress       bool   `json:"compress" yaml:"compress"`
+	CompressFormat string `json:"compressFormat,omitempty" yaml:"compressFormat,omitempty"`
+	CompressLevel  int    `json:"compressLevel,omitempty" yaml:"compressLevel,omitempty"`
+	CompressAlways bool   `json:"compressAlways,omitempty" yaml:"compressAlways,omitempty"`
+	KeyTemplate    string `json:"keyTemplate,omitempty" yaml:"keyTemplate,omitempty"`
+}
+
+// Compression describes the codec and the ratio achieved by the parts of this run.
+type Compression struct {
+	Codec           string  `json:"codec,omitempty" yaml:"codec,omitempty"` // "" when the decision was not to compress
+	Level           int     `json:"level,omitempty" yaml:"level,omitempty"`
+	Reason          string  `json:"reason,omitempty" yaml:"reason,omitempty"`
+	OriginalBytes   int64   `json:"originalBytes" yaml:"originalBytes"`
+	CompressedBytes int64   `json:"compressedBytes" yaml:"compressedBytes"`
+	Ratio           float64 `json:"ratio" yaml:"ratio"` // compressed / original
+}
+
+// Duplicate is the duplicate checker's verdict.
+type Duplicate struct {
+	Upload bool   `json:"upload" yaml:"upload"`
+	Reason string `json:"reason" yaml:"reason"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Part is one part upload. Start/End span all attempts, including the waits
+// between retries.
+type Part struct {
+	Number          int       `json:"number" yaml:"number"`
+	Worker          int       `json:"worker" yaml:"worker"`
+	Start           time.Time `json:"start" yaml:"start"`
+	End             time.Time `json:"end" yaml:"end"`
+	DurationSeconds float64   `json:"durationSeconds" yaml:"durationSeconds"`
+	Attempts        int       `json:"attempts" yaml:"attempts"`
+	Errors          []string  `json:"errors,omitempty" yaml:"errors,omitempty"` // one per failed attempt
+	Bytes           int64     `json:"bytes" yaml:"bytes"`                       // sent to S3
+	SourceBytes     int64     `json:"sourceBytes" yaml:"sourceBytes"`
+	ThroughputBps   float64   `json:"throughputBps" yaml:"throughputBps"`
+	Success         bool      `json:"success" yaml:"success"`
+}
+
+// Recorder collects one session. All methods are safe for concurrent use by
+// the upload workers and do nothing on nil.
+type Recorder struct {
+	mu    sync.Mutex
+	rep   Report
+	parts map[int]*Part
+}
+
+// New starts recording a session of command (upload or resume) with conf.
+func New(command string, conf *config.Config) *Recorder {
+	r := &Recorder{
+		rep:   Report{Session: Session{Version: Version, Command: command, StartedAt: time.Now()}},
+		parts: make(map[int]*Part),
+	}
+	if conf != nil {
+		r.rep.Config = Settings{
+			Region:         conf.Region,
+			PartSizeMB:     conf.PartSizeMB,
+			MaxConcurrency: conf.MaxConcurrency,
+			Compress:       conf.Compress,
+			KeyTemplate:    conf.KeyTemplate,
+		}
+		if conf.Compress {
+			r.rep.Config.CompressFormat = conf.CompressionFormat()
+			r.rep.Config.CompressLevel = conf.CompressLevel
+			r.rep.Config.CompressAlways = conf.CompressAlways
+		}
+	}
+	return r
+}
+
+// Session sets what is being uploaded. Empty values keep the earlier ones.
+func (r *Recorder) Session(file, bucket, key string, size int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	set(&r.rep.File, file)
+	set(&r.rep.Bucket, bucket)
+	set(&r.rep.Key, key)
+	if size > 0 {
+		r.rep.Totals.FileBytes = size
+	}
+}
+
+// Upload records the multipart upload ID and the run ID the UI shows.
+func (r *Recorder) Upload(uploadID, runID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	set(&r.rep.UploadID, uploadID)
+	set(&r.rep.RunID, runID)
+}
+
+// Duplicate records the duplicate checker's verdict.
+func (r *Recorder) Duplicate(upload bool, reason string, err error) {
+	if r == nil {
+		return
+	}
+	d := &Duplicate{Upload: upload, Reason: reason}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rep.Duplicate = d
+}
+
+// Skip marks the session as skipped (nothing uploaded) for reason.
+func (r *Recorder) Skip(reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rep.Skipped = reason
+}
+
+// Compression records the compression decision; codec is "" when the file
+// is uploaded as is.
+func (r *Recorder) Compression(codec string, level int, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rep.Compression = &Compression{Codec: codec, Level: level, Reason: reason}
+}
+
+// AlreadyUploaded counts parts a resume found on S3 and did not send again.
+func (r *Recorder) AlreadyUploaded(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rep.Totals.PartsSkipped += n
+}
+
+// PartStart records that worker begins part num: size bytes go to S3 for
+// srcSize bytes of the file.
+func (r *Recorder) PartStart(num, worker int, size, srcSize int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parts[num] = &Part{Number: num, Worker: worker, Start: time.Now(), Bytes: size, SourceBytes: srcSize}
+}
+
+// PartAttempt records one UploadPart call of part num and its error.
+func (r *Recorder) PartAttempt(num int, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.parts[num]
+	if p == nil {
+		return
+	}
+	p.Attempts++
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+	}
+}
+
+// PartEnd finishes part num; err is non-nil when it failed for good.
+func (r *Recorder) PartEnd(num int, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.parts[num]
+	if p == nil {
+		return
+	}
+	p.End = time.Now()
+	p.Success = err == nil
+	if err != nil && len(p.Errors) == 0 {
+		p.Errors = append(p.Errors, err.Error()) // 시도 전에 실패 (소스 읽기, nil ETag 등)
+	}
+	p.DurationSeconds = p.End.Sub(p.Start).Seconds()
+	if p.Success && p.DurationSeconds > 0 {
+		p.ThroughputBps = float64(p.Bytes) / p.DurationSeconds
+	}
+}
+
+// Finish ends the session with err (nil on success) and returns the report.
+// Parts still in flight are reported as failed at this point.
+func (r *Recorder) Finish(err error) *Report {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := r.rep
+	rep.EndedAt = time.Now()
+	rep.DurationSeconds = rep.EndedAt.Sub(rep.StartedAt).Seconds()
+	rep.Success = err == nil
+	if err != nil {
+		rep.Error = err.Error()
+	}
+
+	workers := make(map[int]bool)
+	rep.Parts = make([]Part, 0, len(r.parts))
+	for _, p := range r.parts {
+		part := *p
+		if part.End.IsZero() {
+			part.End = rep.EndedAt
+			part.DurationSeconds = part.End.Sub(part.Start).Seconds()
+		}
+		rep.Parts = append(rep.Parts, part)
+		workers[part.Worker] = true
+		rep.Totals.Attempts += part.Attempts
+		if part.Attempts > 1 {
+			rep.Totals.Retries += part.Attempts - 1
+		}
+		if !part.Success {
+			rep.Totals.PartsFailed++
+			continue
+		}
+		rep.Totals.Parts++
+		rep.Totals.SourceBytes += part.SourceBytes
+		rep.Totals.SentBytes += part.Bytes
+	}
+	sort.Slice(rep.Parts, func(i, j int) bool { return rep.Parts[i].Number < rep.Parts[j].Number })
+	rep.Totals.Workers = len(workers)
+	if rep.DurationSeconds > 0 {
+		rep.Totals.ThroughputBps = float64(rep.Totals.SentBytes) / rep.DurationSeconds
+	}
+	if c := rep.Compression; c != nil {
+		cc := *c
+		cc.OriginalBytes, cc.CompressedBytes = rep.Totals.SourceBytes, rep.Totals.SentBytes
+		if cc.OriginalBytes > 0 {
+			cc.Ratio = float64(cc.CompressedBytes) / float64(cc.OriginalBytes)
+		}
+		rep.Compression = &cc
+	}
+	if rep.Duplicate != nil {
+		d := *rep.Duplicate
+		rep.Duplicate = &d
+	}
+	return &rep
+}
+
+// Write stores rep as indented JSON at path.
+func Write(path string, rep *Report) error {
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// Load reads a report written by Write.
+func Load(path string) (*Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read report: %w", err)
+	}
+	var rep Report
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return nil, fmt.Errorf("parse report %s: %w", path, err)
+	}
+	if rep.Version == 0 || rep.Version > Version {
+		return nil, fmt.Errorf("%s is not a favus report (version %d)", path, rep.Version)
+	}
+	return &rep, nil
+}
+
+func set(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
@@ -0,0 +1,88 @@
+package report
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary is what favus report show prints.
+type Summary struct {
+	Session Session `json:"session" yaml:"session"`
+
+	PartSeconds    Percentiles   `json:"partSeconds" yaml:"partSeconds"`
+	PartThroughput Percentiles   `json:"partThroughputBps" yaml:"partThroughputBps"`
+	Slowest        []Part        `json:"slowestParts" yaml:"slowestParts"`
+	Workers        []WorkerStats `json:"workers" yaml:"workers"`
+}
+
+// Percentiles of one per-part metric (nearest-rank).
+type Percentiles struct {
+	P50 float64 `json:"p50" yaml:"p50"`
+	P90 float64 `json:"p90" yaml:"p90"`
+	P95 float64 `json:"p95" yaml:"p95"`
+	P99 float64 `json:"p99" yaml:"p99"`
+	Min float64 `json:"min" yaml:"min"`
+	Max float64 `json:"max" yaml:"max"`
+}
+
+// WorkerStats sums the parts one worker uploaded.
+type WorkerStats struct {
+	Worker      int     `json:"worker" yaml:"worker"`
+	Parts       int     `json:"parts" yaml:"parts"`
+	Bytes       int64   `json:"bytes" yaml:"bytes"`
+	BusySeconds float64 `json:"busySeconds" yaml:"busySeconds"`
+}
+
+// Summarize computes percentiles over the successful parts and picks the top
+// slowest parts (failed ones included, they are usually why).
+func Summarize(rep *Report, top int) Summary {
+	s := Summary{Session: rep.Session, Slowest: []Part{}, Workers: []WorkerStats{}}
+	var secs, bps []float64
+	byWorker := make(map[int]*WorkerStats)
+	for _, p := range rep.Parts {
+		w := byWorker[p.Worker]
+		if w == nil {
+			w = &WorkerStats{Worker: p.Worker}
+			byWorker[p.Worker] = w
+		}
+		w.BusySeconds += p.DurationSeconds
+		if !p.Success {
+			continue
+		}
+		w.Parts++
+		w.Bytes += p.Bytes
+		secs = append(secs, p.DurationSeconds)
+		bps = append(bps, p.ThroughputBps)
+	}
+	s.PartSeconds = percentiles(secs)
+	s.PartThroughput = percentiles(bps)
+
+	slow := append([]Part(nil), rep.Parts...)
+	sort.SliceStable(slow, func(i, j int) bool { return slow[i].DurationSeconds > slow[j].DurationSeconds })
+	if top >= 0 && len(slow) > top {
+		slow = slow[:top]
+	}
+	s.Slowest = append(s.Slowest, slow...)
+
+	for _, w := range byWorker {
+		s.Workers = append(s.Workers, *w)
+	}
+	sort.Slice(s.Workers, func(i, j int) bool { return s.Workers[i].Worker < s.Workers[j].Worker })
+	return s
+}
+
+func percentiles(v []float64) Percentiles {
+	if len(v) == 0 {
+		return Percentiles{}
+	}
+	v = append([]float64(nil), v...)
+	sort.Float64s(v)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p/100*float64(len(v)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return v[i]
+	}
+	return Percentiles{P50: rank(50), P90: rank(90), P95: rank(95), P99: rank(99), Min: v[0], Max: v[len(v)-1]}
+}
@@ -0,0 +1,182 @@
+// Package scheduler shares part upload slots between the files of one
+// invocation (e.g. the jobs the queue daemon runs at once), so the configured
+// concurrency applies to parts across all files instead of per file.
+//
+// Every file opens a Stream and acquires a grant before sending a part. A
+// grant is one of the Scheduler's slots plus the part's bytes, which count
+// against a cap on in-flight bytes. Waiting parts are served shortest job
+// first: the file with the fewest bytes left goes first, so small files finish
+// quickly instead of queueing behind large ones. Files with the same amount
+// left take turns, and a part that has been passed over for a while is served
+// next regardless, so large files keep moving.
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// Scheduler hands out part upload grants. It is safe for concurrent use.
+type Scheduler struct {
+	slots    int
+	maxBytes int64 // 0: no cap
+
+	mu       sync.Mutex
+	active   int
+	inFlight int64
+	served   uint64 // grants handed out so far
+	waiting  []*waiter
+}
+
+// Stream is one file's handle on the scheduler.
+type Stream struct {
+	s          *Scheduler
+	remaining  int64  // bytes not yet uploaded
+	lastServed uint64 // served count at this stream's last grant
+}
+
+// Grant is the permission to send one part. Release it when the part is done.
+type Grant struct {
+	st   *Stream
+	size int64
+}
+
+type waiter struct {
+	st    *Stream
+	size  int64
+	since uint64 // served count when it started waiting
+	ready chan struct{}
+}
+
+// New creates a scheduler with slots concurrent parts and at most maxBytes
+// part bytes in flight (0 or less: no cap). A part larger than maxBytes is
+// still sent, alone.
+func New(slots int, maxBytes int64) *Scheduler {
+	if slots < 1 {
+		slots = 1
+	}
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	return &Scheduler{slots: slots, maxBytes: maxBytes}
+}
+
+// Slots is the number of parts sent at once across all streams.
+func (s *Scheduler) Slots() int { return s.slots }
+
+// Stream registers a file with total bytes to upload. On a nil scheduler it
+// returns a nil stream, whose parts are never held back.
+func (s *Scheduler) Stream(total int64) *Stream {
+	if s == nil {
+		return nil
+	}
+	return &Stream{s: s, remaining: total}
+}
+
+// Acquire waits for a slot and size bytes of the in-flight budget. It returns
+// ctx.Err() if ctx ends first. A nil stream grants immediately.
+func (st *Stream) Acquire(ctx context.Context, size int64) (*Grant, error) {
+	if st == nil {
+		return nil, nil
+	}
+	s := st.s
+	w := &waiter{st: st, size: size, ready: make(chan struct{})}
+
+	s.mu.Lock()
+	w.since = s.served
+	s.waiting = append(s.waiting, w)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return &Grant{st: st, size: size}, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.waiting {
+		if x == w {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+	// 취소와 동시에 받은 grant 는 돌려준다
+	s.releaseLocked(size)
+	return nil, ctx.Err()
+}
+
+// Release returns the grant. uploaded is the number of the file's bytes the
+// part completed (0 if it failed), which moves the stream up in priority.
+// Safe on a nil grant.
+func (g *Grant) Release(uploaded int64) {
+	if g == nil {
+		return
+	}
+	s := g.st.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.st.remaining -= uploaded
+	if g.st.remaining < 0 {
+		g.st.remaining = 0
+	}
+	s.releaseLocked(g.size)
+}
+
+func (s *Scheduler) releaseLocked(size int64) {
+	s.active--
+	s.inFlight -= size
+	s.dispatchLocked()
+}
+
+// dispatchLocked hands out grants while slots and bytes allow.
+func (s *Scheduler) dispatchLocked() {
+	for len(s.waiting) > 0 && s.active < s.slots {
+		i := s.pickLocked()
+		w := s.waiting[i]
+		// 예산이 모자라면 다른 파트를 끼워 넣지 않고 기다린다 (큰 파트가 굶지 않도록)
+		if s.maxBytes > 0 && s.inFlight > 0 && s.inFlight+w.size > s.maxBytes {
+			return
+		}
+		s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+		s.active++
+		s.inFlight += w.size
+		s.served++
+		w.st.lastServed = s.served
+		close(w.ready)
+	}
+}
+
+// pickLocked chooses the next waiter: one passed over for too long, else the
+// stream with the fewest bytes left, taking turns among equals.
+func (s *Scheduler) pickLocked() int {
+	starveAfter := uint64(4 * s.slots)
+	best := 0
+	for i, w := range s.waiting {
+		b := s.waiting[best]
+		wStarved := s.served-w.since >= starveAfter
+		bStarved := s.served-b.since >= starveAfter
+		switch {
+		case wStarved != bStarved:
+			if wStarved {
+				best = i
+			}
+		case wStarved:
+			if w.since < b.since {
+				best = i
+			}
+		case w.st.remaining != b.st.remaining:
+			if w.st.remaining < b.st.remaining {
+				best = i
+			}
+		case w.st.lastServed != b.st.lastServed:
+			if w.st.lastServed < b.st.lastServed {
+				best = i
+			}
+		case w.since < b.since:
+			best = i
+		}
+	}
+	return best
+}
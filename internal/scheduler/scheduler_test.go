@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// acquire requests a grant in the background and delivers it on the channel.
+func acquire(t *testing.T, st *Stream, size int64) <-chan *Grant {
+	t.Helper()
+	ch := make(chan *Grant, 1)
+	go func() {
+		g, err := st.Acquire(context.Background(), size)
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+		}
+		ch <- g
+	}()
+	return ch
+}
+
+// waitQueued waits until n requests are waiting for a grant.
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		got := len(s.waiting)
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting = %d, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func mustGrant(t *testing.T, ch <-chan *Grant, what string) *Grant {
+	t.Helper()
+	select {
+	case g := <-ch:
+		return g
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s: not granted", what)
+		return nil
+	}
+}
+
+func mustWait(t *testing.T, ch <-chan *Grant, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+		t.Fatalf("%s: granted too early", what)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestShortestRemainingFirst(t *testing.T) {
+	s := New(1, 0)
+	hold, _ := s.Stream(1000).Acquire(context.Background(), 1)
+
+	big := acquire(t, s.Stream(100), 1)
+	waitQueued(t, s, 1)
+	small := acquire(t, s.Stream(10), 1)
+	waitQueued(t, s, 2)
+
+	hold.Release(0)
+	g := mustGrant(t, small, "small file")
+	mustWait(t, big, "big file")
+	g.Release(1)
+	mustGrant(t, big, "big file").Release(1)
+}
+
+func TestStarvationGuard(t *testing.T) {
+	s := New(1, 0)
+	small := s.Stream(10)
+	g, _ := small.Acquire(context.Background(), 1)
+
+	big := acquire(t, s.Stream(1000), 1)
+	waitQueued(t, s, 1)
+
+	// 작은 파일이 계속 끼어들어도 큰 파일은 4 × slots 번 밀린 뒤 차례가 온다
+	served := 0
+	for {
+		next := acquire(t, small, 1)
+		waitQueued(t, s, 2)
+		g.Release(0)
+		select {
+		case g = <-next:
+			served++
+			if served > 4*s.Slots() {
+				t.Fatalf("big file still waiting after %d grants", served)
+			}
+			continue
+		case g = <-big:
+		case <-time.After(2 * time.Second):
+			t.Fatal("nothing granted")
+		}
+		g.Release(0)
+		mustGrant(t, next, "small file").Release(0)
+		break
+	}
+	if served != 4*s.Slots() {
+		t.Fatalf("big file served after %d grants, want %d", served, 4*s.Slots())
+	}
+}
+
+func TestByteCap(t *testing.T) {
+	s := New(4, 10)
+	st := s.Stream(1000)
+
+	g1, _ := st.Acquire(context.Background(), 6)
+	second := acquire(t, st, 6)
+	waitQueued(t, s, 1)
+	mustWait(t, second, "part over the cap")
+	g1.Release(6)
+	g2 := mustGrant(t, second, "part under the cap")
+
+	// 한도보다 큰 파트는 아무것도 없을 때 혼자 나간다
+	huge := acquire(t, st, 20)
+	waitQueued(t, s, 1)
+	mustWait(t, huge, "oversized part")
+	g2.Release(6)
+	g3 := mustGrant(t, huge, "oversized part")
+
+	tiny := acquire(t, s.Stream(1), 1)
+	waitQueued(t, s, 1)
+	mustWait(t, tiny, "part behind the oversized one")
+	g3.Release(20)
+	mustGrant(t, tiny, "part after the oversized one").Release(1)
+}
+
+func TestByteCapHeadOfLine(t *testing.T) {
+	s := New(4, 10)
+	hold, _ := s.Stream(1000).Acquire(context.Background(), 6)
+
+	// 우선순위가 높은 파트가 예산을 기다리는 동안 작은 파트가 앞지르지 않는다
+	head := acquire(t, s.Stream(10), 8)
+	waitQueued(t, s, 1)
+	other := acquire(t, s.Stream(500), 1)
+	waitQueued(t, s, 2)
+	mustWait(t, other, "part behind the head")
+
+	hold.Release(6)
+	mustGrant(t, head, "head part").Release(8)
+	mustGrant(t, other, "part behind the head").Release(1)
+}
+
+func TestAcquireCanceled(t *testing.T) {
+	s := New(1, 0)
+	st := s.Stream(100)
+	hold, _ := st.Acquire(context.Background(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := st.Acquire(ctx, 1)
+		errc <- err
+	}()
+	waitQueued(t, s, 1)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	waitQueued(t, s, 0)
+
+	hold.Release(1)
+	g := mustGrant(t, acquire(t, st, 1), "after cancel")
+	g.Release(1)
+	if s.active != 0 || s.inFlight != 0 {
+		t.Fatalf("active = %d, inFlight = %d after all releases", s.active, s.inFlight)
+	}
+}
+
+func TestNilScheduler(t *testing.T) {
+	var s *Scheduler
+	g, err := s.Stream(100).Acquire(context.Background(), 1)
+	if g != nil || err != nil {
+		t.Fatalf("nil scheduler: grant %v, err %v", g, err)
+	}
+	g.Release(1)
+}
@@ -0,0 +1,922 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+)
+
+// MinPartSize is the smallest size S3 accepts for any part but the last.
+const MinPartSize = 5 * 1024 * 1024
+
+// LocalStore implements ObjectStore on top of an afero filesystem, which is
+// either an in-memory tree (NewMemoryStore) or a directory (NewFSStore).
+//
+// Layout (relative to the store root):
+//
+//	<bucket>/<key>                                         object data
+//	<bucket>/.favus-store/meta/<key>.json                  object metadata (ETag, encoding, user metadata)
+//	<bucket>/.favus-store/multipart/<uploadId>/upload.json multipart session
+//	<bucket>/.favus-store/multipart/<uploadId>/<n>.part    uploaded parts (+ .json)
+//
+// Object keys map to real paths, so a directory store can be browsed as a
+// normal file tree; the .favus-store directory is hidden from listings.
+type LocalStore struct {
+	fs afero.Fs
+	mu sync.Mutex // guards object/metadata mutations and upload completion
+}
+
+var _ ObjectStore = (*LocalStore)(nil)
+
+// NewMemoryStore returns an empty pure-Go in-memory store.
+func NewMemoryStore() *LocalStore {
+	return &LocalStore{fs: afero.NewMemMapFs()}
+}
+
+// NewFSStore returns a store rooted at dir. Every top-level directory is a bucket.
+func NewFSStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: create root %s: %w", dir, err)
+	}
+	return &LocalStore{fs: afero.NewBasePathFs(afero.NewOsFs(), dir)}, nil
+}
+
+// internalDir holds per-bucket bookkeeping; keys under it are reserved.
+const internalDir = ".favus-store"
+
+type objectMeta struct {
+	ETag            string            `json:"etag"`
+	Size            int64             `json:"size"`
+	LastModified    time.Time         `json:"lastModified"`
+	ContentType     string            `json:"contentType,omitempty"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	PartsCount      int32             `json:"partsCount,omitempty"`
+}
+
+type uploadMeta struct {
+	UploadID        string            `json:"uploadId"`
+	Bucket          string            `json:"bucket"`
+	Key             string            `json:"key"`
+	Initiated       time.Time         `json:"initiated"`
+	ContentType     string            `json:"contentType,omitempty"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+type partMeta struct {
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// ===================== errors =====================
+
+func apiError(code, format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func errNoSuchBucket(bucket string) error {
+	return &s3types.NoSuchBucket{Message: aws.String("bucket does not exist: " + bucket)}
+}
+
+func errNoSuchKey(bucket, key string) error {
+	return &s3types.NoSuchKey{Message: aws.String(fmt.Sprintf("no such key: %s/%s", bucket, key))}
+}
+
+func errNoSuchUpload(uploadID string) error {
+	return &s3types.NoSuchUpload{Message: aws.String("no such upload: " + uploadID)}
+}
+
+// ===================== paths =====================
+
+func validBucket(bucket string) error {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) || strings.HasPrefix(bucket, ".") {
+		return apiError("InvalidBucketName", "invalid bucket name %q", bucket)
+	}
+	return nil
+}
+
+func validKey(key string) error {
+	if key == "" {
+		return apiError("InvalidArgument", "object key is empty")
+	}
+	if key == internalDir || strings.HasPrefix(key, internalDir+"/") {
+		return apiError("InvalidArgument", "object key %q is reserved", key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == ".." || seg == "." {
+			return apiError("InvalidArgument", "object key %q has relative path segments", key)
+		}
+	}
+	return nil
+}
+
+func objectPath(bucket, key string) string {
+	return path.Join("/", bucket, key)
+}
+
+func objectMetaPath(bucket, key string) string {
+	return path.Join("/", bucket, internalDir, "meta", key) + ".json"
+}
+
+func uploadsDir(bucket string) string {
+	return path.Join("/", bucket, internalDir, "multipart")
+}
+
+func uploadDir(bucket, uploadID string) string {
+	return path.Join(uploadsDir(bucket), uploadID)
+}
+
+func partPath(bucket, uploadID string, n int32) string {
+	return path.Join(uploadDir(bucket, uploadID), fmt.Sprintf("%05d.part", n))
+}
+
+// ===================== helpers =====================
+
+func (s *LocalStore) readJSON(p string, v any) error {
+	data, err := afero.ReadFile(s.fs, p)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *LocalStore) writeJSON(p string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := s.fs.MkdirAll(path.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return afero.WriteFile(s.fs, p, data, 0o644)
+}
+
+// writeStream copies r into a temp file inside bucket and returns its path, size and MD5.
+func (s *LocalStore) writeStream(bucket string, r io.Reader) (string, int64, []byte, error) {
+	tmpDir := path.Join("/", bucket, internalDir, "tmp")
+	if err := s.fs.MkdirAll(tmpDir, 0o755); err != nil {
+		return "", 0, nil, err
+	}
+	tmp := path.Join(tmpDir, uuid.NewString())
+	f, err := s.fs.Create(tmp)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	h := md5.New()
+	var n int64
+	if r != nil {
+		n, err = io.Copy(io.MultiWriter(f, h), r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = s.fs.Remove(tmp)
+		return "", 0, nil, err
+	}
+	return tmp, n, h.Sum(nil), nil
+}
+
+func (s *LocalStore) bucketExists(bucket string) bool {
+	if validBucket(bucket) != nil {
+		return false
+	}
+	ok, _ := afero.DirExists(s.fs, "/"+bucket)
+	return ok
+}
+
+func (s *LocalStore) statObject(bucket, key string) (objectMeta, error) {
+	var m objectMeta
+	if !s.bucketExists(bucket) {
+		return m, errNoSuchBucket(bucket)
+	}
+	if err := validKey(key); err != nil {
+		return m, err
+	}
+	info, err := s.fs.Stat(objectPath(bucket, key))
+	if err != nil || info.IsDir() {
+		return m, errNoSuchKey(bucket, key)
+	}
+	if err := s.readJSON(objectMetaPath(bucket, key), &m); err != nil {
+		// 메타데이터 없이 디렉터리에 직접 놓인 파일도 객체로 취급
+		m = objectMeta{}
+	}
+	if m.ETag == "" || m.Size != info.Size() {
+		m.ETag = `"` + fmt.Sprintf("%x-local", info.ModTime().UnixNano()) + `"`
+	}
+	m.Size = info.Size()
+	if m.LastModified.IsZero() {
+		m.LastModified = info.ModTime()
+	}
+	return m, nil
+}
+
+// installObject moves a finished temp file into place and records its metadata.
+// Callers hold s.mu.
+func (s *LocalStore) installObject(tmp, bucket, key string, meta objectMeta) error {
+	dst := objectPath(bucket, key)
+	if err := s.fs.MkdirAll(path.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("storage: create object dir: %w", err)
+	}
+	if err := s.fs.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("storage: install object: %w", err)
+	}
+	meta.LastModified = time.Now().UTC()
+	return s.writeJSON(objectMetaPath(bucket, key), meta)
+}
+
+func lowerMetadata(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+func quoteETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum) + `"`
+}
+
+// CreateBucket creates a bucket directory if it does not exist yet.
+func (s *LocalStore) CreateBucket(bucket string) error {
+	if err := validBucket(bucket); err != nil {
+		return err
+	}
+	return s.fs.MkdirAll("/"+bucket, 0o755)
+}
+
+// ===================== buckets =====================
+
+func (s *LocalStore) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	bucket := aws.ToString(in.Bucket)
+	if !s.bucketExists(bucket) {
+		return nil, &s3types.NotFound{Message: aws.String("bucket does not exist: " + bucket)}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (s *LocalStore) ListBuckets(_ context.Context, _ *s3.ListBucketsInput, _ ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	infos, err := afero.ReadDir(s.fs, "/")
+	if err != nil {
+		return nil, err
+	}
+	out := &s3.ListBucketsOutput{}
+	for _, fi := range infos {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		out.Buckets = append(out.Buckets, s3types.Bucket{
+			Name:         aws.String(fi.Name()),
+			CreationDate: aws.Time(fi.ModTime().UTC()),
+		})
+	}
+	return out, nil
+}
+
+// ===================== objects =====================
+
+func (s *LocalStore) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	bucket, key := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	if !s.bucketExists(bucket) {
+		return nil, errNoSuchBucket(bucket)
+	}
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	tmp, n, sum, err := s.writeStream(bucket, in.Body)
+	if err != nil {
+		return nil, fmt.Errorf("storage: write object: %w", err)
+	}
+	etag := quoteETag(sum)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.installObject(tmp, bucket, key, objectMeta{
+		ETag:            etag,
+		Size:            n,
+		ContentType:     aws.ToString(in.ContentType),
+		ContentEncoding: aws.ToString(in.ContentEncoding),
+		Metadata:        lowerMetadata(in.Metadata),
+	})
+	if err != nil {
+		_ = s.fs.Remove(tmp)
+		return nil, err
+	}
+	return &s3.PutObjectOutput{ETag: aws.String(etag)}, nil
+}
+
+func (s *LocalStore) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	bucket, key := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	m, err := s.statObject(bucket, key)
+	if err != nil {
+		var nk *s3types.NoSuchKey
+		if errors.As(err, &nk) {
+			return nil, &s3types.NotFound{Message: nk.Message}
+		}
+		return nil, err
+	}
+	out := &s3.HeadObjectOutput{
+		AcceptRanges:  aws.String("bytes"),
+		ContentLength: aws.Int64(m.Size),
+		ETag:          aws.String(m.ETag),
+		LastModified:  aws.Time(m.LastModified),
+		Metadata:      m.Metadata,
+	}
+	if m.ContentType != "" {
+		out.ContentType = aws.String(m.ContentType)
+	}
+	if m.ContentEncoding != "" {
+		out.ContentEncoding = aws.String(m.ContentEncoding)
+	}
+	if m.PartsCount > 0 {
+		out.PartsCount = aws.Int32(m.PartsCount)
+	}
+	return out, nil
+}
+
+func (s *LocalStore) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	bucket, key := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	m, err := s.statObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	start, end, partial, err := parseRange(aws.ToString(in.Range), m.Size)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fs.Open(objectPath(bucket, key))
+	if err != nil {
+		return nil, errNoSuchKey(bucket, key)
+	}
+	out := &s3.GetObjectOutput{
+		AcceptRanges:  aws.String("bytes"),
+		Body:          &sectionReadCloser{Reader: io.NewSectionReader(f, start, end-start), c: f},
+		ContentLength: aws.Int64(end - start),
+		ETag:          aws.String(m.ETag),
+		LastModified:  aws.Time(m.LastModified),
+		Metadata:      m.Metadata,
+	}
+	if partial {
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end-1, m.Size))
+	}
+	if m.ContentType != "" {
+		out.ContentType = aws.String(m.ContentType)
+	}
+	if m.ContentEncoding != "" {
+		out.ContentEncoding = aws.String(m.ContentEncoding)
+	}
+	return out, nil
+}
+
+type sectionReadCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (r *sectionReadCloser) Close() error { return r.c.Close() }
+
+// parseRange handles the single-range forms S3 supports:
+// bytes=a-b, bytes=a- and bytes=-n. It returns a half-open [start, end).
+func parseRange(spec string, size int64) (int64, int64, bool, error) {
+	if spec == "" {
+		return 0, size, false, nil
+	}
+	invalid := apiError("InvalidRange", "invalid range %q for object of size %d", spec, size)
+	r, ok := strings.CutPrefix(spec, "bytes=")
+	if !ok || strings.Contains(r, ",") {
+		return 0, 0, false, invalid
+	}
+	a, b, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, false, invalid
+	}
+	var start, end int64
+	switch {
+	case a == "":
+		n, err := strconv.ParseInt(b, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, invalid
+		}
+		if n > size {
+			n = size
+		}
+		start, end = size-n, size
+	default:
+		v, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || v >= size {
+			return 0, 0, false, invalid
+		}
+		start, end = v, size
+		if b != "" {
+			e, err := strconv.ParseInt(b, 10, 64)
+			if err != nil || e < start {
+				return 0, 0, false, invalid
+			}
+			if e+1 < size {
+				end = e + 1
+			}
+		}
+	}
+	return start, end, true, nil
+}
+
+func (s *LocalStore) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	bucket, key := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	if !s.bucketExists(bucket) {
+		return nil, errNoSuchBucket(bucket)
+	}
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// S3 treats deleting a missing key as success.
+	if err := s.fs.Remove(objectPath(bucket, key)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("storage: delete object: %w", err)
+	}
+	_ = s.fs.Remove(objectMetaPath(bucket, key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (s *LocalStore) CopyObject(_ context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	src, err := url.PathUnescape(strings.TrimPrefix(aws.ToString(in.CopySource), "/"))
+	if err != nil {
+		return nil, apiError("InvalidArgument", "invalid copy source %q", aws.ToString(in.CopySource))
+	}
+	src, _, _ = strings.Cut(src, "?versionId=")
+	srcBucket, srcKey, ok := strings.Cut(src, "/")
+	if !ok {
+		return nil, apiError("InvalidArgument", "copy source must be bucket/key, got %q", src)
+	}
+	dstBucket, dstKey := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	if !s.bucketExists(dstBucket) {
+		return nil, errNoSuchBucket(dstBucket)
+	}
+	if err := validKey(dstKey); err != nil {
+		return nil, err
+	}
+
+	m, err := s.statObject(srcBucket, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fs.Open(objectPath(srcBucket, srcKey))
+	if err != nil {
+		return nil, errNoSuchKey(srcBucket, srcKey)
+	}
+	tmp, _, _, err := s.writeStream(dstBucket, f)
+	_ = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("storage: copy object: %w", err)
+	}
+
+	if in.MetadataDirective == s3types.MetadataDirectiveReplace {
+		m.Metadata = lowerMetadata(in.Metadata)
+		m.ContentType = aws.ToString(in.ContentType)
+		m.ContentEncoding = aws.ToString(in.ContentEncoding)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.installObject(tmp, dstBucket, dstKey, m); err != nil {
+		_ = s.fs.Remove(tmp)
+		return nil, err
+	}
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &s3types.CopyObjectResult{
+			ETag:         aws.String(m.ETag),
+			LastModified: aws.Time(time.Now().UTC()),
+		},
+	}, nil
+}
+
+func (s *LocalStore) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	bucket := aws.ToString(in.Bucket)
+	if !s.bucketExists(bucket) {
+		return nil, errNoSuchBucket(bucket)
+	}
+	prefix := aws.ToString(in.Prefix)
+	delimiter := aws.ToString(in.Delimiter)
+	after := aws.ToString(in.StartAfter)
+	if tok := aws.ToString(in.ContinuationToken); tok != "" {
+		after = tok
+	}
+	maxKeys := aws.ToInt32(in.MaxKeys)
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+
+	var keys []string
+	root := "/" + bucket
+	err := afero.Walk(s.fs, root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == internalDir && path.Dir(filepath.ToSlash(p)) == root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		key := strings.TrimPrefix(filepath.ToSlash(p), root+"/")
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list objects: %w", err)
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectsV2Output{
+		Name:      aws.String(bucket),
+		Prefix:    in.Prefix,
+		Delimiter: in.Delimiter,
+		MaxKeys:   aws.Int32(maxKeys),
+	}
+	seenPrefix := map[string]bool{}
+	var count int32
+	for _, key := range keys {
+		if key <= after {
+			continue
+		}
+		cp := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				cp = key[:len(prefix)+i+len(delimiter)]
+				if seenPrefix[cp] {
+					continue
+				}
+			}
+		}
+		if count >= maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			break
+		}
+		if cp != "" {
+			seenPrefix[cp] = true
+			out.CommonPrefixes = append(out.CommonPrefixes, s3types.CommonPrefix{Prefix: aws.String(cp)})
+			// 0xff는 어떤 UTF-8 키보다 크므로 다음 페이지는 이 prefix 전체를 건너뛴다
+			out.NextContinuationToken = aws.String(cp + "\xff")
+			count++
+			continue
+		}
+		m, err := s.statObject(bucket, key)
+		if err != nil {
+			continue
+		}
+		out.Contents = append(out.Contents, s3types.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(m.Size),
+			ETag:         aws.String(m.ETag),
+			LastModified: aws.Time(m.LastModified),
+			StorageClass: s3types.ObjectStorageClassStandard,
+		})
+		out.NextContinuationToken = aws.String(key)
+		count++
+	}
+	if !aws.ToBool(out.IsTruncated) {
+		out.IsTruncated = aws.Bool(false)
+		out.NextContinuationToken = nil
+	}
+	out.KeyCount = aws.Int32(count)
+	return out, nil
+}
+
+// ===================== multipart =====================
+
+func (s *LocalStore) loadUpload(uploadID, bucket, key string) (*uploadMeta, error) {
+	if uploadID == "" || strings.ContainsAny(uploadID, `/\.`) {
+		return nil, errNoSuchUpload(uploadID)
+	}
+	var u uploadMeta
+	if err := s.readJSON(path.Join(uploadDir(bucket, uploadID), "upload.json"), &u); err != nil {
+		return nil, errNoSuchUpload(uploadID)
+	}
+	if u.Bucket != bucket || u.Key != key {
+		return nil, errNoSuchUpload(uploadID)
+	}
+	return &u, nil
+}
+
+func (s *LocalStore) CreateMultipartUpload(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	bucket, key := aws.ToString(in.Bucket), aws.ToString(in.Key)
+	if !s.bucketExists(bucket) {
+		return nil, errNoSuchBucket(bucket)
+	}
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	u := uploadMeta{
+		UploadID:        strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Bucket:          bucket,
+		Key:             key,
+		Initiated:       time.Now().UTC(),
+		ContentType:     aws.ToString(in.ContentType),
+		ContentEncoding: aws.ToString(in.ContentEncoding),
+		Metadata:        lowerMetadata(in.Metadata),
+	}
+	if err := s.writeJSON(path.Join(uploadDir(bucket, u.UploadID), "upload.json"), u); err != nil {
+		return nil, fmt.Errorf("storage: create multipart upload: %w", err)
+	}
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(u.UploadID),
+	}, nil
+}
+
+func (s *LocalStore) UploadPart(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	bucket, key, uploadID := aws.ToString(in.Bucket), aws.ToString(in.Key), aws.ToString(in.UploadId)
+	n := aws.ToInt32(in.PartNumber)
+	if n < 1 || n > 10000 {
+		return nil, apiError("InvalidArgument", "part number must be between 1 and 10000, got %d", n)
+	}
+	if _, err := s.loadUpload(uploadID, bucket, key); err != nil {
+		return nil, err
+	}
+	tmp, size, sum, err := s.writeStream(bucket, in.Body)
+	if err != nil {
+		return nil, fmt.Errorf("storage: write part %d: %w", n, err)
+	}
+	if in.ContentLength != nil && aws.ToInt64(in.ContentLength) != size {
+		_ = s.fs.Remove(tmp)
+		return nil, apiError("IncompleteBody", "part %d: expected %d bytes, got %d", n, aws.ToInt64(in.ContentLength), size)
+	}
+	if err := s.fs.Rename(tmp, partPath(bucket, uploadID, n)); err != nil {
+		_ = s.fs.Remove(tmp)
+		return nil, fmt.Errorf("storage: install part %d: %w", n, err)
+	}
+	etag := quoteETag(sum)
+	if err := s.writeJSON(partPath(bucket, uploadID, n)+".json", partMeta{ETag: etag, Size: size, LastModified: time.Now().UTC()}); err != nil {
+		return nil, fmt.Errorf("storage: record part %d: %w", n, err)
+	}
+	return &s3.UploadPartOutput{ETag: aws.String(etag)}, nil
+}
+
+func (s *LocalStore) parts(bucket, uploadID string) (map[int32]partMeta, error) {
+	infos, err := afero.ReadDir(s.fs, uploadDir(bucket, uploadID))
+	if err != nil {
+		return nil, errNoSuchUpload(uploadID)
+	}
+	parts := make(map[int32]partMeta)
+	for _, fi := range infos {
+		name, ok := strings.CutSuffix(fi.Name(), ".part.json")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		var pm partMeta
+		if err := s.readJSON(path.Join(uploadDir(bucket, uploadID), fi.Name()), &pm); err != nil {
+			continue
+		}
+		parts[int32(n)] = pm
+	}
+	return parts, nil
+}
+
+func (s *LocalStore) ListParts(_ context.Context, in *s3.ListPartsInput, _ ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	bucket, key, uploadID := aws.ToString(in.Bucket), aws.ToString(in.Key), aws.ToString(in.UploadId)
+	if _, err := s.loadUpload(uploadID, bucket, key); err != nil {
+		return nil, err
+	}
+	parts, err := s.parts(bucket, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	numbers := make([]int32, 0, len(parts))
+	for n := range parts {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var marker int32
+	if m := aws.ToString(in.PartNumberMarker); m != "" {
+		v, err := strconv.Atoi(m)
+		if err != nil {
+			return nil, apiError("InvalidArgument", "invalid part number marker %q", m)
+		}
+		marker = int32(v)
+	}
+	maxParts := aws.ToInt32(in.MaxParts)
+	if maxParts <= 0 || maxParts > 1000 {
+		maxParts = 1000
+	}
+
+	out := &s3.ListPartsOutput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		UploadId:    aws.String(uploadID),
+		MaxParts:    aws.Int32(maxParts),
+		IsTruncated: aws.Bool(false),
+	}
+	if in.PartNumberMarker != nil {
+		out.PartNumberMarker = in.PartNumberMarker
+	}
+	for _, n := range numbers {
+		if n <= marker {
+			continue
+		}
+		if int32(len(out.Parts)) >= maxParts {
+			out.IsTruncated = aws.Bool(true)
+			break
+		}
+		pm := parts[n]
+		out.Parts = append(out.Parts, s3types.Part{
+			PartNumber:   aws.Int32(n),
+			ETag:         aws.String(pm.ETag),
+			Size:         aws.Int64(pm.Size),
+			LastModified: aws.Time(pm.LastModified),
+		})
+		out.NextPartNumberMarker = aws.String(strconv.Itoa(int(n)))
+	}
+	return out, nil
+}
+
+func (s *LocalStore) CompleteMultipartUpload(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	bucket, key, uploadID := aws.ToString(in.Bucket), aws.ToString(in.Key), aws.ToString(in.UploadId)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.loadUpload(uploadID, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if in.MultipartUpload == nil || len(in.MultipartUpload.Parts) == 0 {
+		return nil, apiError("MalformedXML", "complete request lists no parts")
+	}
+	have, err := s.parts(bucket, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := in.MultipartUpload.Parts
+	var prev int32
+	for i, cp := range requested {
+		n := aws.ToInt32(cp.PartNumber)
+		if n <= prev {
+			return nil, apiError("InvalidPartOrder", "parts must be listed in ascending order")
+		}
+		prev = n
+		pm, ok := have[n]
+		if !ok || strings.Trim(pm.ETag, `"`) != strings.Trim(aws.ToString(cp.ETag), `"`) {
+			return nil, apiError("InvalidPart", "part %d was not uploaded or its ETag does not match", n)
+		}
+		if i < len(requested)-1 && pm.Size < MinPartSize {
+			return nil, apiError("EntityTooSmall", "part %d is %d bytes; all parts but the last must be at least %d", n, pm.Size, MinPartSize)
+		}
+	}
+
+	// 파트를 순서대로 이어붙여 최종 객체 생성
+	var sums bytes.Buffer
+	var total int64
+	readers := make([]io.Reader, 0, len(requested))
+	var files []afero.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, cp := range requested {
+		n := aws.ToInt32(cp.PartNumber)
+		f, err := s.fs.Open(partPath(bucket, uploadID, n))
+		if err != nil {
+			return nil, fmt.Errorf("storage: open part %d: %w", n, err)
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+		raw, _ := hex.DecodeString(strings.Trim(have[n].ETag, `"`))
+		sums.Write(raw)
+		total += have[n].Size
+	}
+	tmp, size, _, err := s.writeStream(bucket, io.MultiReader(readers...))
+	if err != nil {
+		return nil, fmt.Errorf("storage: assemble object: %w", err)
+	}
+	if size != total {
+		_ = s.fs.Remove(tmp)
+		return nil, fmt.Errorf("storage: assembled %d bytes, expected %d", size, total)
+	}
+	final := md5.Sum(sums.Bytes())
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(final[:]), len(requested))
+
+	err = s.installObject(tmp, bucket, key, objectMeta{
+		ETag:            etag,
+		Size:            size,
+		ContentType:     u.ContentType,
+		ContentEncoding: u.ContentEncoding,
+		Metadata:        u.Metadata,
+		PartsCount:      int32(len(requested)),
+	})
+	if err != nil {
+		_ = s.fs.Remove(tmp)
+		return nil, err
+	}
+	_ = s.fs.RemoveAll(uploadDir(bucket, uploadID))
+
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		ETag:     aws.String(etag),
+		Location: aws.String(objectPath(bucket, key)),
+	}, nil
+}
+
+func (s *LocalStore) AbortMultipartUpload(_ context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	bucket, key, uploadID := aws.ToString(in.Bucket), aws.ToString(in.Key), aws.ToString(in.UploadId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.loadUpload(uploadID, bucket, key); err != nil {
+		return nil, err
+	}
+	if err := s.fs.RemoveAll(uploadDir(bucket, uploadID)); err != nil {
+		return nil, fmt.Errorf("storage: abort upload: %w", err)
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (s *LocalStore) ListMultipartUploads(_ context.Context, in *s3.ListMultipartUploadsInput, _ ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	bucket := aws.ToString(in.Bucket)
+	if !s.bucketExists(bucket) {
+		return nil, errNoSuchBucket(bucket)
+	}
+	prefix := aws.ToString(in.Prefix)
+	keyMarker, idMarker := aws.ToString(in.KeyMarker), aws.ToString(in.UploadIdMarker)
+	maxUploads := aws.ToInt32(in.MaxUploads)
+	if maxUploads <= 0 || maxUploads > 1000 {
+		maxUploads = 1000
+	}
+
+	infos, _ := afero.ReadDir(s.fs, uploadsDir(bucket))
+	var uploads []uploadMeta
+	for _, fi := range infos {
+		var u uploadMeta
+		if !fi.IsDir() || s.readJSON(path.Join(uploadDir(bucket, fi.Name()), "upload.json"), &u) != nil {
+			continue
+		}
+		if strings.HasPrefix(u.Key, prefix) {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+
+	out := &s3.ListMultipartUploadsOutput{
+		Bucket:      aws.String(bucket),
+		Prefix:      in.Prefix,
+		MaxUploads:  aws.Int32(maxUploads),
+		IsTruncated: aws.Bool(false),
+	}
+	for _, u := range uploads {
+		if keyMarker != "" && (u.Key < keyMarker || (u.Key == keyMarker && (idMarker == "" || u.UploadID <= idMarker))) {
+			continue
+		}
+		if int32(len(out.Uploads)) >= maxUploads {
+			out.IsTruncated = aws.Bool(true)
+			break
+		}
+		out.Uploads = append(out.Uploads, s3types.MultipartUpload{
+			Key:          aws.String(u.Key),
+			UploadId:     aws.String(u.UploadID),
+			Initiated:    aws.Time(u.Initiated),
+			StorageClass: s3types.StorageClassStandard,
+		})
+		out.NextKeyMarker = aws.String(u.Key)
+		out.NextUploadIdMarker = aws.String(u.UploadID)
+	}
+	return out, nil
+}
@@ -0,0 +1,186 @@
+// Package storage abstracts the object store operations Favus uses so that
+// uploads, resumes and management commands can run against S3, an in-memory
+// store or a local directory tree.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/GoCOMA/Favus/internal/faults"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectStore covers the multipart, list, head, get, put, delete and copy
+// operations Favus needs. Method signatures mirror the AWS SDK v2 S3 client,
+// so *s3.Client satisfies it as-is and callers keep using s3 input/output types.
+type ObjectStore interface {
+	HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	ListBuckets(ctx context.Context, in *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+
+	CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	ListParts(ctx context.Context, in *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	ListMultipartUploads(ctx context.Context, in *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+
+	ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+// S3Store is the S3 implementation of ObjectStore. It wraps the SDK client.
+type S3Store struct {
+	*s3.Client
+}
+
+// NewS3Store creates an S3 client from awsCfg. Path-style addressing is
+// enabled when AWS_ENDPOINT_URL points at an S3-compatible endpoint.
+func NewS3Store(awsCfg aws.Config) *S3Store {
+	endpoint := os.Getenv("AWS_ENDPOINT_URL")
+	cli := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.UsePathStyle = true // LocalStack / custom S3-compatible endpoints
+		}
+	}, faults.S3Options)
+	return &S3Store{Client: cli}
+}
+
+// NewS3StoreInRegion is NewS3Store with the region overridden (empty keeps
+// awsCfg.Region), for buckets that live outside the default region.
+func NewS3StoreInRegion(awsCfg aws.Config, region string) *S3Store {
+	if region != "" {
+		awsCfg = awsCfg.Copy()
+		awsCfg.Region = region
+	}
+	return NewS3Store(awsCfg)
+}
+
+// Location schemes accepted in place of a plain bucket name.
+const (
+	SchemeS3     = "s3"
+	SchemeFile   = "file"
+	SchemeMemory = "mem"
+)
+
+// Location identifies a bucket on some backend.
+//
+//	my-bucket / s3://my-bucket  → S3 bucket
+//	file:///srv/backups         → local directory /srv/backups (root /srv, bucket "backups")
+//	mem://scratch               → process-wide in-memory bucket "scratch"
+type Location struct {
+	Scheme string
+	Bucket string
+	Root   string // file scheme only: directory holding the bucket directory
+}
+
+// IsLocal reports whether the location is served without AWS.
+func (l Location) IsLocal() bool { return l.Scheme != SchemeS3 }
+
+// String renders the location back in URL form.
+func (l Location) String() string {
+	switch l.Scheme {
+	case SchemeFile:
+		return "file://" + filepath.ToSlash(filepath.Join(l.Root, l.Bucket))
+	case SchemeMemory:
+		return "mem://" + l.Bucket
+	default:
+		return "s3://" + l.Bucket
+	}
+}
+
+// ParseLocation parses a bucket argument. Plain names are S3 buckets.
+func ParseLocation(raw string) (Location, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		return Location{Scheme: SchemeS3, Bucket: raw}, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Location{}, fmt.Errorf("storage: parse location %q: %w", raw, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case SchemeS3:
+		return Location{Scheme: SchemeS3, Bucket: u.Host}, nil
+	case SchemeMemory:
+		if u.Host == "" {
+			return Location{}, fmt.Errorf("storage: %q has no bucket name", raw)
+		}
+		return Location{Scheme: SchemeMemory, Bucket: u.Host}, nil
+	case SchemeFile:
+		p := u.Path
+		if u.Host != "" && u.Host != "localhost" {
+			p = u.Host + "/" + strings.TrimPrefix(p, "/") // file://./data → relative path
+		}
+		p = filepath.Clean(filepath.FromSlash(p))
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return Location{}, fmt.Errorf("storage: resolve %q: %w", raw, err)
+		}
+		if filepath.Dir(abs) == abs {
+			return Location{}, fmt.Errorf("storage: %q cannot be the filesystem root", raw)
+		}
+		return Location{Scheme: SchemeFile, Root: filepath.Dir(abs), Bucket: filepath.Base(abs)}, nil
+	default:
+		return Location{}, fmt.Errorf("storage: unsupported scheme %q (use s3://, file:// or mem://)", u.Scheme)
+	}
+}
+
+// ParseObjectURL parses an object URL such as s3://bucket/path/key or
+// mem://bucket/key into its location and key. file:// is not accepted because
+// the bucket/key split of a local path is ambiguous.
+func ParseObjectURL(raw string) (Location, string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return Location{}, "", fmt.Errorf("storage: parse object URL %q: %w", raw, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != SchemeS3 && scheme != SchemeMemory {
+		return Location{}, "", fmt.Errorf("storage: object URL %q must use s3:// or mem://", raw)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return Location{}, "", fmt.Errorf("storage: object URL %q needs both a bucket and a key", raw)
+	}
+	return Location{Scheme: scheme, Bucket: u.Host}, key, nil
+}
+
+var (
+	memOnce  sync.Once
+	memStore *LocalStore
+)
+
+// OpenLocal opens the backend for a file:// or mem:// location.
+// file:// bucket directories are created on demand; mem:// buckets share one
+// process-wide store so several components see the same objects.
+func OpenLocal(loc Location) (*LocalStore, error) {
+	switch loc.Scheme {
+	case SchemeFile:
+		st, err := NewFSStore(loc.Root)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.CreateBucket(loc.Bucket); err != nil {
+			return nil, err
+		}
+		return st, nil
+	case SchemeMemory:
+		memOnce.Do(func() { memStore = NewMemoryStore() })
+		if err := memStore.CreateBucket(loc.Bucket); err != nil {
+			return nil, err
+		}
+		return memStore, nil
+	default:
+		return nil, fmt.Errorf("storage: %s is not a local location", loc)
+	}
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type exporter interface {
+	export(spans []*Span) error
+	close() error
+}
+
+func newExporter(dest string) (exporter, error) {
+	dest = strings.TrimSpace(dest)
+	switch {
+	case dest == "":
+		return nil, fmt.Errorf("trace: empty destination")
+	case dest == "stdout":
+		// os.Stdout 은 내보낼 때 읽는다 (cat 등은 실행 중에 stdout 을 stderr 로 돌린다)
+		return &writerExporter{w: func() io.Writer { return os.Stdout }}, nil
+	case dest == "stderr":
+		return &writerExporter{w: func() io.Writer { return os.Stderr }}, nil
+	case strings.HasPrefix(dest, "http://"), strings.HasPrefix(dest, "https://"):
+		return &httpExporter{url: dest, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	default:
+		f, err := os.OpenFile(strings.TrimPrefix(dest, "file://"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("trace: open %s: %w", dest, err)
+		}
+		return &writerExporter{w: func() io.Writer { return f }, c: f}, nil
+	}
+}
+
+// writerExporter writes one OTLP/JSON ExportTraceServiceRequest per line.
+type writerExporter struct {
+	mu sync.Mutex
+	w  func() io.Writer
+	c  io.Closer // nil for stdout/stderr
+}
+
+func (e *writerExporter) export(spans []*Span) error {
+	b, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w().Write(append(b, '\n'))
+	return err
+}
+
+func (e *writerExporter) close() error {
+	if e.c == nil {
+		return nil
+	}
+	return e.c.Close()
+}
+
+// httpExporter POSTs OTLP/JSON to a collector, e.g. http://localhost:4318/v1/traces.
+type httpExporter struct {
+	url    string
+	client *http.Client
+}
+
+func (e *httpExporter) export(spans []*Span) error {
+	b, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("trace: post %s: %w", e.url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("trace: %s returned %d: %s", e.url, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error { return nil }
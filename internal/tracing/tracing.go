@@ -0,0 +1,305 @@
+// Package tracing records upload sessions as OpenTelemetry spans and exports
+// them as OTLP/JSON: to stdout/stderr, appended to a file (one request per
+// line), or POSTed to an OTLP/HTTP collector (…/v1/traces). It is a small
+// stand-alone implementation, so tracing works offline without an SDK.
+//
+// Tracing is off until Enable is called; Start then returns nil and every
+// *Span method is a no-op on nil, so call sites need no checks.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Span kinds (OTLP enum values).
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+var (
+	mu       sync.Mutex
+	exp      exporter
+	resource map[string]any
+	pending  = make(map[string][]*Span) // trace ID → finished spans not exported yet
+)
+
+// Span is one timed operation. Create roots with Start and children with
+// (*Span).Child; finish with End.
+type Span struct {
+	traceID, spanID, parentID string
+	name                      string
+	kind                      int
+	start, end                time.Time
+
+	mu     sync.Mutex
+	attrs  map[string]any
+	errMsg string
+	ended  bool
+}
+
+// Enable turns tracing on. dest is "stdout", "stderr", an http(s) OTLP/HTTP
+// traces URL, or a file path. service becomes service.name / service.version.
+func Enable(dest, service, version string) error {
+	e, err := newExporter(dest)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	mu.Lock()
+	defer mu.Unlock()
+	exp = e
+	resource = map[string]any{"service.name": service, "service.version": version, "host.name": host}
+	return nil
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return exp != nil
+}
+
+// Shutdown exports spans still pending (of traces whose root has not ended)
+// and closes the exporter.
+func Shutdown() error {
+	mu.Lock()
+	e := exp
+	var spans []*Span
+	for id, s := range pending {
+		spans = append(spans, s...)
+		delete(pending, id)
+	}
+	exp = nil
+	mu.Unlock()
+	if e == nil {
+		return nil
+	}
+	var err error
+	if len(spans) > 0 {
+		err = e.export(spans)
+	}
+	if cerr := e.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Start begins a new trace with a root span, or returns nil when tracing is off.
+// kv are alternating attribute keys and values.
+func Start(name string, kv ...any) *Span {
+	if !Enabled() {
+		return nil
+	}
+	return newSpan(randomHex(16), "", name, KindInternal, kv)
+}
+
+// Child begins a span under s.
+func (s *Span) Child(name string, kv ...any) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.traceID, s.spanID, name, KindInternal, kv)
+}
+
+// Client begins a CLIENT span under s, for calls to S3 and other services.
+func (s *Span) Client(name string, kv ...any) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.traceID, s.spanID, name, KindClient, kv)
+}
+
+// Set adds or replaces attributes (alternating keys and values).
+func (s *Span) Set(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		s.attrs[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+}
+
+// End finishes s with an error status when err is non-nil. Ending a root span
+// exports its trace; spans ending later go out with Shutdown.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	if err != nil {
+		s.errMsg = err.Error()
+	}
+	s.mu.Unlock()
+
+	mu.Lock()
+	pending[s.traceID] = append(pending[s.traceID], s)
+	var spans []*Span
+	e := exp
+	if s.parentID == "" {
+		spans = pending[s.traceID]
+		delete(pending, s.traceID)
+	}
+	mu.Unlock()
+	if e != nil && len(spans) > 0 {
+		if err := e.export(spans); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  trace export failed: %v\n", err)
+		}
+	}
+}
+
+// TraceID returns the hex trace ID ("" for nil), e.g. for log lines.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID
+}
+
+func newSpan(traceID, parentID, name string, kind int, kv []any) *Span {
+	s := &Span{
+		traceID:  traceID,
+		spanID:   randomHex(8),
+		parentID: parentID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    make(map[string]any, len(kv)/2),
+	}
+	s.Set(kv...)
+	return s
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ===================== OTLP/JSON encoding =====================
+
+type otlpRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   otlpResource `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"` // 2 = ERROR
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type keyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func encode(spans []*Span) otlpRequest {
+	mu.Lock()
+	res := attributes(resource)
+	version, _ := resource["service.version"].(string)
+	mu.Unlock()
+
+	ss := scopeSpans{}
+	ss.Scope.Name = "github.com/GoCOMA/Favus"
+	ss.Scope.Version = version
+	for _, s := range spans {
+		s.mu.Lock()
+		o := otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attrs),
+		}
+		if s.errMsg != "" {
+			o.Status.Code, o.Status.Message = 2, s.errMsg
+		}
+		s.mu.Unlock()
+		ss.Spans = append(ss.Spans, o)
+	}
+	// 시작 시각 순 (파일/stdout 로 볼 때 읽기 쉽게)
+	sort.SliceStable(ss.Spans, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ss.Spans[i].StartTimeUnixNano, 10, 64)
+		b, _ := strconv.ParseInt(ss.Spans[j].StartTimeUnixNano, 10, 64)
+		return a < b
+	})
+	return otlpRequest{ResourceSpans: []resourceSpans{{Resource: otlpResource{Attributes: res}, ScopeSpans: []scopeSpans{ss}}}}
+}
+
+// attributes converts a map to OTLP KeyValues, sorted by key.
+func attributes(m map[string]any) []keyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, keyValue{Key: k, Value: anyValue(m[k])})
+	}
+	return out
+}
+
+// anyValue maps a Go value to an OTLP AnyValue (int64 is a string in OTLP/JSON).
+func anyValue(v any) map[string]any {
+	switch x := v.(type) {
+	case string:
+		return map[string]any{"stringValue": x}
+	case bool:
+		return map[string]any{"boolValue": x}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int32:
+		return map[string]any{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(x, 10)}
+	case float64:
+		return map[string]any{"doubleValue": x}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(x)}
+	}
+}
@@ -0,0 +1,349 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/archive"
+	"github.com/GoCOMA/Favus/internal/tracing"
+	"github.com/GoCOMA/Favus/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/schollz/progressbar/v3"
+)
+
+// tarCodec keeps blocks as they are, for plain .tar archives. Blocks and
+// stored bytes then line up 1:1 in the index.
+var tarCodec = &codec{Name: "none", Ext: ".tar", newWriter: func(int) (memberWriter, error) {
+	return &storeWriter{}, nil
+}}
+
+type storeWriter struct{ w io.Writer }
+
+func (s *storeWriter) Reset(w io.Writer)           { s.w = w }
+func (s *storeWriter) Write(p []byte) (int, error) { return s.w.Write(p) }
+func (s *storeWriter) Close() error                { return nil }
+
+// archiveContentType is the Content-Type of an archive object. The archive is
+// the payload itself, so no Content-Encoding is set (clients must not unpack it).
+func archiveContentType(compression string) string {
+	switch compression {
+	case "gzip":
+		return "application/gzip"
+	case "zstd":
+		return "application/zstd"
+	}
+	return "application/x-tar"
+}
+
+// UploadArchive streams dir as a tar archive (compressed when compression is
+// gzip or zstd) into a multipart upload at s3Key, without a temporary file,
+// and then writes the index sidecar archive.IndexKey(s3Key). The tar stream
+// can only be read once, so an archive upload that fails is aborted rather
+// than left for favus resume.
+func (u *Uploader) UploadArchive(dir, s3Key, compression string) (err error) {
+	utils.Info(fmt.Sprintf("Starting archive upload of %s to s3://%s/%s", dir, u.Config.Bucket, s3Key))
+	sp := tracing.Start("favus archive", "favus.file", dir, "aws.s3.bucket", u.Config.Bucket, "aws.s3.key", s3Key, "favus.compression", compression)
+	defer func() { sp.End(err) }()
+	hr := newHookRun(u.Config.Hooks, u.Config.Bucket, s3Key, dir, 0, false)
+	defer hr.finish(&err)
+
+	// preUpload 가 디렉터리를 채울 수 있으니 stat 과 CreateMultipartUpload 보다 먼저
+	runID := u.RunID
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	if err := hr.pre(s3Key, "", runID); err != nil {
+		return err
+	}
+
+	if err := u.checkBucket(u.Config.Bucket); err != nil {
+		utils.Error(fmt.Sprintf("%v", err))
+		return err
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("archive source: %w", err)
+	}
+	if !dirInfo.IsDir() {
+		return fmt.Errorf("archive source %s is not a directory", dir)
+	}
+
+	cd := tarCodec
+	if compression != "" {
+		if cd, err = newCodec(compression, u.Config.CompressLevel); err != nil {
+			return err
+		}
+	}
+
+	isp := s3Span(sp, "CreateMultipartUpload", u.Config.Bucket, s3Key)
+	initOut, err := u.store.CreateMultipartUpload(context.Background(), &s3.CreateMultipartUploadInput{
+		Bucket:      &u.Config.Bucket,
+		Key:         &s3Key,
+		ContentType: aws.String(archiveContentType(compression)),
+		Metadata: map[string]string{
+			"favus-archive":       "tar",
+			"favus-archive-index": archive.IndexKey(s3Key),
+			"favus-source":        filepath.Base(filepath.Clean(dir)),
+		},
+	})
+	isp.End(err)
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to initiate multipart upload for %s: %v", s3Key, err))
+		return fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+	uploadID := aws.ToString(initOut.UploadId)
+	utils.Info(fmt.Sprintf("Initiated multipart upload with UploadID: %s", uploadID))
+	sp.Set("aws.s3.upload_id", uploadID)
+
+	// 총 크기는 tar 를 다 만들어야 안다 → WS total 0, 진행률 바는 바이트 카운터
+	r := u.newReporter(0)
+	r.runID = runID
+	r.trace(sp)
+	r.start(u.Config.Bucket, s3Key, uploadID, u.Config.PartSizeBytes(), map[string]any{
+		"archive":     true,
+		"source":      dir,
+		"compression": compression,
+	})
+	hr.created(s3Key, uploadID)
+	totalBar := progressbar.NewOptions64(
+		-1,
+		progressbar.OptionSetDescription("archive"),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionSetWriter(os.Stdout),
+	)
+
+	idx := &archive.Index{
+		Version:     1,
+		Format:      "tar",
+		Compression: compression,
+		Key:         s3Key,
+		Source:      filepath.Base(filepath.Clean(dir)),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	// tar 생산자 → 파이프 → 블록 압축 워커 → 파트 업로드 워커
+	pr, pw := io.Pipe()
+	tarDone := make(chan error, 1)
+	go func() {
+		err := archive.Write(dir, pw, idx)
+		pw.CloseWithError(err)
+		tarDone <- err
+	}()
+
+	maxConcurrency := u.Config.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	stream := newSeqPartStream(cd, pr, u.Config.PartSizeBytes(), maxConcurrency, idx.Source+".tar", dirInfo.ModTime())
+	defer stream.Close()
+	stream.onBlock = func(raw Segment, compressedSize int) {
+		idx.Blocks = append(idx.Blocks, archive.Block{
+			Offset:           raw.Offset,
+			Size:             raw.Size,
+			CompressedOffset: idx.CompressedSize,
+			CompressedSize:   int64(compressedSize),
+		})
+		idx.CompressedSize += int64(compressedSize)
+	}
+
+	var (
+		completedParts []s3types.CompletedPart
+		partsMu        sync.Mutex
+		firstErr       error
+		stopOnce       sync.Once
+	)
+	stop := make(chan struct{})
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+	jobs := make(chan partJob, maxConcurrency)
+	var wg sync.WaitGroup
+	for w := 1; w <= maxConcurrency; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case <-stop:
+					job.release()
+					continue
+				default:
+				}
+				etag, err := u.uploadPartJob(context.Background(), workerID, s3Key, uploadID, job, totalBar, r)
+				job.release()
+				if err != nil {
+					fail(err)
+					continue
+				}
+				partsMu.Lock()
+				completedParts = append(completedParts, s3types.CompletedPart{
+					PartNumber: aws.Int32(int32(job.index)),
+					ETag:       aws.String(etag),
+				})
+				partsMu.Unlock()
+			}
+		}(w)
+	}
+
+	free := make(chan *bytes.Buffer, maxConcurrency+1)
+	for i := 0; i < cap(free); i++ {
+		free <- new(bytes.Buffer)
+	}
+produce:
+	for {
+		var buf *bytes.Buffer
+		select {
+		case <-stop:
+			break produce
+		case buf = <-free:
+		}
+		psp := sp.Child("compress part") // tar 생성 + 압축 대기 시간
+		n, seg, err := stream.Next(buf)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			psp.End(err)
+			fail(fmt.Errorf("archive part %d: %w", stream.part, err))
+			break
+		}
+		psp.Set("favus.part", n, "favus.source_bytes", seg.Size, "favus.compressed_bytes", int64(buf.Len()))
+		psp.End(nil)
+		jobs <- partJob{
+			index:   n,
+			offset:  seg.Offset,
+			size:    int64(buf.Len()),
+			srcSize: seg.Size,
+			open: func() (io.ReadSeekCloser, error) {
+				return readSeekNopCloser{bytes.NewReader(buf.Bytes())}, nil
+			},
+			release: func() { free <- buf },
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 업로드가 먼저 실패했으면 tar 생산자가 파이프에서 막히지 않게 풀어 준다
+	pr.CloseWithError(errors.New("archive upload stopped"))
+	if tarErr := <-tarDone; firstErr == nil && tarErr != nil {
+		firstErr = fmt.Errorf("build tar stream: %w", tarErr)
+	}
+	if firstErr != nil {
+		utils.Error(fmt.Sprintf("An error occurred during archive upload: %v", firstErr))
+		_ = u.AbortMultipartUpload(s3Key, uploadID)
+		r.done(false, uploadID)
+		return firstErr
+	}
+
+	sort.Slice(completedParts, func(i, j int) bool {
+		return aws.ToInt32(completedParts[i].PartNumber) < aws.ToInt32(completedParts[j].PartNumber)
+	})
+	sp.Set("favus.parts", len(completedParts), "favus.bytes", idx.Size)
+	fsp := s3Span(sp, "CompleteMultipartUpload", u.Config.Bucket, s3Key, "aws.s3.upload_id", uploadID, "favus.parts", len(completedParts))
+	completeOut, err := u.store.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+		Bucket:          &u.Config.Bucket,
+		Key:             &s3Key,
+		UploadId:        &uploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	fsp.End(err)
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to complete multipart upload: %v", err))
+		_ = u.AbortMultipartUpload(s3Key, uploadID)
+		r.done(false, uploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	utils.Info(fmt.Sprintf("Archive %s: %d entries, %d tar bytes → %d bytes in %d parts",
+		s3Key, len(idx.Entries), idx.Size, idx.CompressedSize, len(completedParts)))
+
+	if err := u.putArchiveIndex(idx); err != nil {
+		r.done(false, uploadID)
+		return err
+	}
+	r.done(true, uploadID)
+	hr.session.Bytes = idx.Size
+	hr.complete(aws.ToString(completeOut.ETag))
+	time.Sleep(1 * time.Second) // WS 메시지 전송 대기
+	return nil
+}
+
+// putArchiveIndex uploads the index sidecar next to the archive.
+func (u *Uploader) putArchiveIndex(idx *archive.Index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("encode archive index: %w", err)
+	}
+	key := archive.IndexKey(idx.Key)
+	err = utils.Retry(3, 2*time.Second, func() error {
+		_, err := u.store.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:        &u.Config.Bucket,
+			Key:           &key,
+			Body:          bytes.NewReader(data),
+			ContentLength: aws.Int64(int64(len(data))),
+			ContentType:   aws.String("application/json"),
+		})
+		return err
+	})
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to upload archive index %s: %v", key, err))
+		return fmt.Errorf("archive uploaded but its index %s failed: %w", key, err)
+	}
+	utils.Info(fmt.Sprintf("Archive index written to s3://%s/%s (%d bytes)", u.Config.Bucket, key, len(data)))
+	return nil
+}
+
+// PlanArchive reports what UploadArchive(dir, s3Key, compression) would do.
+// The tar size is estimated from a directory walk.
+func (u *Uploader) PlanArchive(dir, s3Key, compression string) (*UploadPlan, error) {
+	files, dirs, dataBytes, tarBytes, err := archive.Scan(dir)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", dir, err)
+	}
+	p := &UploadPlan{
+		Operation:      "archive",
+		FilePath:       dir,
+		FileSize:       tarBytes,
+		Bucket:         u.Config.Bucket,
+		Location:       u.Location,
+		Key:            s3Key,
+		Compress:       compression != "",
+		CompressLevel:  u.Config.CompressLevel,
+		PartSizeBytes:  u.Config.PartSizeBytes(),
+		MaxConcurrency: u.Config.MaxConcurrency,
+		BucketCheck:    u.planBucketCheck(u.Config.Bucket),
+		Duplicate:      &DuplicateVerdict{Upload: true, Reason: "not applied to archive uploads"},
+		Archive:        "tar",
+	}
+	if compression != "" {
+		p.Archive += "+" + compression
+	}
+	p.Parts = planParts(tarBytes, p.PartSizeBytes)
+	p.TotalParts = len(p.Parts)
+	p.RemainingParts = p.TotalParts
+	p.RemainingBytes = tarBytes
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("%d files (%d bytes) and %d directories; tar stream is about %d bytes", files, dataBytes, dirs, tarBytes),
+		"archive uploads are streamed once and cannot be resumed; a failed upload is aborted")
+	if compression != "" {
+		p.Notes = append(p.Notes, fmt.Sprintf("the tar stream is %s-compressed in 1 MiB blocks; parts below are for the uncompressed stream (upper bound)", compression))
+	}
+	p.Notes = append(p.Notes, hookNotes(u.Config.Hooks)...)
+	return p, nil
+}
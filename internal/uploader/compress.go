@@ -1,79 +1,497 @@
 package uploader
 
 import (
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/GoCOMA/Favus/internal/config"
 	"github.com/GoCOMA/Favus/pkg/utils"
+	"github.com/klauspost/compress/zstd"
 )
 
-// compressToTempGzip creates a gzipped copy of src under ~/.favus/compressed and returns the new path.
-func compressToTempGzip(srcPath string) (string, error) {
-	info, err := os.Stat(srcPath)
-	if err != nil {
-		return "", fmt.Errorf("stat source file: %w", err)
+// 스트리밍 압축: 임시 사본 없이 업로드 도중 파트 버퍼에 바로 압축한다.
+// 원본을 compressBlockSize 블록으로 나눠 블록마다 독립된 gzip member / zstd frame 으로
+// 여러 워커가 동시에 압축하고, 순서대로 이어 붙여 파트를 만든다. 각 파트가 덮는 원본
+// 범위(Segment)를 상태 파일에 기록하므로, resume 시 빠진 파트만 같은 범위로 다시
+// 압축해 올릴 수 있다. member/frame 을 이어 붙인 객체는 그대로 하나의 유효한
+// .gz/.zst 스트림이다 (RFC 1952, RFC 8878 — gunzip/zcat, zstd -d 모두 지원).
+
+// compressBlockSize is the source size of one member/frame. A part is cut on a
+// block boundary as soon as its compressed size reaches the part size.
+const compressBlockSize = 1 << 20
+
+// memberWriter compresses one self-contained member (gzip) or frame (zstd).
+type memberWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// codec is a compression format whose members can be concatenated.
+type codec struct {
+	Name  string // Content-Encoding and status file "compression"
+	Ext   string // object key suffix
+	Level int    // 0: format default
+
+	newWriter func(level int) (memberWriter, error)
+}
+
+// newCodec returns the codec for a config.CompressGzip / config.CompressZstd format.
+func newCodec(format string, level int) (*codec, error) {
+	switch format {
+	case "", config.CompressGzip:
+		return &codec{Name: config.CompressGzip, Ext: ".gz", Level: level, newWriter: func(level int) (memberWriter, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(nil, level)
+		}}, nil
+	case config.CompressZstd:
+		return &codec{Name: config.CompressZstd, Ext: ".zst", Level: level, newWriter: func(level int) (memberWriter, error) {
+			opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+			if level != 0 {
+				opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+			}
+			return zstd.NewWriter(nil, opts...)
+		}}, nil
 	}
+	return nil, fmt.Errorf("unknown compression format %q", format)
+}
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("determine home directory: %w", err)
+// key returns the object key for a compressed upload (adds .gz / .zst).
+func (c *codec) key(key string) string {
+	if strings.HasSuffix(strings.ToLower(key), c.Ext) {
+		return key
 	}
+	return key + c.Ext
+}
+
+// Segment is the range of the original file that one compressed part covers.
+// The digests let resume check that a rebuilt part still matches what was cut.
+type Segment struct {
+	Offset       int64  `json:"offset"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256,omitempty"`       // compressed part bytes
+	SourceSHA256 string `json:"sourceSha256,omitempty"` // sha256 over the sha256 of each source block
+}
+
+// End is the source offset just past the segment.
+func (s Segment) End() int64 { return s.Offset + s.Size }
+
+// compressedBlock is one source block on its way through the compress workers.
+type compressedBlock struct {
+	seg    Segment
+	buf    bytes.Buffer
+	raw    []byte // sequential sources: the block's source bytes
+	srcSum [sha256.Size]byte
+	err    error
+	done   chan struct{} // closed once buf/srcSum/err are set
+}
+
+// partStream cuts a file into parts that are each a run of complete members of
+// at least partSize compressed bytes (the last part may be smaller, as S3
+// allows). Blocks are compressed ahead by workers goroutines; one producer
+// calls Next, and Close stops the workers.
+type partStream struct {
+	codec     *codec
+	src       io.ReaderAt
+	seq       io.Reader // instead of src: read once, front to back (size < 0)
+	size      int64     // original file size, -1 for seq
+	partSize  int64
+	blockSize int64 // source bytes per member; resume uses the recorded one
+	workers   int
+	name      string    // gzip header of the first member
+	modTime   time.Time // gzip header of the first member
+
+	offset int64 // next source byte to hand out
+	part   int   // next part number
+
+	pending   chan *compressedBlock // in source order
+	free      chan *compressedBlock
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	// onBlock, if set, is called by Next for every block in source order
+	// (archive index: which compressed bytes hold which source range).
+	onBlock func(raw Segment, compressedSize int)
+
+	// Recompress / compressRange (호출한 goroutine 에서 순차 압축)
+	w       memberWriter
+	readBuf []byte
+}
 
-	destDir := filepath.Join(home, ".favus", "compressed")
-	if err := os.MkdirAll(destDir, 0o755); err != nil {
-		return "", fmt.Errorf("create compressed directory: %w", err)
+func newPartStream(c *codec, src io.ReaderAt, size, partSize int64, workers int, name string, modTime time.Time) *partStream {
+	if workers < 1 {
+		workers = 1
 	}
+	return &partStream{
+		codec:     c,
+		src:       src,
+		size:      size,
+		partSize:  partSize,
+		blockSize: compressBlockSize,
+		workers:   workers,
+		name:      name,
+		modTime:   modTime,
+		part:      1,
+		quit:      make(chan struct{}),
+	}
+}
 
-	base := filepath.Base(srcPath)
-	timestamp := time.Now().UnixNano()
-	destPath := filepath.Join(destDir, fmt.Sprintf("%s.%d.gz", base, timestamp))
+// newSeqPartStream is newPartStream for a source of unknown size that can only
+// be read once, such as a tar stream being built. It cannot be resumed.
+func newSeqPartStream(c *codec, r io.Reader, partSize int64, workers int, name string, modTime time.Time) *partStream {
+	s := newPartStream(c, nil, -1, partSize, workers, name, modTime)
+	s.seq = r
+	return s
+}
 
-	src, err := os.Open(srcPath)
-	if err != nil {
-		return "", fmt.Errorf("open source file: %w", err)
+// resumeAfter continues the stream after the parts already cut in an earlier
+// run. It must be called before the first Next.
+func (s *partStream) resumeAfter(segments map[int]Segment) {
+	for n, seg := range segments {
+		if seg.End() > s.offset {
+			s.offset = seg.End()
+		}
+		if n >= s.part {
+			s.part = n + 1
+		}
 	}
-	defer src.Close()
+}
 
-	dest, err := os.Create(destPath)
+// start launches the block producer and the compress workers. At most
+// 2×workers blocks are in flight, which bounds the read-ahead memory.
+func (s *partStream) start() {
+	ahead := 2 * s.workers
+	s.pending = make(chan *compressedBlock, ahead)
+	s.free = make(chan *compressedBlock, ahead)
+	for i := 0; i < ahead; i++ {
+		s.free <- &compressedBlock{}
+	}
+	work := make(chan *compressedBlock, ahead)
+	for i := 0; i < s.workers; i++ {
+		go s.compressWorker(work)
+	}
+	if s.seq != nil {
+		go s.readSequential(work)
+		return
+	}
+	go func() {
+		defer close(work)
+		defer close(s.pending)
+		for off := s.offset; off < s.size; off += s.blockSize {
+			var b *compressedBlock
+			select {
+			case <-s.quit:
+				return
+			case b = <-s.free:
+			}
+			b.seg = Segment{Offset: off, Size: min(s.blockSize, s.size-off)}
+			b.err = nil
+			b.done = make(chan struct{})
+			// 두 채널 모두 블록 총수(ahead)만큼 버퍼가 있어 막히지 않는다
+			s.pending <- b
+			work <- b
+		}
+	}()
+}
+
+// readSequential is the block producer for seq: it reads the blocks itself
+// (the workers only compress) and stops at EOF or on the first read error.
+func (s *partStream) readSequential(work chan<- *compressedBlock) {
+	defer close(work)
+	defer close(s.pending)
+	for off := s.offset; ; {
+		var b *compressedBlock
+		select {
+		case <-s.quit:
+			return
+		case b = <-s.free:
+		}
+		if int64(cap(b.raw)) < s.blockSize {
+			b.raw = make([]byte, s.blockSize)
+		}
+		n, err := io.ReadFull(s.seq, b.raw[:s.blockSize])
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		b.raw = b.raw[:n]
+		b.seg = Segment{Offset: off, Size: int64(n)}
+		b.err = nil
+		b.done = make(chan struct{})
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			b.err = fmt.Errorf("read source at %d: %w", off+int64(n), err)
+			close(b.done)
+			s.pending <- b
+			return
+		}
+		s.pending <- b
+		work <- b
+		off += int64(n)
+		if err != nil { // ErrUnexpectedEOF: 마지막 (짧은) 블록
+			return
+		}
+	}
+}
+
+func (s *partStream) compressWorker(work <-chan *compressedBlock) {
+	var w memberWriter
+	var readBuf []byte
+	if s.seq == nil {
+		readBuf = make([]byte, s.blockSize)
+	}
+	for b := range work {
+		b.buf.Reset()
+		if s.seq != nil {
+			b.srcSum, b.err = s.encodeBlock(&w, &b.buf, b.raw, b.seg)
+		} else {
+			b.srcSum, b.err = s.compressBlock(&w, readBuf, &b.buf, b.seg)
+		}
+		close(b.done)
+	}
+}
+
+// Next fills dst (which is reset first) with the next part and returns its
+// part number, source range and digests. It returns io.EOF once the whole file
+// is cut.
+func (s *partStream) Next(dst *bytes.Buffer) (int, Segment, error) {
+	if s.seq == nil && s.offset >= s.size {
+		return 0, Segment{}, io.EOF
+	}
+	if s.pending == nil {
+		s.start()
+	}
+	dst.Reset()
+	n := s.part
+	seg := Segment{Offset: s.offset}
+	srcHash := sha256.New()
+	for int64(dst.Len()) < s.partSize {
+		b, ok := <-s.pending
+		if !ok {
+			break
+		}
+		<-b.done
+		if b.err != nil {
+			return 0, Segment{}, b.err
+		}
+		dst.Write(b.buf.Bytes())
+		srcHash.Write(b.srcSum[:])
+		seg.Size += b.seg.Size
+		if s.onBlock != nil {
+			s.onBlock(b.seg, b.buf.Len())
+		}
+		s.free <- b
+	}
+	if seg.Size == 0 {
+		if s.seq != nil {
+			return 0, Segment{}, io.EOF // 순차 소스는 끝나 봐야 안다
+		}
+		return 0, Segment{}, fmt.Errorf("%s part %d: compression stopped at source byte %d", s.codec.Name, n, s.offset)
+	}
+	seg.SourceSHA256 = hex.EncodeToString(srcHash.Sum(nil))
+	seg.SHA256 = sha256Hex(dst.Bytes())
+	s.offset = seg.End()
+	s.part++
+	return n, seg, nil
+}
+
+// Close stops the compress workers. Parts already returned stay valid.
+func (s *partStream) Close() {
+	s.closeOnce.Do(func() { close(s.quit) })
+}
+
+// ErrSourceChanged means the source bytes of a part no longer match the
+// digest recorded when the part was cut.
+var ErrSourceChanged = errors.New("source file changed since the upload started")
+
+// Recompress rebuilds part n from the source range recorded for it and checks
+// it against the recorded digests. A source mismatch is an error; different
+// compressed bytes from the same source (e.g. a newer encoder) are still a
+// valid part and are only logged.
+func (s *partStream) Recompress(dst *bytes.Buffer, n int, seg Segment) error {
+	if seg.End() > s.size {
+		return fmt.Errorf("bytes %d-%d are past the end of the %d-byte file: %w", seg.Offset, seg.End(), s.size, ErrSourceChanged)
+	}
+	dst.Reset()
+	srcSum, err := s.compressRange(dst, Segment{Offset: seg.Offset, Size: seg.Size})
 	if err != nil {
-		return "", fmt.Errorf("create compressed file: %w", err)
+		return err
 	}
+	if seg.SourceSHA256 != "" && srcSum != seg.SourceSHA256 {
+		return fmt.Errorf("bytes %d-%d: %w", seg.Offset, seg.End(), ErrSourceChanged)
+	}
+	if seg.SHA256 != "" {
+		if got := sha256Hex(dst.Bytes()); got != seg.SHA256 {
+			utils.Info(fmt.Sprintf("Recompressed part %d differs from the recorded sha256 (%s, was %s) although its source matches; uploading the new bytes", n, got, seg.SHA256))
+		}
+	}
+	return nil
+}
 
-	gw := gzip.NewWriter(dest)
-	gw.Name = base
-	gw.ModTime = info.ModTime()
+// VerifySource checks that the source range of part n still has the recorded
+// digest, without compressing it. Parts cut before digests were recorded pass.
+func (s *partStream) VerifySource(n int, seg Segment) error {
+	if seg.End() > s.size {
+		return fmt.Errorf("part %d covers bytes %d-%d but the file has only %d bytes: %w", n, seg.Offset, seg.End(), s.size, ErrSourceChanged)
+	}
+	if seg.SourceSHA256 == "" {
+		return nil
+	}
+	if s.readBuf == nil {
+		s.readBuf = make([]byte, s.blockSize)
+	}
+	h := sha256.New()
+	for off := seg.Offset; off < seg.End(); off += s.blockSize {
+		buf := s.readBuf[:min(s.blockSize, seg.End()-off)]
+		if err := readFullAt(s.src, buf, off); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(buf)
+		h.Write(sum[:])
+	}
+	if hex.EncodeToString(h.Sum(nil)) != seg.SourceSHA256 {
+		return fmt.Errorf("part %d (bytes %d-%d): %w", n, seg.Offset, seg.End(), ErrSourceChanged)
+	}
+	return nil
+}
+
+// compressRange appends seg to dst as blocks of blockSize on the calling
+// goroutine and returns the source digest. Parts start on a block boundary, so
+// the blocks line up with the ones the workers made.
+func (s *partStream) compressRange(dst *bytes.Buffer, seg Segment) (string, error) {
+	if s.readBuf == nil {
+		s.readBuf = make([]byte, s.blockSize)
+	}
+	h := sha256.New()
+	for off := seg.Offset; off < seg.End(); off += s.blockSize {
+		b := Segment{Offset: off, Size: min(s.blockSize, seg.End()-off)}
+		sum, err := s.compressBlock(&s.w, s.readBuf, dst, b)
+		if err != nil {
+			return "", err
+		}
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// compressBlock appends one member for source bytes seg to dst and returns the
+// sha256 of those source bytes. *w is created on first use and reused.
+func (s *partStream) compressBlock(w *memberWriter, readBuf []byte, dst *bytes.Buffer, seg Segment) ([sha256.Size]byte, error) {
+	buf := readBuf[:seg.Size]
+	if err := readFullAt(s.src, buf, seg.Offset); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return s.encodeBlock(w, dst, buf, seg)
+}
 
-	utils.Info(fmt.Sprintf("Compressing %s → %s (gzip)", srcPath, destPath))
-	if _, err := io.Copy(gw, src); err != nil {
-		_ = gw.Close()
-		_ = dest.Close()
-		_ = os.Remove(destPath)
-		return "", fmt.Errorf("gzip copy failed: %w", err)
+// encodeBlock appends one member holding buf (source bytes seg) to dst.
+func (s *partStream) encodeBlock(w *memberWriter, dst *bytes.Buffer, buf []byte, seg Segment) ([sha256.Size]byte, error) {
+	sum := sha256.Sum256(buf)
+	if *w == nil {
+		nw, err := s.codec.newWriter(s.codec.Level)
+		if err != nil {
+			return sum, fmt.Errorf("%s writer: %w", s.codec.Name, err)
+		}
+		*w = nw
 	}
 
-	if err := gw.Close(); err != nil {
-		_ = dest.Close()
-		_ = os.Remove(destPath)
-		return "", fmt.Errorf("finalize gzip writer: %w", err)
+	(*w).Reset(dst)
+	if gw, ok := (*w).(*gzip.Writer); ok && seg.Offset == 0 {
+		gw.Name = s.name
+		gw.ModTime = s.modTime
+	}
+	if _, err := (*w).Write(buf); err != nil {
+		return sum, fmt.Errorf("%s block at %d: %w", s.codec.Name, seg.Offset, err)
+	}
+	if err := (*w).Close(); err != nil {
+		return sum, fmt.Errorf("finalize %s block at %d: %w", s.codec.Name, seg.Offset, err)
 	}
+	return sum, nil
+}
 
-	if err := dest.Close(); err != nil {
-		_ = os.Remove(destPath)
-		return "", fmt.Errorf("close compressed file: %w", err)
+// readFullAt fills buf from src at off; a short file means it was modified.
+func readFullAt(src io.ReaderAt, buf []byte, off int64) error {
+	n, err := src.ReadAt(buf, off)
+	if err == nil || (errors.Is(err, io.EOF) && n == len(buf)) {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("read source at %d: unexpected end of file: %w", off+int64(n), ErrSourceChanged)
 	}
+	return fmt.Errorf("read source at %d: %w", off, err)
+}
 
-	utils.Info(fmt.Sprintf("Compression finished: %s (size %d bytes)", destPath, fileSize(destPath)))
-	return destPath, nil
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
 
-func fileSize(path string) int64 {
-	info, err := os.Stat(path)
+// sortedSegments returns the part numbers of segments in ascending order.
+func sortedSegments(segments map[int]Segment) []int {
+	nums := make([]int, 0, len(segments))
+	for n := range segments {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// compression picks the codec for filePath, or nil when the file goes up
+// uncompressed. The decision is nil when compression is off altogether.
+func (u *Uploader) compression(filePath string) (*codec, *CompressDecision, error) {
+	if !u.Config.Compress {
+		return nil, nil, nil
+	}
+	if err := u.Config.ValidateCompression(); err != nil {
+		return nil, nil, err
+	}
+	c, err := newCodec(u.Config.CompressionFormat(), u.Config.CompressLevel)
 	if err != nil {
-		return 0
+		return nil, nil, err
+	}
+	if u.Config.CompressAlways {
+		return c, &CompressDecision{Compress: true, Reason: "detection disabled (compressAlways)"}, nil
+	}
+	d, err := detectCompressible(filePath, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !d.Compress {
+		utils.Info(fmt.Sprintf("Skipping compression for %s: %s", filePath, d.Reason))
+		return nil, &d, nil
+	}
+	return c, &d, nil
+}
+
+// compressionMetadata is the favus-* object metadata of a compressed upload, or
+// of one whose compression was skipped. It is nil when compression is off.
+func compressionMetadata(c *codec, d *CompressDecision, filePath string, size int64) map[string]string {
+	if d == nil {
+		return nil
+	}
+	if c == nil {
+		return map[string]string{
+			"favus-compression":         "none",
+			"favus-compression-skipped": d.Reason,
+		}
+	}
+	md := map[string]string{
+		"favus-original-name": filepath.Base(filePath),
+		"favus-original-size": strconv.FormatInt(size, 10),
+		"favus-compression":   c.Name,
+	}
+	if c.Level != 0 {
+		md["favus-compression-level"] = strconv.Itoa(c.Level)
 	}
-	return info.Size()
+	return md
 }
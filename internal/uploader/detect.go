@@ -0,0 +1,129 @@
+package uploader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 이미 압축된 입력(.mp4, .zip ...)은 다시 압축해도 CPU 만 쓰고 크기는 그대로다.
+// 확장자 → 매직 바이트 → 샘플 시험 압축 순서로 판단해 그런 파일은 원본 그대로 올린다.
+
+// incompressibleRatio: trial output above this fraction of the input is not worth it.
+const incompressibleRatio = 0.95
+
+const (
+	detectSamples    = 4
+	detectSampleSize = 256 << 10
+)
+
+// CompressDecision is whether a file is worth compressing and why.
+type CompressDecision struct {
+	Compress bool    `json:"compress"`
+	Reason   string  `json:"reason"`
+	Ratio    float64 `json:"ratio,omitempty"` // trial compressed/original size of the samples
+}
+
+var incompressibleExts = map[string]bool{
+	// video / audio
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".flac": true,
+	// images
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true, ".avif": true,
+	// archives / compressed
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".lz4": true,
+	".7z": true, ".rar": true, ".br": true, ".jar": true, ".apk": true, ".whl": true,
+	// zip-based documents
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".epub": true,
+}
+
+var incompressibleMagic = []struct {
+	name   string
+	offset int
+	magic  []byte
+}{
+	{"gzip", 0, []byte{0x1f, 0x8b}},
+	{"zstd", 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{"zip", 0, []byte("PK\x03\x04")},
+	{"xz", 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{"bzip2", 0, []byte("BZh")},
+	{"7z", 0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}},
+	{"rar", 0, []byte("Rar!")},
+	{"png", 0, []byte{0x89, 'P', 'N', 'G'}},
+	{"jpeg", 0, []byte{0xff, 0xd8, 0xff}},
+	{"gif", 0, []byte("GIF8")},
+	{"matroska/webm", 0, []byte{0x1a, 0x45, 0xdf, 0xa3}},
+	{"ogg", 0, []byte("OggS")},
+	{"flac", 0, []byte("fLaC")},
+	{"mp3", 0, []byte("ID3")},
+	{"mp4/mov", 4, []byte("ftyp")},
+}
+
+// detectCompressible decides whether compressing path with c is worthwhile.
+func detectCompressible(path string, c *codec) (CompressDecision, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if incompressibleExts[ext] {
+		return CompressDecision{Reason: fmt.Sprintf("extension %s is already compressed", ext)}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return CompressDecision{}, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return CompressDecision{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	for _, m := range incompressibleMagic {
+		if len(head) >= m.offset+len(m.magic) && bytes.Equal(head[m.offset:m.offset+len(m.magic)], m.magic) {
+			return CompressDecision{Reason: fmt.Sprintf("content looks like %s", m.name)}, nil
+		}
+	}
+
+	// 파일 여기저기서 샘플을 떠서 같은 코덱으로 시험 압축
+	var in, out int64
+	var dst bytes.Buffer
+	stream := newPartStream(c, f, fi.Size(), 0, 1, "", fi.ModTime())
+	for _, off := range sampleOffsets(fi.Size()) {
+		seg := Segment{Offset: off, Size: min(detectSampleSize, fi.Size()-off)}
+		dst.Reset()
+		if _, err := stream.compressRange(&dst, seg); err != nil {
+			return CompressDecision{}, fmt.Errorf("trial compression: %w", err)
+		}
+		in += seg.Size
+		out += int64(dst.Len())
+	}
+	if in == 0 {
+		return CompressDecision{Compress: true, Reason: "empty file"}, nil
+	}
+	ratio := float64(out) / float64(in)
+	if ratio > incompressibleRatio {
+		return CompressDecision{Reason: fmt.Sprintf("trial %s compression ratio %.2f is above %.2f", c.Name, ratio, incompressibleRatio), Ratio: ratio}, nil
+	}
+	return CompressDecision{Compress: true, Reason: fmt.Sprintf("trial %s compression ratio %.2f", c.Name, ratio), Ratio: ratio}, nil
+}
+
+// sampleOffsets spreads detectSamples windows over a file of the given size.
+func sampleOffsets(size int64) []int64 {
+	if size <= detectSamples*detectSampleSize {
+		offs := []int64{}
+		for off := int64(0); off < size; off += detectSampleSize {
+			offs = append(offs, off)
+		}
+		return offs
+	}
+	offs := make([]int64, detectSamples)
+	step := (size - detectSampleSize) / (detectSamples - 1)
+	for i := range offs {
+		offs[i] = int64(i) * step
+	}
+	return offs
+}
@@ -0,0 +1,493 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/scheduler"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/tracing"
+	"github.com/GoCOMA/Favus/pkg/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Destination is one target of a fan-out upload.
+type Destination struct {
+	URL      string // as given on the command line, e.g. s3://bucket/key?region=eu-west-1
+	Bucket   string
+	Key      string
+	Region   string // empty: the default region
+	Location string // non-S3 backend (mem://...), recorded in the status file
+	Store    storage.ObjectStore
+}
+
+// FanOutResult is the outcome for one destination.
+type FanOutResult struct {
+	Destination string `json:"destination"`
+	Location    string `json:"location"` // bucket in URL form (s3://, file://, mem://)
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	UploadID    string `json:"uploadId,omitempty"`
+	RunID       string `json:"runId"`
+	StatusFile  string `json:"statusFile,omitempty"` // kept for `favus resume` when the destination failed
+	Error       string `json:"error,omitempty"`
+}
+
+// fanOutTarget is the per-destination multipart session.
+type fanOutTarget struct {
+	Destination
+	uploadID   string
+	statusPath string
+	status     *WSTracker
+	lock       *SessionLock
+	sched      *scheduler.Stream
+
+	r   *wsReporter
+	rmu sync.Mutex // wsReporter is not safe for concurrent use
+
+	mu    sync.Mutex
+	parts []s3types.CompletedPart
+	err   error
+}
+
+func (t *fanOutTarget) failed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err != nil
+}
+
+// fail records the first error; the session is left open so it can be resumed.
+func (t *fanOutTarget) fail(err error, partNum *int) {
+	t.mu.Lock()
+	first := t.err == nil
+	if first {
+		t.err = err
+	}
+	t.mu.Unlock()
+	if !first {
+		return
+	}
+	utils.Error(fmt.Sprintf("Fan-out destination %s failed: %v", t.URL, err))
+	t.report(func(r *wsReporter) {
+		r.error(err.Error(), partNum)
+		r.done(false, t.uploadID)
+	})
+}
+
+func (t *fanOutTarget) report(fn func(r *wsReporter)) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+	fn(t.r)
+}
+
+// readSeekNopCloser lets a shared part buffer go through ReadSeekCloserProgress.
+type readSeekNopCloser struct{ *bytes.Reader }
+
+func (readSeekNopCloser) Close() error { return nil }
+
+// FanOutUpload uploads filePath to every destination at once. Each part is read
+// from disk a single time and the same buffer is sent to all destinations, each
+// of which has its own multipart session, status file and WS run. A failing
+// destination does not stop the others; its status file is kept for resume.
+func (u *Uploader) FanOutUpload(filePath string, dests []Destination) (_ []FanOutResult, err error) {
+	if len(dests) == 0 {
+		return nil, fmt.Errorf("no destinations given")
+	}
+	utils.Info(fmt.Sprintf("Starting fan-out upload for file: %s to %d destinations", filePath, len(dests)))
+	sp := tracing.Start("favus fanout", "favus.file", filePath, "favus.destinations", len(dests))
+	defer func() { sp.End(err) }()
+
+	originalInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if originalInfo.Size() == 0 {
+		utils.Info(fmt.Sprintf("File %s is empty, skipping upload", filePath))
+		return nil, nil
+	}
+	if u.duplicateChecker != nil {
+		utils.Info("Duplicate check is not applied to fan-out uploads")
+	}
+
+	uploadSize := originalInfo.Size()
+	sp.Set("favus.bytes", uploadSize)
+	var csp *tracing.Span
+	if u.Config.Compress {
+		csp = sp.Child("compression decision")
+	}
+	cd, decision, err := u.compression(filePath)
+	if decision != nil {
+		csp.Set("favus.compress", decision.Compress, "favus.compress.reason", decision.Reason)
+	}
+	csp.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("compression: %w", err)
+	}
+	metadata := compressionMetadata(cd, decision, filePath, originalInfo.Size())
+	if cd != nil {
+		// 압축은 파트마다 한 번만 하고 같은 버퍼를 모든 대상이 공유
+		utils.Info(fmt.Sprintf("Compression enabled (%s); compressing parts while uploading.", cd.Name))
+	}
+
+	partSize := u.Config.PartSizeBytes()
+	plan := planParts(uploadSize, partSize)
+	groupID := uuid.NewString()
+
+	statusDir := StatusDir()
+	os.MkdirAll(statusDir, 0755)
+
+	targets := make([]*fanOutTarget, len(dests))
+	runIDs := make([]string, len(dests))
+	for i, d := range dests {
+		if cd != nil {
+			d.Key = cd.key(d.Key)
+		}
+		targets[i] = &fanOutTarget{Destination: d, r: newWSReporter(uploadSize)}
+		targets[i].r.trace(sp.Child("favus upload", "favus.destination", d.URL, "aws.s3.bucket", d.Bucket, "aws.s3.key", d.Key))
+		runIDs[i] = targets[i].r.runID
+	}
+	defer func() {
+		for _, t := range targets {
+			t.lock.Release()
+		}
+	}()
+
+	// 대상별 세션 시작 (실패한 대상은 건너뛰고 나머지는 계속)
+	ctx := context.Background()
+	for i, t := range targets {
+		if err := u.checkBucketIn(t.Store, t.Bucket); err != nil {
+			t.fail(err, nil)
+			continue
+		}
+		in := &s3.CreateMultipartUploadInput{Bucket: aws.String(t.Bucket), Key: aws.String(t.Key)}
+		if cd != nil {
+			in.ContentEncoding = aws.String(cd.Name)
+		}
+		if len(metadata) > 0 {
+			in.Metadata = metadata
+		}
+		isp := s3Span(t.r.span, "CreateMultipartUpload", t.Bucket, t.Key)
+		out, err := t.Store.CreateMultipartUpload(ctx, in)
+		isp.End(err)
+		if err != nil {
+			t.fail(fmt.Errorf("initiate multipart upload: %w", err), nil)
+			continue
+		}
+		t.uploadID = aws.ToString(out.UploadId)
+		t.r.span.Set("aws.s3.upload_id", t.uploadID)
+		utils.Info(fmt.Sprintf("Initiated multipart upload for %s with UploadID: %s", t.URL, t.uploadID))
+
+		t.statusPath = filepath.Join(statusDir, fmt.Sprintf("%s_%s.upload_status", filepath.Base(filePath), t.uploadID[:8]))
+		us := NewUploadStatus(filePath, t.Bucket, t.Key, t.uploadID, len(plan), partSize)
+		if cd != nil {
+			// TotalParts 는 압축이 끝나야 확정된다 (그 전까지 0)
+			us.OriginalFilePath = filePath
+			us.Compression = cd.Name
+			us.CompressionLevel = cd.Level
+			us.CompressionBlock = compressBlockSize
+			us.Segments = make(map[int]Segment)
+			us.TotalParts = 0
+		}
+		us.Location = t.Location
+		us.Region = t.Region
+		us.FanOutGroup = groupID
+		t.status = NewWSTracker(us)
+		// 상태 파일을 먼저 써야 잠금만 남는 일이 없다 (잠그기 전에 죽으면 resume 으로 이어간다)
+		if err := t.status.SaveStatus(t.statusPath); err != nil {
+			utils.Error(fmt.Sprintf("Failed to save status for %s: %v", t.URL, err))
+		}
+		if lock, lerr := AcquireLock(t.statusPath, us, false); lerr != nil {
+			utils.Error(fmt.Sprintf("Failed to lock status file for %s: %v", t.URL, lerr))
+		} else {
+			t.lock = lock
+		}
+
+		extra := map[string]any{
+			"destination": t.URL,
+			"fanOut": map[string]any{
+				"groupId": groupID,
+				"index":   i,
+				"total":   len(targets),
+				"runIds":  runIDs,
+			},
+		}
+		if cd != nil {
+			extra["compressed"] = true
+			extra["compression"] = cd.Name
+			extra["streamed"] = true
+			extra["originalBytes"] = originalInfo.Size()
+			extra["originalName"] = filepath.Base(filePath)
+		} else if decision != nil {
+			extra["compressionSkipped"] = decision.Reason
+		}
+		t.report(func(r *wsReporter) { r.start(t.Bucket, t.Key, t.uploadID, partSize, extra) })
+	}
+
+	totalBar := progressbar.NewOptions64(
+		uploadSize*int64(alive(targets)),
+		progressbar.OptionSetDescription(fmt.Sprintf("total ×%d", alive(targets))),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionSetWriter(os.Stdout),
+	)
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	// nextPart 는 다음 파트를 buf 에 채우고 (파트, 원본 바이트 수)를 돌려준다
+	var nextPart func(buf *bytes.Buffer) (PartPlan, int64, error)
+	if cd != nil {
+		stream := newPartStream(cd, f, originalInfo.Size(), partSize, u.Config.MaxConcurrency, filepath.Base(filePath), originalInfo.ModTime())
+		defer stream.Close()
+		nextPart = func(buf *bytes.Buffer) (PartPlan, int64, error) {
+			psp := sp.Child("compress part") // 모든 대상이 같은 압축 결과를 쓴다
+			n, seg, err := stream.Next(buf)
+			if errors.Is(err, io.EOF) {
+				return PartPlan{}, 0, err
+			}
+			psp.Set("favus.part", n, "favus.source_bytes", seg.Size, "favus.compressed_bytes", int64(buf.Len()))
+			psp.End(err)
+			if err != nil {
+				return PartPlan{}, 0, err
+			}
+			// 업로드 전에 범위를 기록해야 중단돼도 같은 파트를 다시 만들 수 있다
+			for _, t := range targets {
+				if t.status == nil || t.failed() {
+					continue
+				}
+				t.status.SetSegment(n, seg)
+				if err := t.status.SaveStatus(t.statusPath); err != nil {
+					utils.Error(fmt.Sprintf("Failed to save status for %s: %v", t.URL, err))
+				}
+			}
+			return PartPlan{PartNumber: n, Offset: seg.Offset, Size: int64(buf.Len())}, seg.Size, nil
+		}
+	} else {
+		next := 0
+		nextPart = func(buf *bytes.Buffer) (PartPlan, int64, error) {
+			if next >= len(plan) {
+				return PartPlan{}, 0, io.EOF
+			}
+			pt := plan[next]
+			next++
+			buf.Reset()
+			if _, err := io.CopyN(buf, io.NewSectionReader(f, pt.Offset, pt.Size), pt.Size); err != nil {
+				return PartPlan{}, 0, fmt.Errorf("read part %d: %w", pt.PartNumber, err)
+			}
+			return pt, pt.Size, nil
+		}
+	}
+
+	maxConcurrency := u.Config.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	// 대상들이 파트 슬롯과 in-flight 바이트를 나눠 쓴다: maxConcurrency 는 대상 하나가
+	// 아니라 전체 요청 수 (큐와 같은 의미). 공유 스케줄러가 있으면 그것을 따른다
+	sched := u.Scheduler
+	if sched == nil {
+		sched = scheduler.New(maxConcurrency, u.Config.MaxInFlightBytes())
+	}
+	for _, t := range targets {
+		t.sched = sched.Stream(originalInfo.Size())
+	}
+	// 동시에 읽어 둔 파트 수를 maxConcurrency 로 제한 → 메모리 사용량 = maxConcurrency × partSize
+	free := make(chan *bytes.Buffer, maxConcurrency)
+	for i := 0; i < maxConcurrency; i++ {
+		free <- new(bytes.Buffer)
+	}
+	var wg sync.WaitGroup
+	parts := 0
+	for alive(targets) > 0 {
+		buf := <-free
+		pt, srcSize, err := nextPart(buf)
+		if errors.Is(err, io.EOF) {
+			free <- buf
+			break
+		}
+		if err != nil {
+			for _, t := range targets {
+				t.fail(err, nil)
+			}
+			free <- buf
+			break
+		}
+		parts++
+
+		wg.Add(1)
+		go func(pt PartPlan, srcSize int64, buf *bytes.Buffer) {
+			defer wg.Done()
+			defer func() { free <- buf }()
+			var pwg sync.WaitGroup
+			for _, t := range targets {
+				if t.failed() {
+					continue
+				}
+				pwg.Add(1)
+				go func(t *fanOutTarget) {
+					defer pwg.Done()
+					grant, err := t.sched.Acquire(ctx, int64(buf.Len()))
+					if err != nil {
+						return
+					}
+					u.fanOutPart(t, pt, srcSize, buf.Bytes(), totalBar)
+					if t.failed() {
+						grant.Release(0)
+					} else {
+						grant.Release(srcSize)
+					}
+				}(t)
+			}
+			pwg.Wait()
+		}(pt, srcSize, buf)
+	}
+	wg.Wait()
+
+	// 살아남은 대상만 Complete
+	for _, t := range targets {
+		if t.failed() {
+			continue
+		}
+		if cd != nil {
+			t.status.TotalParts = parts
+		}
+		sort.Slice(t.parts, func(i, j int) bool {
+			return aws.ToInt32(t.parts[i].PartNumber) < aws.ToInt32(t.parts[j].PartNumber)
+		})
+		fsp := s3Span(t.r.span, "CompleteMultipartUpload", t.Bucket, t.Key, "aws.s3.upload_id", t.uploadID, "favus.parts", len(t.parts))
+		_, err := t.Store.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(t.Bucket),
+			Key:             aws.String(t.Key),
+			UploadId:        aws.String(t.uploadID),
+			MultipartUpload: &s3types.CompletedMultipartUpload{Parts: t.parts},
+		})
+		fsp.End(err)
+		if err != nil {
+			t.fail(fmt.Errorf("complete multipart upload: %w", err), nil)
+			continue
+		}
+		utils.Info(fmt.Sprintf("Multipart upload completed successfully for %s", t.URL))
+		t.report(func(r *wsReporter) { r.done(true, t.uploadID) })
+		if err := os.Remove(t.statusPath); err != nil {
+			utils.Error(fmt.Sprintf("Failed to remove status file %s: %v", t.statusPath, err))
+		}
+		t.statusPath = ""
+	}
+
+	sp.Set("favus.parts", parts)
+	for _, t := range targets {
+		t.r.span.Set("favus.parts", len(t.parts))
+		t.r.span.End(t.err)
+	}
+
+	// Add a small delay to ensure WebSocket messages are sent before exiting.
+	time.Sleep(1 * time.Second)
+
+	results := make([]FanOutResult, len(targets))
+	failed := 0
+	for i, t := range targets {
+		results[i] = FanOutResult{
+			Destination: t.URL,
+			Location:    locationURL(t.Location, t.Bucket),
+			Bucket:      t.Bucket,
+			Key:         t.Key,
+			UploadID:    t.uploadID,
+			RunID:       t.r.runID,
+			StatusFile:  t.statusPath,
+		}
+		if t.err != nil {
+			results[i].Error = t.err.Error()
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d destinations failed", failed, len(targets))
+	}
+	return results, nil
+}
+
+// fanOutPart uploads one already-read part to one destination.
+func (u *Uploader) fanOutPart(t *fanOutTarget, pt PartPlan, srcSize int64, buf []byte, totalBar *progressbar.ProgressBar) {
+	t.report(func(r *wsReporter) { r.partStart(pt.PartNumber, pt.Size, pt.Offset) })
+
+	var out *s3.UploadPartOutput
+	attempt := 0
+	err := utils.Retry(5, 2*time.Second, func() error {
+		if attempt++; attempt > 1 {
+			t.report(func(r *wsReporter) { r.partRetry(pt.PartNumber, attempt) })
+		}
+		pr := NewReadSeekCloserProgress(readSeekNopCloser{bytes.NewReader(buf)}, func(n int64) {
+			src := n // 진행률은 원본 바이트 기준
+			if pt.Size > 0 && srcSize != pt.Size {
+				src = n * srcSize / pt.Size
+			}
+			_ = totalBar.Add64(src)
+			t.report(func(r *wsReporter) {
+				r.progressAdd(src)
+				r.partProgressAdd(pt.PartNumber, n)
+			})
+		})
+		var partErr error
+		psp := s3Span(t.r.span, "UploadPart", t.Bucket, t.Key,
+			"aws.s3.upload_id", t.uploadID, "aws.s3.part_number", pt.PartNumber, "favus.attempt", attempt, "favus.part_bytes", pt.Size)
+		out, partErr = t.Store.UploadPart(context.Background(), &s3.UploadPartInput{
+			Body:          pr,
+			Bucket:        aws.String(t.Bucket),
+			Key:           aws.String(t.Key),
+			PartNumber:    aws.Int32(int32(pt.PartNumber)),
+			UploadId:      aws.String(t.uploadID),
+			ContentLength: aws.Int64(pt.Size),
+		})
+		psp.End(partErr)
+		if partErr != nil {
+			utils.Error(fmt.Sprintf("[%s] Failed to upload part %d: %v", t.URL, pt.PartNumber, partErr))
+		}
+		return partErr
+	})
+	if err == nil && out.ETag == nil {
+		err = fmt.Errorf("ETag for part %d is nil", pt.PartNumber)
+	}
+	if err != nil {
+		pn := pt.PartNumber
+		t.fail(fmt.Errorf("upload part %d: %w", pt.PartNumber, err), &pn)
+		return
+	}
+
+	t.mu.Lock()
+	t.parts = append(t.parts, s3types.CompletedPart{PartNumber: aws.Int32(int32(pt.PartNumber)), ETag: out.ETag})
+	t.mu.Unlock()
+	t.status.AddCompletedPart(pt.PartNumber, *out.ETag)
+	if err := t.status.SaveStatus(t.statusPath); err != nil {
+		utils.Error(fmt.Sprintf("[%s] Failed to save status for part %d: %v", t.URL, pt.PartNumber, err))
+	}
+	utils.Info(fmt.Sprintf("[%s] Successfully uploaded part %d. ETag: %s", t.URL, pt.PartNumber, *out.ETag))
+	t.report(func(r *wsReporter) { r.partDone(pt.PartNumber, pt.Size, *out.ETag) })
+}
+
+func alive(targets []*fanOutTarget) int {
+	n := 0
+	for _, t := range targets {
+		if !t.failed() {
+			n++
+		}
+	}
+	return n
+}
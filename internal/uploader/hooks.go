@@ -0,0 +1,91 @@
+package uploader
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/hooks"
+	"github.com/GoCOMA/Favus/pkg/utils"
+)
+
+// hookRun carries one upload session through the configured hooks: pre runs
+// where the WS session starts, finish (deferred) runs postUpload or onFailure
+// once the upload returns.
+type hookRun struct {
+	hooks     config.Hooks
+	session   hooks.Session
+	started   time.Time
+	completed bool
+}
+
+func newHookRun(h config.Hooks, bucket, key, file string, size int64, resumed bool) *hookRun {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return &hookRun{
+		hooks:   h,
+		started: time.Now(),
+		session: hooks.Session{Bucket: bucket, Key: key, File: file, Bytes: size, Resumed: resumed},
+	}
+}
+
+// pre runs preUpload for the session that has just been started. A non-nil
+// error is the hook's veto.
+func (h *hookRun) pre(key, uploadID, runID string) error {
+	h.session.Key, h.session.UploadID, h.session.RunID = key, uploadID, runID
+	if err := h.run(hooks.PreUpload, h.hooks.PreUpload); err != nil {
+		return fmt.Errorf("upload vetoed: %w", err)
+	}
+	return nil
+}
+
+// created records the multipart upload of a session whose preUpload ran
+// before it existed, for the hooks that follow.
+func (h *hookRun) created(key, uploadID string) {
+	h.session.Key, h.session.UploadID = key, uploadID
+}
+
+// complete records that the object exists, with its final ETag.
+func (h *hookRun) complete(etag string) {
+	h.completed = true
+	h.session.ETag = etag
+}
+
+// finish runs onFailure when *errp is set and postUpload when the object was
+// completed. A failing postUpload hook becomes the upload's error (the
+// object itself stays).
+func (h *hookRun) finish(errp *error) {
+	switch {
+	case *errp != nil:
+		h.session.Error = (*errp).Error()
+		_ = h.run(hooks.OnFailure, h.hooks.OnFailure) // 실패 훅의 종료 코드는 로그로만
+	case h.completed:
+		if err := h.run(hooks.PostUpload, h.hooks.PostUpload); err != nil {
+			utils.Error(fmt.Sprintf("s3://%s/%s was uploaded but its postUpload hook failed", h.session.Bucket, h.session.Key))
+			*errp = err
+		}
+	}
+}
+
+// hookNotes lists the configured hooks for dry-run plans (they do not run there).
+func hookNotes(h config.Hooks) []string {
+	var notes []string
+	for _, e := range []struct {
+		event string
+		hook  config.Hook
+	}{{hooks.PreUpload, h.PreUpload}, {hooks.PostUpload, h.PostUpload}, {hooks.OnFailure, h.OnFailure}} {
+		if e.hook.Command != "" {
+			notes = append(notes, fmt.Sprintf("%s hook: %s", e.event, e.hook.Command))
+		}
+	}
+	return notes
+}
+
+func (h *hookRun) run(event string, hook config.Hook) error {
+	s := h.session
+	s.Event = event
+	s.DurationMs = time.Since(h.started).Milliseconds()
+	return hooks.Run(hook, s)
+}
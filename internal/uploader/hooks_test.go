@@ -0,0 +1,94 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// preUpload 가 원본을 새로 쓰면 업로드는 훅이 끝난 뒤의 파일을 올려야 한다.
+func TestPreUploadHookRewritesSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command uses sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DISABLE_DUPLICATE_CHECK", "true")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "dump.bin")
+	if err := os.WriteFile(src, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 파트 두 개가 필요한 크기로 다시 쓴다 (5 MiB + 1)
+	fresh := filepath.Join(dir, "fresh.bin")
+	want := bytes.Repeat([]byte("favus"), (5<<20)/5+1)
+	if err := os.WriteFile(fresh, want, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := storage.OpenLocal(storage.Location{Scheme: storage.SchemeMemory, Bucket: "hooks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{Bucket: "hooks", PartSizeMB: 5, MaxConcurrency: 2}
+	conf.Hooks.PreUpload.Command = "cp " + fresh + " " + src
+	u := NewUploaderWithStore(conf, st)
+	u.Location = "mem://hooks"
+
+	if err := u.UploadFile(src, "dump.bin"); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	out, err := st.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String("hooks"), Key: aws.String("dump.bin")})
+	if err != nil {
+		t.Fatalf("get object: %v", err)
+	}
+	defer out.Body.Close()
+	got, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("uploaded %d bytes, want the %d bytes the hook wrote", len(got), len(want))
+	}
+}
+
+// 거부된 업로드는 멀티파트 업로드를 만들지 않는다.
+func TestPreUploadHookVetoCreatesNothing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command uses sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DISABLE_DUPLICATE_CHECK", "true")
+
+	src := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(src, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st, err := storage.OpenLocal(storage.Location{Scheme: storage.SchemeMemory, Bucket: "veto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{Bucket: "veto", PartSizeMB: 5, MaxConcurrency: 1}
+	conf.Hooks.PreUpload.Command = "exit 3"
+	u := NewUploaderWithStore(conf, st)
+
+	if err := u.UploadFile(src, "a.bin"); err == nil {
+		t.Fatal("upload succeeded despite the preUpload veto")
+	}
+	out, err := st.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{Bucket: aws.String("veto")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Uploads) != 0 {
+		t.Fatalf("%d multipart upload(s) created before the veto", len(out.Uploads))
+	}
+}
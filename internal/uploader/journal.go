@@ -0,0 +1,246 @@
+package uploader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Status files are a header line followed by an append-only journal:
+//
+//	{"favusStatus":2,"filePath":"...","uploadId":"...",...}   every field but the parts
+//	{"part":1,"etag":"\"9b2c…\""}
+//	{"part":2,"segment":{"offset":0,"size":...}}               streamed compression
+//	{"part":2,"etag":"\"41d8…\""}
+//
+// A save appends the parts recorded since the previous save and fsyncs, so a
+// crash loses at most the line being written. Compaction rewrites the file
+// (header plus one line per part) to a temp file and renames it over the old
+// one. It runs on the first save of a process, when a header field changed,
+// and once the journal has grown past twice the live parts, which keeps the
+// I/O of an n-part upload O(n).
+const statusFormat = 2
+
+// compactMinLines is the journal length below which a file is never compacted
+// just for its size.
+const compactMinLines = 1024
+
+// statusFields has the fields of UploadStatus without its methods, so the
+// header can embed it.
+type statusFields UploadStatus
+
+// statusHeader is the first line of a status file. The part maps are shadowed
+// by empty fields of the same name: parts live in the journal lines.
+type statusHeader struct {
+	Format int `json:"favusStatus"`
+	*statusFields
+	CompletedParts map[int]string  `json:"completedParts,omitempty"`
+	Segments       map[int]Segment `json:"segments,omitempty"`
+}
+
+// journalEntry is one journal line. A part may appear several times; later
+// lines win.
+type journalEntry struct {
+	Part    int      `json:"part"`
+	ETag    string   `json:"etag,omitempty"`
+	Segment *Segment `json:"segment,omitempty"`
+}
+
+// statusJournal is what this process knows is on disk.
+type statusJournal struct {
+	path   string
+	header []byte // header line as written
+	lines  int    // journal lines after the header
+	live   int    // lines right after the last compaction
+}
+
+// saveLocked writes the parts recorded since the last save. us.Mu is held.
+func (us *UploadStatus) saveLocked(path string) error {
+	header, err := json.Marshal(statusHeader{Format: statusFormat, statusFields: (*statusFields)(us)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload status: %w", err)
+	}
+	j := us.journal
+	if j == nil || j.path != path || !bytes.Equal(j.header, header) || j.lines >= max(compactMinLines, 2*j.live) {
+		return us.compactLocked(path, header)
+	}
+	if len(us.pending) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, e := range us.pending {
+		if err := writeJournalLine(&buf, e); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		// 파일이 지워졌거나 옮겨졌다 — 통째로 다시 쓴다
+		return us.compactLocked(path, header)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		// 일부만 쓰였을 수 있으니 다음 저장은 압축(재작성)으로
+		us.journal = nil
+		return fmt.Errorf("append upload status: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		us.journal = nil
+		return fmt.Errorf("sync upload status: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		us.journal = nil
+		return fmt.Errorf("close upload status: %w", err)
+	}
+	j.lines += len(us.pending)
+	us.pending = nil
+	return nil
+}
+
+// compactLocked replaces the file at path with the header and one line per
+// part, via a synced temp file and a rename.
+func (us *UploadStatus) compactLocked(path string, header []byte) error {
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	entries := us.entriesLocked()
+	for _, e := range entries {
+		if err := writeJournalLine(&buf, e); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("write upload status: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write upload status: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("sync upload status: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write upload status: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace upload status: %w", err)
+	}
+	syncDir(filepath.Dir(path))
+
+	us.journal = &statusJournal{path: path, header: header, lines: len(entries), live: len(entries)}
+	us.pending = nil
+	return nil
+}
+
+// entriesLocked returns one entry per known part, in part order.
+func (us *UploadStatus) entriesLocked() []journalEntry {
+	byPart := make(map[int]*journalEntry, len(us.CompletedParts))
+	get := func(n int) *journalEntry {
+		e, ok := byPart[n]
+		if !ok {
+			e = &journalEntry{Part: n}
+			byPart[n] = e
+		}
+		return e
+	}
+	for n, seg := range us.Segments {
+		seg := seg
+		get(n).Segment = &seg
+	}
+	for n, etag := range us.CompletedParts {
+		get(n).ETag = etag
+	}
+	entries := make([]journalEntry, 0, len(byPart))
+	for _, e := range byPart {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Part < entries[j].Part })
+	return entries
+}
+
+func writeJournalLine(buf *bytes.Buffer, e journalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal part %d: %w", e.Part, err)
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// parseStatus decodes a status file: the journal format, or the single JSON
+// document written by older versions. A torn last journal line (a crash in
+// the middle of an append) is dropped; the next save then compacts the file.
+func parseStatus(path string, data []byte) (*UploadStatus, error) {
+	us := &UploadStatus{}
+	first, rest, _ := bytes.Cut(data, []byte("\n"))
+	var h statusHeader
+	h.statusFields = (*statusFields)(us)
+	if err := json.Unmarshal(first, &h); err != nil || h.Format == 0 {
+		// 이전 버전: 파일 전체가 하나의 JSON
+		us = &UploadStatus{}
+		if err := json.Unmarshal(data, us); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal upload status: %w", err)
+		}
+		if us.CompletedParts == nil {
+			us.CompletedParts = make(map[int]string)
+		}
+		return us, nil
+	}
+	if h.Format > statusFormat {
+		return nil, fmt.Errorf("upload status format %d is newer than this favus supports (%d)", h.Format, statusFormat)
+	}
+	us.CompletedParts = make(map[int]string)
+
+	lines := bytes.Split(rest, []byte("\n"))
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1] // 마지막 줄바꿈 뒤의 빈 조각
+	}
+	clean := true
+	for i, line := range lines {
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil || e.Part <= 0 {
+			if i == len(lines)-1 {
+				clean = false
+				break
+			}
+			return nil, fmt.Errorf("upload status %s: corrupt journal line %d", path, i+2)
+		}
+		if e.ETag != "" {
+			us.CompletedParts[e.Part] = e.ETag
+		}
+		if e.Segment != nil {
+			if us.Segments == nil {
+				us.Segments = make(map[int]Segment)
+			}
+			us.Segments[e.Part] = *e.Segment
+		}
+	}
+	if clean && len(data) > 0 && data[len(data)-1] == '\n' {
+		us.journal = &statusJournal{path: path, header: first, lines: len(lines), live: len(lines)}
+	}
+	return us, nil
+}
+
+// syncDir makes a rename in dir durable. Best effort: not every platform can
+// sync a directory.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
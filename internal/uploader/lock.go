@@ -0,0 +1,128 @@
+package uploader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoCOMA/Favus/internal/lockfile"
+)
+
+// lockSuffix is appended to a status file path to name its lock file.
+const lockSuffix = ".lock"
+
+// SessionLock is the advisory lock of one status file, held by the process
+// driving its upload (upload, resume, fan-out). The lock file next to the
+// status file records who holds it.
+type SessionLock struct {
+	lockfile.Holder
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	UploadID string `json:"uploadId"`
+
+	path string
+}
+
+// LockedError is returned when another live process holds the lock.
+type LockedError struct {
+	Holder SessionLock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("upload %s is in use by %s (lock file %s)", e.Holder.UploadID, e.Holder.Owner(), e.Holder.path)
+}
+
+// StatusDir is where upload and resume keep status files (~/.favus/status).
+func StatusDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".favus", "status")
+}
+
+// AcquireLock takes the lock of statusFilePath for the upload in us. A stale
+// lock is replaced; a live one fails with *LockedError unless force is set.
+func AcquireLock(statusFilePath string, us *UploadStatus, force bool) (*SessionLock, error) {
+	l := &SessionLock{
+		Holder:   lockfile.Self(),
+		Bucket:   us.Bucket,
+		Key:      us.Key,
+		UploadID: us.UploadID,
+		path:     statusFilePath + lockSuffix,
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("marshal lock: %w", err)
+	}
+	err = lockfile.Acquire(l.path, data, force)
+	var busy *lockfile.BusyError
+	if errors.As(err, &busy) && busy.Holder.PID > 0 {
+		holder := SessionLock{path: l.path}
+		if json.Unmarshal(busy.Data, &holder) == nil {
+			return nil, &LockedError{Holder: holder}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Release removes the lock file if it is still ours. Safe on a nil lock.
+func (l *SessionLock) Release() {
+	if l == nil {
+		return
+	}
+	lockfile.Release(l.path, l.Holder)
+}
+
+// ReadLock returns the lock of statusFilePath, or nil when it is not locked.
+func ReadLock(statusFilePath string) (*SessionLock, error) {
+	l, err := readLock(statusFilePath + lockSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// ActiveLocks returns the locks in dir whose holders are not known to be
+// gone, keyed by upload ID. Stale locks without a status file next to them
+// (left by a process killed before its first save) are removed.
+func ActiveLocks(dir string) map[string]SessionLock {
+	locks := make(map[string]SessionLock)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return locks
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), lockSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		l, err := readLock(path)
+		if err != nil || l.UploadID == "" || l.Stale() {
+			if err == nil && l.Stale() {
+				if _, serr := os.Stat(strings.TrimSuffix(path, lockSuffix)); errors.Is(serr, os.ErrNotExist) {
+					_ = os.Remove(path)
+				}
+			}
+			continue
+		}
+		locks[l.UploadID] = *l
+	}
+	return locks
+}
+
+func readLock(path string) (*SessionLock, error) {
+	_, data, err := lockfile.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	var l SessionLock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s", path)
+	}
+	l.path = path
+	return &l, nil
+}
@@ -0,0 +1,294 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PartPlan is one planned UploadPart call.
+type PartPlan struct {
+	PartNumber int   `json:"partNumber"`
+	Offset     int64 `json:"offset"`
+	Size       int64 `json:"size"`
+	Done       bool  `json:"done,omitempty"` // resume: already on the server
+}
+
+// DuplicateVerdict is what the duplicate checker would decide.
+type DuplicateVerdict struct {
+	Checked bool   `json:"checked"`
+	Upload  bool   `json:"upload"`
+	Reason  string `json:"reason,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UploadPlan describes what UploadFile or ResumeUpload would do, computed
+// without any mutating call (no CreateMultipartUpload, UploadPart, chunk files
+// or temp archives).
+type UploadPlan struct {
+	Operation       string            `json:"operation"` // upload | resume
+	FilePath        string            `json:"filePath"`
+	FileSize        int64             `json:"fileSize"`
+	Bucket          string            `json:"bucket"`
+	Location        string            `json:"location,omitempty"`
+	Key             string            `json:"key"`
+	RequestedKey    string            `json:"requestedKey,omitempty"` // set when the key is rewritten (.gz)
+	UploadID        string            `json:"uploadId,omitempty"`
+	Compress        bool              `json:"compress"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	CompressLevel   int               `json:"compressLevel,omitempty"`
+	CompressCheck   *CompressDecision `json:"compressCheck,omitempty"` // incompressible-input detection
+	Archive         string            `json:"archive,omitempty"`       // --archive: tar, tar+gzip or tar+zstd
+	PartSizeBytes   int64             `json:"partSizeBytes"`
+	MaxConcurrency  int               `json:"maxConcurrency"`
+	TotalParts      int               `json:"totalParts"`
+	RemainingParts  int               `json:"remainingParts"`
+	RemainingBytes  int64             `json:"remainingBytes"`
+	Parts           []PartPlan        `json:"parts"`
+	BucketCheck     string            `json:"bucketCheck"`
+	Duplicate       *DuplicateVerdict `json:"duplicate,omitempty"`
+	Notes           []string          `json:"notes,omitempty"`
+}
+
+// planParts splits size into partSize pieces the same way the chunker does.
+func planParts(size, partSize int64) []PartPlan {
+	if size <= 0 || partSize <= 0 {
+		return nil
+	}
+	parts := make([]PartPlan, 0, (size+partSize-1)/partSize)
+	for off, n := int64(0), 1; off < size; off, n = off+partSize, n+1 {
+		sz := partSize
+		if off+sz > size {
+			sz = size - off
+		}
+		parts = append(parts, PartPlan{PartNumber: n, Offset: off, Size: sz})
+	}
+	return parts
+}
+
+func (u *Uploader) planBucketCheck(bucket string) string {
+	if err := u.checkBucket(bucket); err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// PlanUpload reports what UploadFile(filePath, s3Key) would do.
+func (u *Uploader) PlanUpload(filePath, s3Key string) (*UploadPlan, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	p := &UploadPlan{
+		Operation:      "upload",
+		FilePath:       filePath,
+		FileSize:       fi.Size(),
+		Bucket:         u.Config.Bucket,
+		Location:       u.Location,
+		Key:            s3Key,
+		Compress:       u.Config.Compress,
+		PartSizeBytes:  u.Config.PartSizeBytes(),
+		MaxConcurrency: u.Config.MaxConcurrency,
+		BucketCheck:    u.planBucketCheck(u.Config.Bucket),
+	}
+
+	if u.duplicateChecker != nil {
+		upload, reason, err := u.duplicateChecker.CheckDuplicate(context.Background(), filePath, u.Config)
+		p.Duplicate = &DuplicateVerdict{Checked: true, Upload: upload, Reason: reason}
+		if err != nil {
+			p.Duplicate.Error = err.Error()
+		}
+		if !upload {
+			p.Notes = append(p.Notes, "duplicate check would skip this upload: "+reason)
+		}
+	} else {
+		p.Duplicate = &DuplicateVerdict{Upload: true, Reason: "duplicate check disabled or unavailable"}
+	}
+
+	if fi.Size() == 0 {
+		p.Notes = append(p.Notes, "file is empty; upload would be skipped")
+		return p, nil
+	}
+
+	cd, decision, err := u.compression(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("compression: %w", err)
+	}
+	p.CompressCheck = decision
+	p.Compress = cd != nil
+	if cd != nil {
+		if k := cd.key(s3Key); k != s3Key {
+			p.RequestedKey, p.Key = s3Key, k
+		}
+		p.ContentEncoding = cd.Name
+		p.CompressLevel = cd.Level
+		p.Notes = append(p.Notes, fmt.Sprintf(
+			"parts are compressed while uploading (1 MiB %s blocks, %d workers) and cut once their output reaches the part size; parts below are for the uncompressed %d bytes (upper bound)", cd.Name, max(p.MaxConcurrency, 1), fi.Size()))
+	} else if decision != nil {
+		p.Notes = append(p.Notes, "compression skipped: "+decision.Reason)
+	}
+
+	p.Parts = planParts(fi.Size(), p.PartSizeBytes)
+	p.TotalParts = len(p.Parts)
+	p.RemainingParts = p.TotalParts
+	p.RemainingBytes = fi.Size()
+	p.Notes = append(p.Notes, hookNotes(u.Config.Hooks)...)
+	return p, nil
+}
+
+// PlanResume reports what ResumeUpload(statusFilePath) would upload after
+// reconciling the status file with ListParts.
+func (u *Uploader) PlanResume(statusFilePath string) (*UploadPlan, error) {
+	status, err := LoadStatus(statusFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload status for resume: %w", err)
+	}
+	fi, err := os.Stat(status.FilePath)
+	if err != nil && status.Compression == "" && status.OriginalFilePath != "" {
+		return u.planLegacyArchiveResume(status, statusFilePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", status.FilePath, err)
+	}
+	p := &UploadPlan{
+		Operation:      "resume",
+		FilePath:       status.FilePath,
+		FileSize:       fi.Size(),
+		Bucket:         status.Bucket,
+		Location:       status.Location,
+		Key:            status.Key,
+		UploadID:       status.UploadID,
+		Compress:       status.OriginalFilePath != "",
+		PartSizeBytes:  status.PartSizeBytes,
+		MaxConcurrency: u.Config.MaxConcurrency,
+		BucketCheck:    u.planBucketCheck(status.Bucket),
+	}
+	if p.Compress {
+		p.ContentEncoding = "gzip" // 스트리밍 이전 상태 파일은 항상 gzip
+		if status.Compression != "" {
+			p.ContentEncoding = status.Compression
+			p.CompressLevel = status.CompressionLevel
+		}
+	}
+
+	ru := NewResumeUploader(u.store)
+	server, err := ru.fetchServerCompletedParts(context.Background(), status.Bucket, status.Key, status.UploadID)
+	if err != nil {
+		return nil, fmt.Errorf("list parts: %w", err)
+	}
+	p.Notes = append(p.Notes, hookNotes(u.Config.Hooks)...)
+	if l, _ := ReadLock(statusFilePath); l != nil && !l.Stale() {
+		p.Notes = append(p.Notes, fmt.Sprintf("status file is locked by %s (%s); resume would refuse to run", l.Owner(), l.Command))
+	}
+
+	if status.Compression != "" {
+		p.planStreamedResume(status, server, fi.Size())
+		p.Notes = append(p.Notes, "status file: "+filepath.Clean(statusFilePath))
+		return p, nil
+	}
+
+	p.Parts = planParts(fi.Size(), status.PartSizeBytes)
+	p.TotalParts = len(p.Parts)
+	if p.TotalParts != status.TotalParts {
+		p.Notes = append(p.Notes, fmt.Sprintf("part count mismatch: file gives %d, status file says %d; resume would abort", p.TotalParts, status.TotalParts))
+	}
+	for i := range p.Parts {
+		n := p.Parts[i].PartNumber
+		_, local := status.CompletedParts[n]
+		_, remote := server[n]
+		if local || remote {
+			p.Parts[i].Done = true
+			continue
+		}
+		p.RemainingParts++
+		p.RemainingBytes += p.Parts[i].Size
+	}
+	if len(server) < len(status.CompletedParts) {
+		p.Notes = append(p.Notes, fmt.Sprintf("status file lists %d completed parts but the server has %d", len(status.CompletedParts), len(server)))
+	}
+	p.Notes = append(p.Notes, "status file: "+filepath.Clean(statusFilePath))
+	return p, nil
+}
+
+// planStreamedResume fills the parts of a streamed compressed upload from the
+// source ranges in the status file. Offsets and sizes are in source bytes.
+func (p *UploadPlan) planStreamedResume(status *UploadStatus, server map[int]string, size int64) {
+	var covered int64
+	for _, n := range sortedSegments(status.Segments) {
+		seg := status.Segments[n]
+		pp := PartPlan{PartNumber: n, Offset: seg.Offset, Size: seg.Size}
+		_, local := status.CompletedParts[n]
+		_, remote := server[n]
+		if local || remote {
+			pp.Done = true
+		} else {
+			p.RemainingParts++
+			p.RemainingBytes += seg.Size
+		}
+		p.Parts = append(p.Parts, pp)
+		if seg.End() > covered {
+			covered = seg.End()
+		}
+	}
+	p.TotalParts = status.TotalParts
+	p.Notes = append(p.Notes, "parts are "+status.Compression+" members compressed while uploading; offsets and sizes below are source bytes")
+	if len(p.Parts) > 0 {
+		p.Notes = append(p.Notes, "resume would first check the source sha256 of the parts already uploaded, and of every part it recompresses")
+	}
+	if rest := size - covered; rest > 0 {
+		p.RemainingBytes += rest
+		p.Notes = append(p.Notes, fmt.Sprintf("source bytes %d-%d are not compressed yet; resume would cut them into new parts", covered, size))
+	} else if rest < 0 {
+		p.Notes = append(p.Notes, fmt.Sprintf("file is %d bytes but %d were already compressed; resume would fail", size, covered))
+	}
+}
+
+// planLegacyArchiveResume covers a status file from before streamed
+// compression whose temporary .gz has been removed.
+func (u *Uploader) planLegacyArchiveResume(status *UploadStatus, statusFilePath string) (*UploadPlan, error) {
+	fi, err := os.Stat(status.OriginalFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", status.OriginalFilePath, err)
+	}
+	p := &UploadPlan{
+		Operation:       "resume",
+		FilePath:        status.OriginalFilePath,
+		FileSize:        fi.Size(),
+		Bucket:          status.Bucket,
+		Location:        status.Location,
+		Key:             status.Key,
+		UploadID:        status.UploadID,
+		Compress:        true,
+		ContentEncoding: "gzip",
+		PartSizeBytes:   status.PartSizeBytes,
+		MaxConcurrency:  u.Config.MaxConcurrency,
+		TotalParts:      status.TotalParts,
+		RemainingParts:  status.TotalParts - len(status.CompletedParts),
+		BucketCheck:     u.planBucketCheck(status.Bucket),
+	}
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("compressed archive %s is missing; resume would recreate it from %s and check it against the uploaded part ETags before uploading", status.FilePath, status.OriginalFilePath),
+		"status file: "+filepath.Clean(statusFilePath))
+	return p, nil
+}
+
+// PlanFanOut reports what FanOutUpload(filePath, dests) would do, one plan per
+// destination.
+func (u *Uploader) PlanFanOut(filePath string, dests []Destination) ([]*UploadPlan, error) {
+	plans := make([]*UploadPlan, 0, len(dests))
+	for i, d := range dests {
+		conf := *u.Config
+		conf.Bucket = d.Bucket
+		du := &Uploader{store: d.Store, Config: &conf, Location: d.Location}
+		p, err := du.PlanUpload(filePath, d.Key)
+		if err != nil {
+			return nil, err
+		}
+		p.Operation = "fan-out"
+		p.Duplicate = &DuplicateVerdict{Upload: true, Reason: "not applied to fan-out uploads"}
+		p.Notes = append(p.Notes, fmt.Sprintf("destination %d/%d: %s (parts are read once and shared)", i+1, len(dests), d.URL))
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
@@ -1,14 +1,27 @@
 package uploader
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoCOMA/Favus/internal/chunker"
+	"github.com/GoCOMA/Favus/internal/config"
+	"github.com/GoCOMA/Favus/internal/report"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/tracing"
 	"github.com/GoCOMA/Favus/internal/wsagent"
 	"github.com/GoCOMA/Favus/pkg/utils"
 
@@ -20,28 +33,82 @@ import (
 
 // ResumeUploader allows resuming a multipart upload (AWS SDK v2).
 type ResumeUploader struct {
-	S3Client *s3.Client
+	Store storage.ObjectStore
+	Hooks config.Hooks // run around the resumed session like UploadFile does
+
+	// Report, when set, records per-part timings of the resumed session (--report).
+	Report *report.Recorder
+
+	// BreakLock takes over the status file even when its lock holder still
+	// looks alive (e.g. a crashed process on another host sharing ~/.favus).
+	BreakLock bool
+
+	hr  *hookRun
+	sp  *tracing.Span   // 세션 span (--trace 가 없으면 nil)
+	ctx context.Context // 취소되면 남은 파트를 올리지 않는다 (상태 파일은 그대로)
 }
 
 // NewResumeUploader creates a new ResumeUploader.
-func NewResumeUploader(s3Client *s3.Client) *ResumeUploader {
-	return &ResumeUploader{S3Client: s3Client}
+func NewResumeUploader(store storage.ObjectStore) *ResumeUploader {
+	return &ResumeUploader{Store: store}
 }
 
 // ResumeUpload resumes a multipart upload from a saved status.
 func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
+	return ru.ResumeUploadContext(context.Background(), statusFilePath)
+}
+
+// ResumeUploadContext is ResumeUpload that stops when ctx is canceled; the
+// status file is kept so the upload can be resumed again.
+func (ru *ResumeUploader) ResumeUploadContext(ctx context.Context, statusFilePath string) (err error) {
+	ru.ctx = ctx
 	status, err := LoadStatus(statusFilePath)
 	if err != nil {
 		utils.Error(fmt.Sprintf("Failed to load upload status for resume from %s: %v", statusFilePath, err))
 		return fmt.Errorf("failed to load upload status for resume: %w", err)
 	}
+	// 같은 업로드 ID 를 두 프로세스가 동시에 진행하면 파트가 중복되고 complete 가 충돌한다
+	lock, err := AcquireLock(statusFilePath, status, ru.BreakLock)
+	if err != nil {
+		utils.Error(fmt.Sprintf("Cannot resume %s: %v", statusFilePath, err))
+		return err
+	}
+	defer lock.Release()
+	// 잠그기 전에 읽은 내용은 이전 holder 가 그 뒤에 더 기록했을 수 있다 — 잠근 뒤 다시 읽는다
+	status, err = LoadStatus(statusFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("upload status %s was removed before it could be locked; the upload may have finished", statusFilePath)
+	}
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to load upload status for resume from %s: %v", statusFilePath, err))
+		return fmt.Errorf("failed to load upload status for resume: %w", err)
+	}
+	file := status.FilePath
+	if status.OriginalFilePath != "" {
+		file = status.OriginalFilePath
+	}
+	var size int64
+	if fi, err := os.Stat(file); err == nil {
+		size = fi.Size()
+	}
+	ru.sp = tracing.Start("favus resume", "favus.file", file, "aws.s3.bucket", status.Bucket, "aws.s3.key", status.Key,
+		"aws.s3.upload_id", status.UploadID, "favus.bytes", size, "favus.resumed", true)
+	defer func() { ru.sp.End(err) }()
+	ru.hr = newHookRun(ru.Hooks, status.Bucket, status.Key, file, size, true)
+	defer ru.hr.finish(&err)
+	ru.Report.Session(file, status.Bucket, status.Key, size)
+	if status.Compression != "" {
+		ru.Report.Compression(status.Compression, status.CompressionLevel, "resumed")
+	}
 
 	utils.Info(fmt.Sprintf("Resuming upload for file: %s with UploadID: %s", status.FilePath, status.UploadID))
 
 	// === [추가] 서버 상태와 동기화(ListParts) ===
 	{
-		ctx := context.Background()
-		srvCompleted, err := ru.fetchServerCompletedParts(ctx, status.Bucket, status.Key, status.UploadID)
+		lsp := s3Span(ru.sp, "ListParts", status.Bucket, status.Key, "aws.s3.upload_id", status.UploadID)
+		srvCompleted, err := ru.fetchServerCompletedParts(ru.ctx, status.Bucket, status.Key, status.UploadID)
+		lsp.Set("favus.parts", len(srvCompleted))
+		lsp.End(err)
 		if err != nil {
 			utils.Error(fmt.Sprintf("ListParts failed for %s/%s (UploadID=%s): %v", status.Bucket, status.Key, status.UploadID, err))
 			return fmt.Errorf("list parts: %w", err)
@@ -56,12 +123,26 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 	}
 	// === [추가 끝] ===
 
+	if status.Compression != "" {
+		return ru.resumeStreamed(status, statusFilePath)
+	}
+	if status.OriginalFilePath != "" {
+		// 스트리밍 이전 상태 파일: FilePath 는 ~/.favus/compressed 의 임시 .gz
+		if err := restoreLegacyArchive(status); err != nil {
+			utils.Error(fmt.Sprintf("Cannot resume compressed upload of %s: %v", status.OriginalFilePath, err))
+			return err
+		}
+	}
+
 	fileChunker, err := chunker.NewFileChunker(status.FilePath, status.PartSizeBytes)
 	if err != nil {
 		utils.Error(fmt.Sprintf("Failed to create file chunker for resume for %s: %v", status.FilePath, err))
 		return fmt.Errorf("failed to create file chunker for resume: %w", err)
 	}
+	chsp := ru.sp.Child("chunk file")
 	chunks := fileChunker.Chunks()
+	chsp.Set("favus.parts", len(chunks))
+	chsp.End(nil)
 
 	// 총 파트 개수 일치 여부 확인
 	if len(chunks) != status.TotalParts {
@@ -108,6 +189,10 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 	// === WS Reporter: 세션 시작(Resumed) ===
 	r := newWSReporter(fi.Size())
+	r.trace(ru.sp)
+	r.rec = ru.Report
+	ru.Report.Upload(status.UploadID, r.runID)
+	ru.Report.AlreadyUploaded(len(completedParts))
 	// UI 초기화용 preCompleted 목록 구성(파트/크기/etag)
 	preCompleted := make([]map[string]any, 0, len(completedParts))
 	for _, cp := range completedParts {
@@ -123,13 +208,24 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 			"etag": aws.ToString(cp.ETag),
 		})
 	}
-	r.start(status.Bucket, status.Key, status.UploadID, status.PartSizeBytes, map[string]any{
+	extra := map[string]any{
 		"resumed":       true,
 		"alreadyBytes":  already,
 		"preCompleted":  preCompleted,
 		"totalParts":    status.TotalParts,
 		"partSizeBytes": status.PartSizeBytes,
-	})
+	}
+	if status.FanOutGroup != "" {
+		// fan-out 대상 하나를 재개 → UI 에서 같은 그룹으로 묶이도록
+		extra["fanOut"] = map[string]any{"groupId": status.FanOutGroup}
+	}
+	r.start(status.Bucket, status.Key, status.UploadID, status.PartSizeBytes, extra)
+	// preUpload 훅이 거부하면 상태 파일은 그대로 두고 멈춘다 (나중에 다시 resume)
+	if err := ru.hr.pre(status.Key, status.UploadID, r.runID); err != nil {
+		r.error(err.Error(), nil)
+		r.done(false, status.UploadID)
+		return err
+	}
 	// 진행률 기준을 맞추기 위해 내부 누적값 초기화
 	r.uploadedBytes = already // 같은 패키지이므로 필드 접근 가능
 
@@ -150,6 +246,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 		// WS: 파트 시작
 		r.partStart(ch.Index, ch.Size, ch.Offset)
+		r.rec.PartStart(ch.Index, 1, ch.Size, ch.Size) // resume 은 파트를 하나씩 올린다
 
 		// 파트 진행률 바
 		partBar := progressbar.NewOptions64(
@@ -183,9 +280,15 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 			ch.Index, ch.Offset, ch.Size, status.FilePath))
 
 		var uploadOutput *s3.UploadPartOutput
-		err = utils.Retry(5, 2*time.Second, func() error {
+		attempt := 0
+		err = utils.RetryContext(ru.ctx, 5, 2*time.Second, func() error {
+			if attempt++; attempt > 1 {
+				r.partRetry(ch.Index, attempt)
+			}
 			var partErr error
-			uploadOutput, partErr = ru.S3Client.UploadPart(context.Background(), &s3.UploadPartInput{
+			psp := s3Span(r.span, "UploadPart", status.Bucket, status.Key,
+				"aws.s3.upload_id", status.UploadID, "aws.s3.part_number", ch.Index, "favus.attempt", attempt, "favus.part_bytes", ch.Size)
+			uploadOutput, partErr = ru.Store.UploadPart(ru.ctx, &s3.UploadPartInput{
 				Body:          pr,
 				Bucket:        &status.Bucket,
 				Key:           &status.Key,
@@ -193,6 +296,8 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 				UploadId:      &status.UploadID,
 				ContentLength: aws.Int64(ch.Size),
 			})
+			psp.End(partErr)
+			r.rec.PartAttempt(ch.Index, partErr)
 			if partErr != nil {
 				utils.Error(fmt.Sprintf("Failed to upload part %d for %s: %v", ch.Index, status.FilePath, partErr))
 				return partErr
@@ -203,6 +308,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 		if err != nil {
 			utils.Error(fmt.Sprintf("Failed to upload part %d for %s after retries: %v", ch.Index, status.FilePath, err))
+			r.rec.PartEnd(ch.Index, err)
 			r.error(fmt.Sprintf("upload part %d failed after retries: %v", ch.Index, err), &ch.Index)
 			r.done(false, status.UploadID)
 			return fmt.Errorf("failed to upload part %d after retries: %w", ch.Index, err)
@@ -210,6 +316,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 		if uploadOutput.ETag == nil {
 			utils.Error(fmt.Sprintf("ETag for part %d is nil. Aborting resume.", ch.Index))
+			r.rec.PartEnd(ch.Index, fmt.Errorf("ETag for part %d is nil", ch.Index))
 			r.error(fmt.Sprintf("nil ETag on part %d", ch.Index), &ch.Index)
 			r.done(false, status.UploadID)
 			return fmt.Errorf("ETag for part %d is nil", ch.Index)
@@ -221,6 +328,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 			utils.Error(fmt.Sprintf("Failed to save status after completing part %d for %s: %v", ch.Index, status.FilePath, err))
 		}
 		_ = partBar.Finish()
+		r.rec.PartEnd(ch.Index, nil)
 		utils.Info(fmt.Sprintf("Successfully uploaded part %d. ETag: %s", ch.Index, *uploadOutput.ETag))
 
 		completedParts = append(completedParts, s3types.CompletedPart{
@@ -239,7 +347,9 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 	// Complete the multipart upload
 	utils.Info(fmt.Sprintf("Completing multipart upload for file: %s", status.FilePath))
-	_, err = ru.S3Client.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+	ru.sp.Set("favus.parts", len(completedParts))
+	fsp := s3Span(ru.sp, "CompleteMultipartUpload", status.Bucket, status.Key, "aws.s3.upload_id", status.UploadID, "favus.parts", len(completedParts))
+	completeOut, err := ru.Store.CompleteMultipartUpload(ru.ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:   &status.Bucket,
 		Key:      &status.Key,
 		UploadId: &status.UploadID,
@@ -247,6 +357,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 			Parts: completedParts,
 		},
 	})
+	fsp.End(err)
 	if err != nil {
 		utils.Error(fmt.Sprintf("Failed to complete multipart upload for %s: %v", status.FilePath, err))
 		r.error(fmt.Sprintf("complete multipart: %v", err), nil)
@@ -256,6 +367,7 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 
 	utils.Info(fmt.Sprintf("Multipart upload completed successfully for %s", status.FilePath))
 	r.done(true, status.UploadID)
+	ru.hr.complete(aws.ToString(completeOut.ETag))
 
 	// Clean up status file
 	if err := os.Remove(statusFilePath); err != nil {
@@ -273,6 +385,339 @@ func (ru *ResumeUploader) ResumeUpload(statusFilePath string) error {
 	return nil
 }
 
+// resumeStreamed resumes an upload whose parts were compressed while uploading.
+// Missing parts are recompressed from the source range recorded for them, then
+// the rest of the file is compressed into new parts after the last one.
+func (ru *ResumeUploader) resumeStreamed(status *UploadStatus, statusFilePath string) error {
+	f, err := os.Open(status.FilePath)
+	if err != nil {
+		return fmt.Errorf("open source for compressed resume: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", status.FilePath, err)
+	}
+
+	// 기록된 범위는 0 부터 빈틈없이 이어져야 한다
+	nums := sortedSegments(status.Segments)
+	var covered int64
+	for _, n := range nums {
+		seg := status.Segments[n]
+		if seg.Offset != covered {
+			return fmt.Errorf("status file segments are not contiguous at part %d (offset %d, expected %d)", n, seg.Offset, covered)
+		}
+		covered = seg.End()
+	}
+	if covered > fi.Size() {
+		return fmt.Errorf("%s is %d bytes but %d bytes were already compressed: %w", status.FilePath, fi.Size(), covered, ErrSourceChanged)
+	}
+
+	cd, err := newCodec(status.Compression, status.CompressionLevel)
+	if err != nil {
+		return err
+	}
+	stream := newPartStream(cd, f, fi.Size(), status.PartSizeBytes, runtime.NumCPU(), filepath.Base(status.FilePath), fi.ModTime())
+	defer stream.Close()
+	if status.CompressionBlock > 0 {
+		stream.blockSize = status.CompressionBlock // 같은 블록 경계로 다시 만든다
+	}
+
+	// 이미 올라간 파트의 원본이 그대로인지 확인 (바뀌었으면 객체에 옛/새 내용이 섞인다)
+	var verified int
+	for _, n := range nums {
+		if !status.IsPartCompleted(n) {
+			continue
+		}
+		if err := stream.VerifySource(n, status.Segments[n]); err != nil {
+			return fmt.Errorf("%w; abort this upload and upload the file again", err)
+		}
+		verified++
+	}
+	utils.Info(fmt.Sprintf("Verified source of %d uploaded parts of %s", verified, status.FilePath))
+
+	completedParts := make([]s3types.CompletedPart, 0, len(nums))
+	preCompleted := make([]map[string]any, 0, len(nums))
+	var already int64
+	for _, n := range nums {
+		if etag, ok := status.CompletedParts[n]; ok {
+			completedParts = append(completedParts, s3types.CompletedPart{PartNumber: aws.Int32(int32(n)), ETag: aws.String(etag)})
+			preCompleted = append(preCompleted, map[string]any{"part": n, "size": status.Segments[n].Size, "etag": etag})
+			already += status.Segments[n].Size
+		}
+	}
+
+	// 진행률은 원본 바이트 기준
+	totalBar := progressbar.NewOptions64(
+		fi.Size(),
+		progressbar.OptionSetDescription("total"),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetWidth(30),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionSetWriter(os.Stdout),
+	)
+	_ = totalBar.Add64(already)
+
+	r := newWSReporter(fi.Size())
+	r.trace(ru.sp)
+	r.rec = ru.Report
+	ru.Report.Upload(status.UploadID, r.runID)
+	ru.Report.AlreadyUploaded(len(completedParts))
+	extra := map[string]any{
+		"resumed":       true,
+		"alreadyBytes":  already,
+		"preCompleted":  preCompleted,
+		"totalParts":    status.TotalParts,
+		"partSizeBytes": status.PartSizeBytes,
+		"compressed":    true,
+		"compression":   status.Compression,
+		"streamed":      true,
+		"originalBytes": fi.Size(),
+		"originalName":  filepath.Base(status.FilePath),
+	}
+	if status.FanOutGroup != "" {
+		extra["fanOut"] = map[string]any{"groupId": status.FanOutGroup}
+	}
+	r.start(status.Bucket, status.Key, status.UploadID, status.PartSizeBytes, extra)
+	// preUpload 훅이 거부하면 상태 파일은 그대로 두고 멈춘다 (나중에 다시 resume)
+	if err := ru.hr.pre(status.Key, status.UploadID, r.runID); err != nil {
+		r.error(err.Error(), nil)
+		r.done(false, status.UploadID)
+		return err
+	}
+	r.uploadedBytes = already
+
+	upload := func(n int, seg Segment, data []byte) error {
+		r.partStart(n, int64(len(data)), seg.Offset)
+		r.rec.PartStart(n, 1, int64(len(data)), seg.Size)
+		utils.Info(fmt.Sprintf("Uploading compressed part %d (source bytes %d-%d, %d bytes) for file %s",
+			n, seg.Offset, seg.End(), len(data), status.FilePath))
+
+		var out *s3.UploadPartOutput
+		attempt := 0
+		err := utils.RetryContext(ru.ctx, 5, 2*time.Second, func() error {
+			if attempt++; attempt > 1 {
+				r.partRetry(n, attempt)
+			}
+			pr := NewReadSeekCloserProgress(readSeekNopCloser{bytes.NewReader(data)}, func(d int64) {
+				src := d * seg.Size / int64(len(data))
+				_ = totalBar.Add64(src)
+				r.progressAdd(src)
+				r.partProgressAdd(n, d)
+			})
+			var partErr error
+			psp := s3Span(r.span, "UploadPart", status.Bucket, status.Key,
+				"aws.s3.upload_id", status.UploadID, "aws.s3.part_number", n, "favus.attempt", attempt, "favus.part_bytes", int64(len(data)))
+			out, partErr = ru.Store.UploadPart(ru.ctx, &s3.UploadPartInput{
+				Body:          pr,
+				Bucket:        &status.Bucket,
+				Key:           &status.Key,
+				PartNumber:    aws.Int32(int32(n)),
+				UploadId:      &status.UploadID,
+				ContentLength: aws.Int64(int64(len(data))),
+			})
+			psp.End(partErr)
+			r.rec.PartAttempt(n, partErr)
+			if partErr != nil {
+				utils.Error(fmt.Sprintf("Failed to upload part %d for %s: %v", n, status.FilePath, partErr))
+			}
+			return partErr
+		})
+		if err == nil && out.ETag == nil {
+			err = fmt.Errorf("ETag for part %d is nil", n)
+		}
+		r.rec.PartEnd(n, err)
+		if err != nil {
+			r.error(fmt.Sprintf("upload part %d failed: %v", n, err), &n)
+			r.done(false, status.UploadID)
+			return fmt.Errorf("failed to upload part %d after retries: %w", n, err)
+		}
+
+		status.AddCompletedPart(n, *out.ETag)
+		if err := status.SaveStatus(statusFilePath); err != nil {
+			utils.Error(fmt.Sprintf("Failed to save status after completing part %d for %s: %v", n, status.FilePath, err))
+		}
+		completedParts = append(completedParts, s3types.CompletedPart{PartNumber: aws.Int32(int32(n)), ETag: out.ETag})
+		utils.Info(fmt.Sprintf("Successfully uploaded part %d. ETag: %s", n, *out.ETag))
+		r.partDone(n, int64(len(data)), *out.ETag)
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	// 1) 범위가 기록됐지만 서버에 없는 파트 → 같은 범위로 다시 압축
+	for _, n := range nums {
+		if status.IsPartCompleted(n) {
+			utils.Info(fmt.Sprintf("Part %d already completed, skipping.", n))
+			continue
+		}
+		seg := status.Segments[n]
+		psp := ru.sp.Child("compress part", "favus.part", n, "favus.source_bytes", seg.Size, "favus.recompressed", true)
+		err := stream.Recompress(&buf, n, seg)
+		psp.Set("favus.compressed_bytes", int64(buf.Len()))
+		psp.End(err)
+		if err != nil {
+			r.error(err.Error(), &n)
+			r.done(false, status.UploadID)
+			return fmt.Errorf("recompress part %d: %w", n, err)
+		}
+		if err := upload(n, seg, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	// 2) 아직 압축하지 않은 나머지 → 새 파트
+	stream.resumeAfter(status.Segments)
+	for {
+		psp := ru.sp.Child("compress part")
+		n, seg, err := stream.Next(&buf)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		psp.Set("favus.part", n, "favus.source_bytes", seg.Size, "favus.compressed_bytes", int64(buf.Len()))
+		psp.End(err)
+		if err != nil {
+			r.error(err.Error(), nil)
+			r.done(false, status.UploadID)
+			return fmt.Errorf("compress part %d: %w", stream.part, err)
+		}
+		status.SetSegment(n, seg)
+		if err := status.SaveStatus(statusFilePath); err != nil {
+			utils.Error(fmt.Sprintf("Failed to save status for part %d: %v", n, err))
+		}
+		if err := upload(n, seg, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	status.TotalParts = len(status.Segments)
+
+	sort.Slice(completedParts, func(i, j int) bool {
+		return aws.ToInt32(completedParts[i].PartNumber) < aws.ToInt32(completedParts[j].PartNumber)
+	})
+	utils.Info(fmt.Sprintf("Completing multipart upload for file: %s", status.FilePath))
+	ru.sp.Set("favus.parts", len(completedParts), "favus.compression", status.Compression)
+	fsp := s3Span(ru.sp, "CompleteMultipartUpload", status.Bucket, status.Key, "aws.s3.upload_id", status.UploadID, "favus.parts", len(completedParts))
+	completeOut, err := ru.Store.CompleteMultipartUpload(ru.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &status.Bucket,
+		Key:             &status.Key,
+		UploadId:        &status.UploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	fsp.End(err)
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to complete multipart upload for %s: %v", status.FilePath, err))
+		r.error(fmt.Sprintf("complete multipart: %v", err), nil)
+		r.done(false, status.UploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	utils.Info(fmt.Sprintf("Multipart upload completed successfully for %s", status.FilePath))
+	r.done(true, status.UploadID)
+	ru.hr.complete(aws.ToString(completeOut.ETag))
+	if err := os.Remove(statusFilePath); err != nil {
+		utils.Error(fmt.Sprintf("Failed to remove status file %s: %v", statusFilePath, err))
+	}
+	return nil
+}
+
+// restoreLegacyArchive makes sure the temporary .gz of a status file written
+// before compression was streamed is usable. A missing archive is recreated
+// from OriginalFilePath with the settings the old code used (default level,
+// original name and mtime in the header), which is byte-for-byte reproducible
+// while the source is unchanged. Either way the archive is checked against the
+// ETags of the parts already uploaded before anything else is sent.
+func restoreLegacyArchive(status *UploadStatus) error {
+	path := status.FilePath
+	if _, err := os.Stat(path); err == nil {
+		if err := verifyLegacyArchive(path, status); err != nil {
+			return fmt.Errorf("compressed archive %s: %w", path, err)
+		}
+		return nil
+	}
+
+	utils.Info(fmt.Sprintf("Compressed archive %s is gone; recreating it from %s", path, status.OriginalFilePath))
+	src, err := os.Open(status.OriginalFilePath)
+	if err != nil {
+		return fmt.Errorf("recreate compressed archive: %w", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", status.OriginalFilePath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create compressed directory: %w", err)
+	}
+	tmp := path + ".partial"
+	dest, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create compressed file: %w", err)
+	}
+	gw := gzip.NewWriter(dest)
+	gw.Name = filepath.Base(status.OriginalFilePath)
+	gw.ModTime = info.ModTime()
+	_, err = io.Copy(gw, src)
+	if cerr := gw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dest.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = verifyLegacyArchive(tmp, status)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("recreate compressed archive: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// verifyLegacyArchive compares the chunks of path with the ETags of completed
+// parts. S3 part ETags are the MD5 of the part unless SSE-KMS is used; when no
+// ETag can be checked that way the archive cannot be trusted.
+func verifyLegacyArchive(path string, status *UploadStatus) error {
+	fc, err := chunker.NewFileChunker(path, status.PartSizeBytes)
+	if err != nil {
+		return err
+	}
+	chunks := fc.Chunks()
+	if len(chunks) != status.TotalParts {
+		return fmt.Errorf("it has %d parts, the upload has %d: %w", len(chunks), status.TotalParts, ErrSourceChanged)
+	}
+	var checked, unverifiable int
+	for _, ch := range chunks {
+		etag, ok := status.CompletedParts[ch.Index]
+		if !ok {
+			continue
+		}
+		want := strings.Trim(etag, `"`)
+		if len(want) != md5.Size*2 {
+			unverifiable++
+			continue
+		}
+		rc, err := fc.GetChunkReader(ch)
+		if err != nil {
+			return err
+		}
+		h := md5.New()
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("read part %d: %w", ch.Index, err)
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			return fmt.Errorf("part %d has MD5 %s but ETag %s was uploaded: %w", ch.Index, got, want, ErrSourceChanged)
+		}
+		checked++
+	}
+	if unverifiable > 0 && checked == 0 {
+		return fmt.Errorf("none of the %d uploaded part ETags is a plain MD5, so the archive cannot be verified", unverifiable)
+	}
+	utils.Info(fmt.Sprintf("Verified %d uploaded parts against %s", checked, path))
+	return nil
+}
+
 // fetchServerCompletedParts lists completed parts on S3 and returns a map[partNumber]ETag.
 // It handles pagination via PartNumberMarker/NextPartNumberMarker.
 func (ru *ResumeUploader) fetchServerCompletedParts(
@@ -293,7 +738,7 @@ func (ru *ResumeUploader) fetchServerCompletedParts(
 			in.PartNumberMarker = partMarkerStr
 		}
 
-		out, err := ru.S3Client.ListParts(ctx, in)
+		out, err := ru.Store.ListParts(ctx, in)
 		if err != nil {
 			return nil, err
 		}
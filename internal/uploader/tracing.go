@@ -0,0 +1,23 @@
+package uploader
+
+import "github.com/GoCOMA/Favus/internal/tracing"
+
+// trace ties the session span to this reporter: the span gets the run ID, and
+// part uploads (which only see the reporter) can add child spans.
+func (r *wsReporter) trace(sp *tracing.Span) {
+	r.span = sp
+	sp.Set("favus.run_id", r.runID)
+}
+
+// s3Span starts a CLIENT span for one S3 API call, named like the AWS SDK
+// instrumentation does ("S3.UploadPart").
+func s3Span(parent *tracing.Span, op, bucket, key string, kv ...any) *tracing.Span {
+	attrs := append([]any{
+		"rpc.system", "aws-api",
+		"rpc.service", "S3",
+		"rpc.method", op,
+		"aws.s3.bucket", bucket,
+		"aws.s3.key", key,
+	}, kv...)
+	return parent.Client("S3."+op, attrs...)
+}
@@ -1,7 +1,6 @@
 package uploader
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
@@ -9,15 +8,26 @@ import (
 
 // UploadStatus represents the status of a multipart upload.
 type UploadStatus struct {
-	FilePath         string         `json:"filePath"`
-	OriginalFilePath string         `json:"originalFilePath,omitempty"`
-	UploadID         string         `json:"uploadId"`
-	Bucket           string         `json:"bucket"`
-	Key              string         `json:"key"`
-	CompletedParts   map[int]string `json:"completedParts"`
-	TotalParts       int            `json:"totalParts"`
-	PartSizeBytes    int64          `json:"partSizeBytes"`
-	Mu               sync.Mutex     `json:"-"`
+	FilePath         string          `json:"filePath"`
+	OriginalFilePath string          `json:"originalFilePath,omitempty"`
+	FileSize         int64           `json:"fileSize,omitempty"` // browser uploads: the file is not on this machine
+	UploadID         string          `json:"uploadId"`
+	Bucket           string          `json:"bucket"`
+	Key              string          `json:"key"`
+	CompletedParts   map[int]string  `json:"completedParts"`
+	TotalParts       int             `json:"totalParts"`
+	PartSizeBytes    int64           `json:"partSizeBytes"`
+	Location         string          `json:"location,omitempty"`    // non-S3 backend, e.g. file:///srv/backups
+	Region           string          `json:"region,omitempty"`      // fan-out destination outside the default region
+	FanOutGroup      string          `json:"fanOutGroup,omitempty"` // shared by every destination of one fan-out upload
+	Compression      string          `json:"compression,omitempty"` // gzip | zstd: parts are members compressed from FilePath while uploading
+	CompressionLevel int             `json:"compressionLevel,omitempty"`
+	CompressionBlock int64           `json:"compressionBlockSize,omitempty"` // source bytes per member/frame
+	Segments         map[int]Segment `json:"segments,omitempty"`             // streamed compression: source range of each part cut so far
+	Mu               sync.Mutex      `json:"-"`
+
+	journal *statusJournal // on disk as of the last save (journal.go)
+	pending []journalEntry // recorded since the last save
 }
 
 // NewUploadStatus creates a new UploadStatus.
@@ -37,7 +47,22 @@ func NewUploadStatus(filePath, bucket, key, uploadID string, totalParts int, par
 func (us *UploadStatus) AddCompletedPart(partNumber int, eTag string) {
 	us.Mu.Lock()
 	defer us.Mu.Unlock()
+	if old, ok := us.CompletedParts[partNumber]; ok && old == eTag {
+		return
+	}
 	us.CompletedParts[partNumber] = eTag
+	us.pending = append(us.pending, journalEntry{Part: partNumber, ETag: eTag})
+}
+
+// SetSegment records the source range of a streamed compressed part.
+func (us *UploadStatus) SetSegment(partNumber int, seg Segment) {
+	us.Mu.Lock()
+	defer us.Mu.Unlock()
+	if us.Segments == nil {
+		us.Segments = make(map[int]Segment)
+	}
+	us.Segments[partNumber] = seg
+	us.pending = append(us.pending, journalEntry{Part: partNumber, Segment: &seg})
 }
 
 // IsPartCompleted checks if a part has been completed.
@@ -48,16 +73,13 @@ func (us *UploadStatus) IsPartCompleted(partNumber int) bool {
 	return exists
 }
 
-// SaveStatus saves the current upload status to a file.
+// SaveStatus persists the status to statusFilePath: it appends the parts
+// recorded since the last save to the journal and fsyncs, or compacts the
+// file when needed (see journal.go). Safe for concurrent use.
 func (us *UploadStatus) SaveStatus(statusFilePath string) error {
 	us.Mu.Lock()
 	defer us.Mu.Unlock()
-
-	data, err := json.MarshalIndent(us, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal upload status: %w", err)
-	}
-	return os.WriteFile(statusFilePath, data, 0644)
+	return us.saveLocked(statusFilePath)
 }
 
 // LoadStatus loads an upload status from a file.
@@ -66,10 +88,5 @@ func LoadStatus(statusFilePath string) (*UploadStatus, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read upload status file: %w", err)
 	}
-	var us UploadStatus
-	if err := json.Unmarshal(data, &us); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal upload status: %w", err)
-	}
-	us.Mu = sync.Mutex{} // Initialize mutex after unmarshaling
-	return &us, nil
+	return parseStatus(statusFilePath, data)
 }
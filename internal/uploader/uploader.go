@@ -1,19 +1,25 @@
 package uploader
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoCOMA/Favus/internal/chunker"
 	"github.com/GoCOMA/Favus/internal/config"
 	"github.com/GoCOMA/Favus/internal/duplicate"
+	"github.com/GoCOMA/Favus/internal/faults"
+	"github.com/GoCOMA/Favus/internal/report"
+	"github.com/GoCOMA/Favus/internal/scheduler"
+	"github.com/GoCOMA/Favus/internal/storage"
+	"github.com/GoCOMA/Favus/internal/tracing"
 	"github.com/GoCOMA/Favus/pkg/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -21,25 +27,79 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
 	"github.com/schollz/progressbar/v3"
 )
 
-// Uploader manages file uploads, deletions, and multipart upload operations for S3.
+// Uploader manages file uploads, deletions, and multipart upload operations for S3
+// (or any other storage.ObjectStore backend).
 type Uploader struct {
-	s3Client         *s3.Client
+	store            storage.ObjectStore
 	Config           *config.Config
 	duplicateChecker *duplicate.DuplicateChecker
+
+	// Location is recorded in status files when the store is not S3
+	// (e.g. "file:///srv/backups"), so resume can reopen the same backend.
+	Location string
+
+	// RunID, when set, is used as the run ID of the next upload instead of a
+	// fresh one, so a {runId} key template matches what the UI shows.
+	RunID string
+
+	// Report, when set, records per-part timings of the next upload (--report).
+	Report *report.Recorder
+
+	// Scheduler, when set, is shared with the other uploads of this process:
+	// every part waits for one of its slots, so MaxConcurrency still caps this
+	// file's workers but the scheduler caps parts across all files.
+	Scheduler *scheduler.Scheduler
+
+	// BreakLock makes ResumeUpload take over a status file locked by a
+	// process that still looks alive (resume --break-lock).
+	BreakLock bool
+
+	// OnStart, when set, is called once the multipart upload exists and its
+	// status file is saved, so a caller can resume it after an interruption.
+	OnStart func(uploadID, statusFilePath string)
+}
+
+// Store returns the backend the uploader talks to.
+func (u *Uploader) Store() storage.ObjectStore { return u.store }
+
+// LocationURL is the bucket in URL form: Location for local backends,
+// otherwise s3://<bucket>.
+func (u *Uploader) LocationURL() string { return locationURL(u.Location, u.Config.Bucket) }
+
+func locationURL(location, bucket string) string {
+	if location != "" {
+		return location
+	}
+	return "s3://" + bucket
 }
 
 // ResumeUpload proxies to ResumeUploader so main can call on *Uploader.
 func (u *Uploader) ResumeUpload(statusFilePath string) error {
-	ru := NewResumeUploader(u.s3Client)
-	return ru.ResumeUpload(statusFilePath)
+	return u.ResumeUploadContext(context.Background(), statusFilePath)
+}
+
+// ResumeUploadContext is ResumeUpload that stops when ctx is canceled,
+// leaving the status file for the next resume.
+func (u *Uploader) ResumeUploadContext(ctx context.Context, statusFilePath string) error {
+	ru := NewResumeUploader(u.store)
+	ru.Hooks = u.Config.Hooks
+	ru.Report = u.Report
+	ru.BreakLock = u.BreakLock
+	return ru.ResumeUploadContext(ctx, statusFilePath)
 }
 
 // checkBucket verifies that the bucket exists and that the caller has permissions.
 func (u *Uploader) checkBucket(bucket string) error {
-	_, err := u.s3Client.HeadBucket(context.Background(), &s3.HeadBucketInput{
+	return u.checkBucketIn(u.store, bucket)
+}
+
+// checkBucketIn is checkBucket against another store (fan-out destinations).
+func (u *Uploader) checkBucketIn(store storage.ObjectStore, bucket string) error {
+	_, err := store.HeadBucket(context.Background(), &s3.HeadBucketInput{
 		Bucket: &bucket,
 	})
 	if err != nil {
@@ -91,41 +151,74 @@ func NewUploader(cfgApp *config.Config) (*Uploader, error) {
 		if endpoint != "" {
 			o.UsePathStyle = true // For LocalStack or custom S3-compatible endpoints
 		}
-	})
+	}, faults.S3Options)
 
-	// Create duplicate checker (unless explicitly disabled)
-	var duplicateChecker *duplicate.DuplicateChecker
-	if os.Getenv("DISABLE_DUPLICATE_CHECK") != "true" {
-		duplicateChecker, err = duplicate.NewDuplicateChecker(cfgApp)
-		if err != nil {
-			utils.Error(fmt.Sprintf("Failed to create duplicate checker: %v", err))
-			// Continue without duplicate checking if Redis is not available
-			duplicateChecker = nil
-		}
-	} else {
-		utils.Info("Duplicate checking is disabled via DISABLE_DUPLICATE_CHECK env var")
-		duplicateChecker = nil
-	}
+	return NewUploaderWithStore(cfgApp, &storage.S3Store{Client: cli}), nil
+}
 
+// NewUploaderWithStore builds an Uploader on top of an arbitrary ObjectStore
+// (S3, in-memory or a local directory).
+func NewUploaderWithStore(cfgApp *config.Config, store storage.ObjectStore) *Uploader {
 	return &Uploader{
-		s3Client:         cli,
+		store:            store,
 		Config:           cfgApp,
-		duplicateChecker: duplicateChecker,
-	}, nil
+		duplicateChecker: newDuplicateChecker(cfgApp, store),
+	}
+}
+
+// newDuplicateChecker creates the duplicate checker unless it is disabled or Redis is unavailable.
+func newDuplicateChecker(cfgApp *config.Config, store storage.ObjectStore) *duplicate.DuplicateChecker {
+	if os.Getenv("DISABLE_DUPLICATE_CHECK") == "true" {
+		utils.Info("Duplicate checking is disabled via DISABLE_DUPLICATE_CHECK env var")
+		return nil
+	}
+	dc, err := duplicate.NewDuplicateCheckerWithStore(store)
+	if err != nil {
+		utils.Error(fmt.Sprintf("Failed to create duplicate checker: %v", err))
+		// Continue without duplicate checking if Redis is not available
+		return nil
+	}
+	return dc
 }
 
 // UploadFile performs a multipart upload of a local file to S3.
 func (u *Uploader) UploadFile(filePath, s3Key string) error {
+	return u.UploadFileContext(context.Background(), filePath, s3Key)
+}
+
+// UploadFileContext is UploadFile that stops when ctx is canceled. An
+// interrupted upload is not aborted: its status file is kept for resume.
+func (u *Uploader) UploadFileContext(ctx context.Context, filePath, s3Key string) (err error) {
 	utils.Info(fmt.Sprintf("Starting multipart upload for file: %s to s3://%s/%s", filePath, u.Config.Bucket, s3Key))
+	sp := tracing.Start("favus upload", "favus.file", filePath, "aws.s3.bucket", u.Config.Bucket, "aws.s3.key", s3Key)
+	defer func() { sp.End(err) }() // 훅까지 포함하도록 가장 먼저 defer
+	hr := newHookRun(u.Config.Hooks, u.Config.Bucket, s3Key, filePath, 0, false)
+	defer hr.finish(&err)
+	u.Report.Session(filePath, u.Config.Bucket, s3Key, 0)
+
+	// preUpload 는 원본을 만들거나 바꿀 수 있다 → 해시, stat, 파트 계획, CreateMultipartUpload 보다 먼저
+	runID := u.RunID
+	if runID == "" {
+		runID = uuid.NewString()
+	}
+	if err := hr.pre(s3Key, "", runID); err != nil {
+		return err
+	}
 
 	// Check for duplicates if duplicate checker is available
 	if u.duplicateChecker != nil {
-		shouldUpload, reason, err := u.duplicateChecker.CheckDuplicate(context.Background(), filePath, u.Config)
+		dsp := sp.Child("duplicate check")
+		shouldUpload, reason, err := u.duplicateChecker.CheckDuplicate(ctx, filePath, u.Config)
+		dsp.Set("favus.duplicate.upload", shouldUpload, "favus.duplicate.reason", reason)
+		dsp.End(err)
+		u.Report.Duplicate(shouldUpload, reason, err)
 		if err != nil {
 			utils.Error(fmt.Sprintf("Duplicate check failed: %v", err))
 			// Continue with upload if duplicate check fails
 		} else if !shouldUpload {
 			utils.Info(fmt.Sprintf("Skipping upload for file %s: %s", filePath, reason))
+			sp.Set("favus.skipped", reason)
+			u.Report.Skip(reason)
 			return nil
 		}
 		utils.Info(fmt.Sprintf("Duplicate check passed for file %s: %s", filePath, reason))
@@ -144,73 +237,92 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 	}
 	if originalInfo.Size() == 0 {
 		utils.Info(fmt.Sprintf("File %s is empty, skipping upload", filePath))
+		u.Report.Skip("empty file")
 		return nil
 	}
+	hr.session.Bytes = originalInfo.Size()
+	u.Report.Session("", "", "", originalInfo.Size())
 
-	uploadPath := filePath
-	uploadInfo := originalInfo
-	removeCompressed := false
-	var metadata map[string]string
-	var extra map[string]any
-
+	// 압축 여부/코덱 결정 (이미 압축된 입력이면 cd == nil → 원본 그대로)
+	var csp *tracing.Span
 	if u.Config.Compress {
-		utils.Info("Compression enabled; creating gzip archive before upload.")
-		var err error
-		uploadPath, err = compressToTempGzip(filePath)
-		if err != nil {
-			return fmt.Errorf("compress file: %w", err)
-		}
-		removeCompressed = true
-
-		uploadInfo, err = os.Stat(uploadPath)
-		if err != nil {
-			return fmt.Errorf("stat compressed file: %w", err)
-		}
+		csp = sp.Child("compression decision")
+	}
+	cd, decision, err := u.compression(filePath)
+	if decision != nil {
+		csp.Set("favus.compress", decision.Compress, "favus.compress.reason", decision.Reason)
+	}
+	csp.End(err)
+	if err != nil {
+		return fmt.Errorf("compression: %w", err)
+	}
+	sp.Set("favus.bytes", originalInfo.Size())
+	if cd != nil {
+		sp.Set("favus.compression", cd.Name)
+		u.Report.Compression(cd.Name, cd.Level, decision.Reason)
+	} else if decision != nil {
+		u.Report.Compression("", 0, decision.Reason)
+	}
+	metadata := compressionMetadata(cd, decision, filePath, originalInfo.Size())
+	var extra map[string]any
 
-		if !strings.HasSuffix(strings.ToLower(s3Key), ".gz") {
-			s3Key = s3Key + ".gz"
-			utils.Info(fmt.Sprintf("Object key updated to include .gz suffix: %s", s3Key))
+	if cd != nil {
+		// 임시 사본 없이 파트 버퍼에 바로 압축 (compress.go)
+		utils.Info(fmt.Sprintf("Compression enabled (%s); compressing parts while uploading.", cd.Name))
+		if k := cd.key(s3Key); k != s3Key {
+			s3Key = k
+			utils.Info(fmt.Sprintf("Object key updated to include %s suffix: %s", cd.Ext, s3Key))
 		}
 		u.Config.Key = s3Key
 
-		metadata = map[string]string{
-			"favus-original-name": filepath.Base(filePath),
-			"favus-original-size": strconv.FormatInt(originalInfo.Size(), 10),
-		}
 		extra = map[string]any{
-			"compressed":      true,
-			"originalBytes":   originalInfo.Size(),
-			"originalName":    filepath.Base(filePath),
-			"compressedBytes": uploadInfo.Size(),
+			"compressed":    true,
+			"compression":   cd.Name,
+			"streamed":      true, // 압축 크기는 끝나야 알 수 있음 → 진행률은 원본 바이트 기준
+			"originalBytes": originalInfo.Size(),
+			"originalName":  filepath.Base(filePath),
 		}
-		utils.Info(fmt.Sprintf("Compression result: %d bytes → %d bytes", originalInfo.Size(), uploadInfo.Size()))
+	} else if decision != nil {
+		extra = map[string]any{"compressionSkipped": decision.Reason}
 	}
 
-	success := false
-	defer func() {
-		if success && removeCompressed {
-			if err := os.Remove(uploadPath); err != nil {
-				utils.Error(fmt.Sprintf("Failed to remove temporary compressed file %s: %v", uploadPath, err))
-			} else {
-				utils.Info(fmt.Sprintf("Removed temporary compressed file: %s", uploadPath))
-			}
-		}
-	}()
-
 	// WS reporter (에이전트가 떠있을 때만 실제로 전송)
-	r := newWSReporter(uploadInfo.Size())
+	r := u.newReporter(originalInfo.Size())
+	r.runID = runID
+	r.trace(sp)
+	r.rec = u.Report
 
-	fileChunker, err := chunker.NewFileChunker(uploadPath, u.Config.PartSizeBytes())
-	if err != nil {
-		utils.Error(fmt.Sprintf("Failed to create file chunker for %s: %v", uploadPath, err))
-		r.error(fmt.Sprintf("create chunker: %v", err), nil)
-		return fmt.Errorf("failed to create file chunker: %w", err)
+	var (
+		fileChunker *chunker.FileChunker
+		chunks      []chunker.Chunk
+		stream      *partStream
+	)
+	if cd != nil {
+		src, err := os.Open(filePath)
+		if err != nil {
+			r.error(fmt.Sprintf("open source: %v", err), nil)
+			return fmt.Errorf("open %s: %w", filePath, err)
+		}
+		defer src.Close()
+		// 블록 압축은 업로드 동시성만큼의 워커가 나눠 맡는다
+		stream = newPartStream(cd, src, originalInfo.Size(), u.Config.PartSizeBytes(), u.Config.MaxConcurrency, filepath.Base(filePath), originalInfo.ModTime())
+		defer stream.Close()
+	} else {
+		fileChunker, err = chunker.NewFileChunker(filePath, u.Config.PartSizeBytes())
+		if err != nil {
+			utils.Error(fmt.Sprintf("Failed to create file chunker for %s: %v", filePath, err))
+			r.error(fmt.Sprintf("create chunker: %v", err), nil)
+			return fmt.Errorf("failed to create file chunker: %w", err)
+		}
+		chsp := sp.Child("chunk file")
+		chunks = fileChunker.Chunks()
+		chsp.Set("favus.parts", len(chunks))
+		chsp.End(nil)
 	}
-	chunks := fileChunker.Chunks()
 
 	// Progress bars: total + per-part
 	totalBar := progressbar.NewOptions64(
-		uploadInfo.Size(),
+		originalInfo.Size(),
 		progressbar.OptionSetDescription("total"),
 		progressbar.OptionShowBytes(true),
 		progressbar.OptionSetWidth(30),
@@ -224,13 +336,18 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 		Bucket: &u.Config.Bucket,
 		Key:    &s3Key,
 	}
-	if u.Config.Compress {
-		initInput.ContentEncoding = aws.String("gzip")
-		if len(metadata) > 0 {
-			initInput.Metadata = metadata
-		}
+	if cd != nil {
+		initInput.ContentEncoding = aws.String(cd.Name)
 	}
-	initiateOutput, err := u.s3Client.CreateMultipartUpload(context.Background(), initInput)
+	if len(metadata) > 0 {
+		initInput.Metadata = metadata
+	}
+	isp := s3Span(sp, "CreateMultipartUpload", u.Config.Bucket, s3Key)
+	initiateOutput, err := u.store.CreateMultipartUpload(ctx, initInput)
+	if err == nil {
+		isp.Set("aws.s3.upload_id", aws.ToString(initiateOutput.UploadId))
+	}
+	isp.End(err)
 	if err != nil {
 		utils.Error(fmt.Sprintf("Failed to initiate multipart upload for %s: %v", s3Key, err))
 		r.error(fmt.Sprintf("initiate multipart: %v", err), nil)
@@ -238,118 +355,207 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 	}
 	uploadID := aws.ToString(initiateOutput.UploadId)
 	utils.Info(fmt.Sprintf("Initiated multipart upload with UploadID: %s", uploadID))
+	sp.Set("aws.s3.upload_id", uploadID)
+	u.Report.Session("", "", s3Key, 0) // 압축 확장자가 붙었을 수 있다
+	u.Report.Upload(uploadID, r.runID)
 
-	// WS: 세션 시작
 	r.start(u.Config.Bucket, s3Key, uploadID, u.Config.PartSizeBytes(), extra)
+	hr.created(s3Key, uploadID)
 
 	// Prepare status tracker
-	home, _ := os.UserHomeDir()
-	statusDir := filepath.Join(home, ".favus", "status")
+	statusDir := StatusDir()
 	os.MkdirAll(statusDir, 0755)
-	statusFilePath := filepath.Join(statusDir, fmt.Sprintf("%s_%s.upload_status", filepath.Base(uploadPath), uploadID[:8]))
+	statusFilePath := filepath.Join(statusDir, fmt.Sprintf("%s_%s.upload_status", filepath.Base(filePath), uploadID[:8]))
 	utils.Info(fmt.Sprintf("Status file will be saved to: %s", statusFilePath))
 	status := NewWSTracker(
-		NewUploadStatus(uploadPath, u.Config.Bucket, s3Key, uploadID, len(chunks), u.Config.PartSizeBytes()),
+		NewUploadStatus(filePath, u.Config.Bucket, s3Key, uploadID, len(chunks), u.Config.PartSizeBytes()),
 	)
-	if u.Config.Compress {
+	if cd != nil {
+		// TotalParts 는 압축이 끝나야 확정된다 (그 전까지 0)
 		status.UploadStatus.OriginalFilePath = filePath
+		status.UploadStatus.Compression = cd.Name
+		status.UploadStatus.CompressionLevel = cd.Level
+		status.UploadStatus.CompressionBlock = compressBlockSize
+		status.UploadStatus.Segments = make(map[int]Segment)
+	}
+	status.UploadStatus.Location = u.Location
+	// 상태 파일을 먼저 써야 잠금만 남는 일이 없다 (잠그기 전에 죽으면 resume 으로 이어간다)
+	if err := status.SaveStatus(statusFilePath); err != nil {
+		utils.Error(fmt.Sprintf("Failed to save status file %s: %v", statusFilePath, err))
+	}
+	// 새 업로드 ID 라 충돌할 일은 없다 — 다른 resume/kill-orphans 가 건드리지 않도록 잡아 둔다
+	if lock, err := AcquireLock(statusFilePath, status.UploadStatus, false); err != nil {
+		utils.Error(fmt.Sprintf("Failed to lock status file %s: %v", statusFilePath, err))
+	} else {
+		defer lock.Release()
+	}
+	if u.OnStart != nil {
+		u.OnStart(uploadID, statusFilePath)
 	}
 
-	var completedParts []s3types.CompletedPart
-	// Concurrently upload chunks
+	// Concurrently upload parts
 	maxConcurrency := u.Config.MaxConcurrency
 	if maxConcurrency <= 0 {
 		maxConcurrency = 1
 	}
-	jobs := make(chan chunker.Chunk, len(chunks))
-	results := make(chan s3types.CompletedPart, len(chunks))
-	errs := make(chan error, len(chunks))
+	var (
+		completedParts  []s3types.CompletedPart
+		partsMu         sync.Mutex
+		compressedBytes int64
+		firstErr        error
+		stopOnce        sync.Once
+	)
+	stop := make(chan struct{})
+	wctx, cancel := context.WithCancel(ctx) // 실패 시 스케줄러 대기도 풀어준다
+	defer cancel()
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+			cancel()
+		})
+	}
+	go func() {
+		<-wctx.Done()
+		if ctx.Err() != nil {
+			fail(fmt.Errorf("upload interrupted: %w", ctx.Err()))
+		}
+	}()
+	jobs := make(chan partJob, maxConcurrency)
+	sched := u.Scheduler.Stream(originalInfo.Size())
 
+	var wg sync.WaitGroup
 	for w := 1; w <= maxConcurrency; w++ {
+		wg.Add(1)
 		go func(workerID int) {
-			for ch := range jobs {
-				utils.Info(fmt.Sprintf("[Worker %d] Uploading part %d for file %s", workerID, ch.Index, uploadPath))
-
-				reader, err := fileChunker.GetChunkReader(ch)
-				if err != nil {
-					errs <- fmt.Errorf("[Worker %d] failed to get chunk reader for part %d: %w", workerID, ch.Index, err)
-					return
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case <-stop: // 이미 실패 → 남은 파트는 올리지 않고 버퍼만 반환
+					job.grant.Release(0)
+					job.release()
+					continue
+				default:
 				}
-
-				r.partStart(ch.Index, ch.Size, ch.Offset)
-
-				// Retry logic for each part
-				var uploadOutput *s3.UploadPartOutput
-				err = utils.Retry(5, 2*time.Second, func() error {
-					// Reset reader to the beginning of the chunk for each retry
-					_, seekErr := reader.Seek(0, 0)
-					if seekErr != nil {
-						return fmt.Errorf("failed to seek chunk reader for part %d: %w", ch.Index, seekErr)
+				grant := job.grant // 압축 파트는 생산자가 버퍼를 채우기 전에 받아 둔다
+				if grant == nil {
+					var err error
+					if grant, err = sched.Acquire(wctx, job.size); err != nil {
+						job.release()
+						continue
 					}
-
-					pr := NewReadSeekCloserProgress(reader, func(n int64) {
-						_ = totalBar.Add64(n)
-						r.progressAdd(n)
-						r.partProgressAdd(ch.Index, n)
-					})
-
-					var partErr error
-					uploadOutput, partErr = u.s3Client.UploadPart(context.Background(), &s3.UploadPartInput{
-						Body:          pr,
-						Bucket:        &u.Config.Bucket,
-						Key:           &s3Key,
-						PartNumber:    aws.Int32(int32(ch.Index)),
-						UploadId:      &uploadID,
-						ContentLength: aws.Int64(ch.Size),
-					})
-					if partErr != nil {
-						utils.Error(fmt.Sprintf("[Worker %d] Failed to upload part %d: %v", workerID, ch.Index, partErr))
-						return partErr
-					}
-					return nil
-				})
-				_ = reader.Close()
-
-				if err != nil {
-					errs <- fmt.Errorf("[Worker %d] failed to upload part %d after retries: %w", workerID, ch.Index, err)
-					return
 				}
-
-				if uploadOutput.ETag == nil {
-					errs <- fmt.Errorf("[Worker %d] ETag for part %d is nil", workerID, ch.Index)
-					return
-				}
-
-				part := s3types.CompletedPart{
-					PartNumber: aws.Int32(int32(ch.Index)),
-					ETag:       uploadOutput.ETag,
+				etag, err := u.uploadPartJob(wctx, workerID, s3Key, uploadID, job, totalBar, r)
+				job.release()
+				if err != nil {
+					grant.Release(0)
+					fail(err)
+					continue
 				}
-				results <- part
-				status.AddCompletedPart(ch.Index, *uploadOutput.ETag)
+				grant.Release(job.srcSize)
+				partsMu.Lock()
+				completedParts = append(completedParts, s3types.CompletedPart{
+					PartNumber: aws.Int32(int32(job.index)),
+					ETag:       aws.String(etag),
+				})
+				partsMu.Unlock()
+				status.AddCompletedPart(job.index, etag)
 				if err := status.SaveStatus(statusFilePath); err != nil {
-					utils.Error(fmt.Sprintf("[Worker %d] Failed to save status for part %d: %v", workerID, ch.Index, err))
+					utils.Error(fmt.Sprintf("[Worker %d] Failed to save status for part %d: %v", workerID, job.index, err))
 				}
-				utils.Info(fmt.Sprintf("[Worker %d] Successfully uploaded part %d. ETag: %s", workerID, ch.Index, *uploadOutput.ETag))
-				r.partDone(ch.Index, ch.Size, *uploadOutput.ETag)
 			}
 		}(w)
 	}
 
-	for _, ch := range chunks {
-		jobs <- ch
+	if stream != nil {
+		// 압축 버퍼는 maxConcurrency+1 개만 재사용 → 메모리 ≈ (maxConcurrency+1) × partSize.
+		// 스케줄러가 있으면 버퍼마다 grant 를 먼저 받으므로 채워진 버퍼도 maxInFlightMB 안에 든다
+		free := make(chan *bytes.Buffer, maxConcurrency+1)
+		for i := 0; i < cap(free); i++ {
+			free <- new(bytes.Buffer)
+		}
+	produce:
+		for {
+			grant, err := sched.Acquire(wctx, u.Config.PartSizeBytes())
+			if err != nil {
+				break // 다른 파트가 실패했다
+			}
+			var buf *bytes.Buffer
+			select {
+			case <-stop:
+				grant.Release(0)
+				break produce
+			case buf = <-free:
+			}
+			psp := sp.Child("compress part") // EOF 면 End 하지 않으므로 내보내지 않는다
+			n, seg, err := stream.Next(buf)
+			if errors.Is(err, io.EOF) {
+				grant.Release(0)
+				break
+			}
+			if err != nil {
+				grant.Release(0)
+				psp.End(err)
+				fail(fmt.Errorf("compress part %d: %w", stream.part, err))
+				break
+			}
+			psp.Set("favus.part", n, "favus.source_bytes", seg.Size, "favus.compressed_bytes", int64(buf.Len()))
+			psp.End(nil)
+			// 업로드 전에 범위를 기록해야 중단돼도 같은 파트를 다시 만들 수 있다
+			status.SetSegment(n, seg)
+			if err := status.SaveStatus(statusFilePath); err != nil {
+				utils.Error(fmt.Sprintf("Failed to save status for part %d: %v", n, err))
+			}
+			compressedBytes += int64(buf.Len())
+			utils.Info(fmt.Sprintf("Compressed part %d: source bytes %d-%d → %d bytes", n, seg.Offset, seg.End(), buf.Len()))
+			jobs <- partJob{
+				index:   n,
+				offset:  seg.Offset,
+				size:    int64(buf.Len()),
+				srcSize: seg.Size,
+				open: func() (io.ReadSeekCloser, error) {
+					return readSeekNopCloser{bytes.NewReader(buf.Bytes())}, nil
+				},
+				release: func() { free <- buf },
+				grant:   grant,
+			}
+		}
+	} else {
+	send:
+		for _, ch := range chunks {
+			ch := ch
+			select {
+			case <-stop:
+				break send
+			case jobs <- partJob{
+				index:   ch.Index,
+				offset:  ch.Offset,
+				size:    ch.Size,
+				srcSize: ch.Size,
+				open:    func() (io.ReadSeekCloser, error) { return fileChunker.GetChunkReader(ch) },
+				release: func() {},
+			}:
+			}
+		}
 	}
 	close(jobs)
+	wg.Wait()
 
-	for i := 0; i < len(chunks); i++ {
-		select {
-		case part := <-results:
-			completedParts = append(completedParts, part)
-		case err := <-errs:
-			utils.Error(fmt.Sprintf("An error occurred during upload: %v", err))
-			_ = u.AbortMultipartUpload(s3Key, uploadID)
-			r.done(false, uploadID)
-			return err
-		}
+	if firstErr != nil && ctx.Err() != nil {
+		// 중단은 실패가 아니다 — 업로드를 남겨 두고 resume 으로 이어간다
+		utils.Info(fmt.Sprintf("Upload interrupted; resume with: favus resume --file %s", statusFilePath))
+		r.done(false, uploadID)
+		return fmt.Errorf("upload interrupted: %w", ctx.Err())
+	}
+	if firstErr != nil {
+		utils.Error(fmt.Sprintf("An error occurred during upload: %v", firstErr))
+		_ = u.AbortMultipartUpload(s3Key, uploadID)
+		r.done(false, uploadID)
+		return firstErr
+	}
+	if stream != nil {
+		status.UploadStatus.TotalParts = len(completedParts)
+		utils.Info(fmt.Sprintf("Compression result: %d bytes → %d bytes in %d parts", originalInfo.Size(), compressedBytes, len(completedParts)))
 	}
 
 	// Complete 전에 파트 오름차순 정렬(안전)
@@ -359,7 +565,9 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 
 	// Complete the multipart upload
 	utils.Info(fmt.Sprintf("Completing multipart upload for file: %s", filePath))
-	_, err = u.s3Client.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+	sp.Set("favus.parts", len(completedParts))
+	fsp := s3Span(sp, "CompleteMultipartUpload", u.Config.Bucket, s3Key, "aws.s3.upload_id", uploadID, "favus.parts", len(completedParts))
+	completeOut, err := u.store.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:   &u.Config.Bucket,
 		Key:      &s3Key,
 		UploadId: &uploadID,
@@ -367,16 +575,20 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 			Parts: completedParts,
 		},
 	})
+	fsp.End(err)
 	if err != nil {
 		utils.Error(fmt.Sprintf("Failed to complete multipart upload: %v", err))
 		r.error(fmt.Sprintf("complete multipart: %v", err), nil)
-		_ = u.AbortMultipartUpload(s3Key, uploadID)
+		if ctx.Err() == nil {
+			_ = u.AbortMultipartUpload(s3Key, uploadID)
+		}
 		r.done(false, uploadID)
 		return fmt.Errorf("failed to complete multipart upload: %w", err)
 	}
 
 	utils.Info(fmt.Sprintf("Multipart upload completed successfully for %s", filePath))
 	r.done(true, uploadID)
+	hr.complete(aws.ToString(completeOut.ETag))
 
 	// Record successful upload in duplicate checker
 	if u.duplicateChecker != nil {
@@ -394,18 +606,100 @@ func (u *Uploader) UploadFile(filePath, s3Key string) error {
 	}
 
 	// Clean up chunk files
-	if err := fileChunker.CleanupChunks(); err != nil {
-		utils.Error(fmt.Sprintf("Failed to cleanup chunks for %s: %v", uploadPath, err))
+	if fileChunker != nil {
+		if err := fileChunker.CleanupChunks(); err != nil {
+			utils.Error(fmt.Sprintf("Failed to cleanup chunks for %s: %v", filePath, err))
+		}
 	}
 
-	success = true
 	return nil
 }
 
+// partJob is one UploadPart call: a chunk of the file or a compressed part buffer.
+type partJob struct {
+	index   int
+	offset  int64 // source offset
+	size    int64 // bytes sent to S3
+	srcSize int64 // source bytes the part covers (progress is reported in source bytes)
+	open    func() (io.ReadSeekCloser, error)
+	release func()
+	grant   *scheduler.Grant // taken before the part was built (compressed parts); nil: the worker acquires one
+}
+
+// uploadPartJob uploads one part with retries and returns its ETag.
+func (u *Uploader) uploadPartJob(ctx context.Context, workerID int, s3Key, uploadID string, job partJob, totalBar *progressbar.ProgressBar, r *wsReporter) (etag string, err error) {
+	utils.Info(fmt.Sprintf("[Worker %d] Uploading part %d (%d bytes)", workerID, job.index, job.size))
+	r.rec.PartStart(job.index, workerID, job.size, job.srcSize)
+	defer func() { r.rec.PartEnd(job.index, err) }()
+
+	reader, err := job.open()
+	if err != nil {
+		return "", fmt.Errorf("[Worker %d] failed to get chunk reader for part %d: %w", workerID, job.index, err)
+	}
+	defer reader.Close()
+
+	r.partStart(job.index, job.size, job.offset)
+
+	// Retry logic for each part
+	var uploadOutput *s3.UploadPartOutput
+	attempt := 0
+	err = utils.RetryContext(ctx, 5, 2*time.Second, func() error {
+		if attempt++; attempt > 1 {
+			r.partRetry(job.index, attempt)
+		}
+		// Reset reader to the beginning of the chunk for each retry
+		if _, seekErr := reader.Seek(0, io.SeekStart); seekErr != nil {
+			return fmt.Errorf("failed to seek chunk reader for part %d: %w", job.index, seekErr)
+		}
+
+		pr := NewReadSeekCloserProgress(reader, func(n int64) {
+			src := n
+			if job.size > 0 && job.srcSize != job.size {
+				src = n * job.srcSize / job.size
+			}
+			_ = totalBar.Add64(src)
+			r.progressAdd(src)
+			r.partProgressAdd(job.index, n)
+		})
+
+		var partErr error
+		psp := s3Span(r.span, "UploadPart", u.Config.Bucket, s3Key,
+			"aws.s3.upload_id", uploadID, "aws.s3.part_number", job.index, "favus.attempt", attempt, "favus.part_bytes", job.size)
+		uploadOutput, partErr = u.store.UploadPart(ctx, &s3.UploadPartInput{
+			Body:          pr,
+			Bucket:        &u.Config.Bucket,
+			Key:           &s3Key,
+			PartNumber:    aws.Int32(int32(job.index)),
+			UploadId:      &uploadID,
+			ContentLength: aws.Int64(job.size),
+		})
+		psp.End(partErr)
+		r.rec.PartAttempt(job.index, partErr)
+		if partErr != nil {
+			utils.Error(fmt.Sprintf("[Worker %d] Failed to upload part %d: %v", workerID, job.index, partErr))
+			return partErr
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[Worker %d] failed to upload part %d after retries: %w", workerID, job.index, err)
+		r.error(err.Error(), &job.index)
+		return "", err
+	}
+	if uploadOutput.ETag == nil {
+		r.error(fmt.Sprintf("nil ETag on part %d", job.index), &job.index)
+		return "", fmt.Errorf("[Worker %d] ETag for part %d is nil", workerID, job.index)
+	}
+
+	utils.Info(fmt.Sprintf("[Worker %d] Successfully uploaded part %d. ETag: %s", workerID, job.index, *uploadOutput.ETag))
+	r.partDone(job.index, job.size, *uploadOutput.ETag)
+	return *uploadOutput.ETag, nil
+}
+
 // DeleteFile deletes a specific object from the configured S3 bucket.
 func (u *Uploader) DeleteFile(s3Key string) error {
 	utils.Info(fmt.Sprintf("Deleting file s3://%s/%s", u.Config.Bucket, s3Key))
-	_, err := u.s3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+	_, err := u.store.DeleteObject(context.Background(), &s3.DeleteObjectInput{
 		Bucket: &u.Config.Bucket,
 		Key:    &s3Key,
 	})
@@ -420,7 +714,7 @@ func (u *Uploader) DeleteFile(s3Key string) error {
 // AbortMultipartUpload aborts an ongoing multipart upload in S3.
 func (u *Uploader) AbortMultipartUpload(s3Key, uploadID string) error {
 	utils.Info(fmt.Sprintf("Aborting multipart upload for key: %s, UploadID: %s", s3Key, uploadID))
-	_, err := u.s3Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+	_, err := u.store.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
 		Bucket:   &u.Config.Bucket,
 		Key:      &s3Key,
 		UploadId: &uploadID,
@@ -436,7 +730,7 @@ func (u *Uploader) AbortMultipartUpload(s3Key, uploadID string) error {
 // ListMultipartUploads lists all ongoing multipart uploads for the configured S3 bucket.
 func (u *Uploader) ListMultipartUploads() ([]s3types.MultipartUpload, error) {
 	utils.Info(fmt.Sprintf("Listing ongoing multipart uploads for bucket: %s", u.Config.Bucket))
-	output, err := u.s3Client.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{
+	output, err := u.store.ListMultipartUploads(context.Background(), &s3.ListMultipartUploadsInput{
 		Bucket: &u.Config.Bucket,
 	})
 	if err != nil {
@@ -448,34 +742,7 @@ func (u *Uploader) ListMultipartUploads() ([]s3types.MultipartUpload, error) {
 
 // NewUploaderWithAWSConfig lets callers provide a pre-built aws.Config (e.g., from awsutils.LoadAWSConfig).
 func NewUploaderWithAWSConfig(cfgApp *config.Config, awsCfg aws.Config) (*Uploader, error) {
-	endpoint := os.Getenv("AWS_ENDPOINT_URL")
-	cli := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		if endpoint != "" {
-			o.UsePathStyle = true // LocalStack / custom S3-compatible endpoints
-		}
-	})
-
-	// Create duplicate checker (unless explicitly disabled)
-	var duplicateChecker *duplicate.DuplicateChecker
-	if os.Getenv("DISABLE_DUPLICATE_CHECK") != "true" {
-		dc, err := duplicate.NewDuplicateChecker(cfgApp)
-		if err != nil {
-			utils.Error(fmt.Sprintf("Failed to create duplicate checker: %v", err))
-			// Continue without duplicate checking if Redis is not available
-			duplicateChecker = nil
-		} else {
-			duplicateChecker = dc
-		}
-	} else {
-		utils.Info("Duplicate checking is disabled via DISABLE_DUPLICATE_CHECK env var")
-		duplicateChecker = nil
-	}
-
-	return &Uploader{
-		s3Client:         cli,
-		Config:           cfgApp,
-		duplicateChecker: duplicateChecker,
-	}, nil
+	return NewUploaderWithStore(cfgApp, storage.NewS3Store(awsCfg)), nil
 }
 
 // ListObjects lists completed objects (not multipart sessions) in the configured bucket.
@@ -495,7 +762,7 @@ func (u *Uploader) ListObjects(prefix string, maxKeys int32) ([]s3types.Object,
 	var token *string
 	for {
 		input.ContinuationToken = token
-		out, err := u.s3Client.ListObjectsV2(context.Background(), input)
+		out, err := u.store.ListObjectsV2(context.Background(), input)
 		if err != nil {
 			return nil, fmt.Errorf("list objects: %w", err)
 		}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/GoCOMA/Favus/internal/report"
+	"github.com/GoCOMA/Favus/internal/tracing"
 	"github.com/GoCOMA/Favus/internal/wsagent"
 	"github.com/google/uuid"
 )
@@ -35,8 +37,15 @@ type wsReporter struct {
 	startSent    bool
 
 	parts map[int]*partTracker
+
+	span *tracing.Span    // 세션 span (--trace 가 없으면 nil)
+	rec  *report.Recorder // 파트별 기록 (--report 가 없으면 nil)
 }
 
+// EventSink, when set, receives every event the reporters produce, whether or
+// not a UI agent is running (the CLI sets it for in-process notifications).
+var EventSink func(wsagent.Event)
+
 func agentAddr() string {
 	if v := os.Getenv("FAVUS_AGENT_ADDR"); v != "" {
 		return v // 사용자가 favus ui --addr 바꾸면 ENV로 맞출 수 있음
@@ -60,7 +69,17 @@ func newWSReporter(total int64) *wsReporter {
 	}
 }
 
+// newReporter is newWSReporter with the uploader's preset RunID, if any.
+func (u *Uploader) newReporter(total int64) *wsReporter {
+	r := newWSReporter(total)
+	if u.RunID != "" {
+		r.runID = u.RunID
+	}
+	return r
+}
+
 func (r *wsReporter) send(evType string, payload any) {
+	r.notify(evType, payload)
 	if !r.ensureAgent() {
 		return
 	}
@@ -84,6 +103,7 @@ func (r *wsReporter) start(bucket, key, uploadID string, partSizeBytes int64, ex
 	}
 	r.startPayload = p
 	r.startSent = false
+	r.notify("session_start", p)
 	r.emitStart()
 }
 
@@ -93,10 +113,7 @@ func (r *wsReporter) progressAdd(delta int64) {
 	}
 	r.uploadedBytes += delta
 
-	// 250ms 스로틀
-	if !r.ensureAgent() {
-		return
-	}
+	// 250ms 스로틀 (에이전트가 없어도 EventSink 로는 보낸다)
 	now := time.Now()
 	if r.lastProgressFlush.IsZero() || now.Sub(r.lastProgressFlush) >= 250*time.Millisecond {
 		elapsed := now.Sub(r.started).Seconds()
@@ -121,9 +138,6 @@ func (r *wsReporter) progressAdd(delta int64) {
 func (r *wsReporter) totalProgressImmediate(bytes int64) {
 	// resume 초기 바이트 등 즉시 1회 송신
 	r.uploadedBytes = bytes
-	if !r.ensureAgent() {
-		return
-	}
 	elapsed := time.Since(r.started).Seconds()
 	var bps float64
 	if elapsed > 0 {
@@ -198,6 +212,14 @@ func (r *wsReporter) partDone(part int, size int64, etag string) {
 	delete(r.parts, part)
 }
 
+// partRetry announces attempt (2, 3, ...) of a part before it is sent again.
+func (r *wsReporter) partRetry(part, attempt int) {
+	r.send("part_retry", map[string]any{
+		"part":    part,
+		"attempt": attempt,
+	})
+}
+
 func (r *wsReporter) error(msg string, partNum *int) {
 	payload := map[string]any{
 		"message": msg,
@@ -219,6 +241,14 @@ func (r *wsReporter) done(success bool, uploadID string) {
 	})
 }
 
+func (r *wsReporter) notify(evType string, payload any) {
+	if EventSink == nil {
+		return
+	}
+	b, _ := json.Marshal(payload)
+	EventSink(wsagent.Event{Type: evType, RunID: r.runID, Timestamp: time.Now(), Payload: b})
+}
+
 func (r *wsReporter) ensureAgent() bool {
 	if r.enabled {
 		return true
@@ -246,7 +276,7 @@ func (r *wsReporter) emitStart() {
 
 func (r *wsReporter) writeEvent(evType string, payload any) error {
 	b, _ := json.Marshal(payload)
-	fmt.Printf("[WS-DEBUG] send → type=%s payload=%s\n", evType, string(b))
+	fmt.Fprintf(os.Stderr, "[WS-DEBUG] send → type=%s payload=%s\n", evType, string(b))
 	err := wsagent.SendEvent(context.Background(), r.addr, wsagent.Event{
 		Type:      evType,
 		RunID:     r.runID,
@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/GoCOMA/Favus/internal/faults"
 	"github.com/gorilla/websocket"
 )
 
@@ -29,6 +30,12 @@ type AgentConfig struct {
 
 	// 필요 시 API 키를 헤더로 보냄 (X-API-Key)
 	APIKey string
+
+	// 로컬 HTTP 서버에 추가로 마운트할 핸들러 (패턴 → 핸들러, 예: "/api/uploads/")
+	Handlers map[string]http.Handler
+
+	// 에이전트를 지나는 모든 이벤트(/event, Publish)를 받는 콜백 (예: 웹훅 알림)
+	OnEvent func(Event)
 }
 
 // 이벤트 공용 포맷(권장). 자유 필드가 필요하면 Payload를 쓰면 됨.
@@ -72,6 +79,8 @@ func SendEvent(ctx context.Context, addr string, ev any) error {
 	}
 	url := "http://" + addr + "/event"
 
+	// FAVUS_FAULTS 규칙에서 op=SendEvent로 이벤트 전송 경로를 지정할 수 있다
+	ctx = faults.WithOperation(ctx, "SendEvent")
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
 	if err != nil {
 		return fmt.Errorf("wsagent: build request: %w", err)
@@ -80,7 +89,8 @@ func SendEvent(ctx context.Context, addr string, ev any) error {
 
 	// 요청마다 새로운 클라이언트 생성
 	client := &http.Client{
-		Timeout: 10 * time.Second, // 필요 시 적절히 조정
+		Timeout:   10 * time.Second, // 필요 시 적절히 조정
+		Transport: faults.Transport(http.DefaultTransport),
 	}
 
 	res, err := client.Do(req)
@@ -130,6 +140,7 @@ type Agent struct {
 	wsConn *websocket.Conn
 
 	writeTimeout time.Duration
+	metrics      *metrics // /metrics (Prometheus)
 
 	httpSrv  *http.Server
 	started  chan struct{}
@@ -171,6 +182,7 @@ func Start(cfg AgentConfig) (*Agent, error) {
 		cfg:          cfg,
 		wsConn:       conn,
 		writeTimeout: 10 * time.Second,
+		metrics:      newMetrics(),
 		started:      make(chan struct{}),
 		stopping:     make(chan struct{}),
 	}
@@ -180,6 +192,10 @@ func Start(cfg AgentConfig) (*Agent, error) {
 	mux.HandleFunc("/healthz", ag.handleHealth)
 	mux.HandleFunc("/event", ag.handleEvent)
 	mux.HandleFunc("/shutdown", ag.handleStop)
+	mux.Handle("/metrics", ag.metrics)
+	for pattern, h := range cfg.Handlers {
+		mux.Handle(pattern, h)
+	}
 
 	ag.httpSrv = &http.Server{
 		Addr:              cfg.Addr,
@@ -257,6 +273,7 @@ func (a *Agent) handleEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	_ = r.Body.Close()
+	a.observe(body)
 
 	// 업스트림 WS로 그대로 전달(서버가 Event 스키마를 검증한다고 가정)
 	a.mu.Lock()
@@ -282,6 +299,35 @@ func (a *Agent) handleStop(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("stopping"))
 }
 
+// Publish forwards an event from inside the agent process (e.g. the browser
+// upload API) to the upstream WebSocket, like a POST to /event would.
+func (a *Agent) Publish(ev Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("wsagent: marshal event: %w", err)
+	}
+	a.metrics.observe(ev)
+	if a.cfg.OnEvent != nil {
+		a.cfg.OnEvent(ev)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.writeMessageLocked(websocket.TextMessage, b)
+}
+
+// observe feeds a raw /event body to the metrics and cfg.OnEvent; bodies that
+// are not an Event are only forwarded upstream.
+func (a *Agent) observe(body []byte) {
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil || ev.Type == "" {
+		return
+	}
+	a.metrics.observe(ev)
+	if a.cfg.OnEvent != nil {
+		a.cfg.OnEvent(ev)
+	}
+}
+
 // ===================== WS loops =====================
 
 func (a *Agent) readLoop() {
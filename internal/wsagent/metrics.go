@@ -0,0 +1,253 @@
+package wsagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staleSession: 이 시간 동안 이벤트가 없는 세션은 (CLI가 죽은 것으로 보고) active 에서 뺀다.
+const staleSession = 15 * time.Minute
+
+// durationBuckets are the favus_session_duration_seconds histogram bounds.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200, 21600}
+
+// metrics aggregates the events passing through the agent into Prometheus
+// counters, served as text exposition format on /metrics.
+type metrics struct {
+	mu sync.Mutex
+
+	uploadedBytes map[string]float64    // bucket → bytes of completed parts
+	partsDone     map[string]float64    // bucket
+	partsFailed   map[string]float64    // bucket
+	retries       map[string]float64    // bucket
+	sessions      map[[2]string]float64 // bucket, result → finished sessions
+	durations     map[string]*histogram // bucket
+
+	active map[string]*runMetrics // by run ID
+}
+
+type runMetrics struct {
+	bucket, key string
+	started     time.Time
+	lastSeen    time.Time
+	partBytes   float64
+	bps         float64 // last total_progress bps; 0 → derived from part bytes
+}
+
+type histogram struct {
+	counts []float64 // per durationBuckets bound (not cumulative)
+	sum    float64
+	count  float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		uploadedBytes: make(map[string]float64),
+		partsDone:     make(map[string]float64),
+		partsFailed:   make(map[string]float64),
+		retries:       make(map[string]float64),
+		sessions:      make(map[[2]string]float64),
+		durations:     make(map[string]*histogram),
+		active:        make(map[string]*runMetrics),
+	}
+}
+
+// observe updates the counters for one event.
+func (m *metrics) observe(ev Event) {
+	var p struct {
+		Bucket   string  `json:"bucket"`
+		Key      string  `json:"key"`
+		Size     float64 `json:"size"`
+		Part     *int    `json:"part"`
+		PartNum  *int    `json:"partNumber"`
+		Bps      float64 `json:"bps"`
+		Success  bool    `json:"success"`
+		Duration string  `json:"duration"`
+	}
+	if len(ev.Payload) > 0 {
+		_ = json.Unmarshal(ev.Payload, &p)
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.active[ev.RunID]
+	if ev.Type == "session_start" {
+		// 같은 runId 로 다시 시작(브라우저 업로드 재개 등)하면 기존 세션을 이어 쓴다
+		if run == nil {
+			run = &runMetrics{started: now}
+			m.active[ev.RunID] = run
+		}
+		run.bucket, run.key = p.Bucket, p.Key
+	}
+	bucket := "unknown" // 시작 이벤트를 못 본 세션
+	if run != nil {
+		run.lastSeen = now
+		if run.bucket != "" {
+			bucket = run.bucket
+		}
+	}
+
+	switch ev.Type {
+	case "total_progress":
+		if run != nil {
+			run.bps = p.Bps
+		}
+	case "part_done":
+		m.partsDone[bucket]++
+		m.uploadedBytes[bucket] += p.Size
+		if run != nil {
+			run.partBytes += p.Size
+		}
+	case "part_retry":
+		m.retries[bucket]++
+	case "error":
+		if p.Part != nil || p.PartNum != nil {
+			m.partsFailed[bucket]++
+		}
+	case "session_done":
+		result := "failure"
+		if p.Success {
+			result = "success"
+		}
+		m.sessions[[2]string{bucket, result}]++
+		d, err := time.ParseDuration(p.Duration)
+		if err != nil && run != nil {
+			d, err = now.Sub(run.started), nil
+		}
+		if err == nil {
+			h := m.durations[bucket]
+			if h == nil {
+				h = &histogram{counts: make([]float64, len(durationBuckets))}
+				m.durations[bucket] = h
+			}
+			h.observe(d.Seconds())
+		}
+		delete(m.active, ev.RunID)
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range durationBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format (version 0.0.4).
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, run := range m.active {
+		if now.Sub(run.lastSeen) > staleSession {
+			delete(m.active, id)
+		}
+	}
+
+	counter := func(name, help string, values map[string]float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, b := range sortedKeys(values) {
+			fmt.Fprintf(w, "%s{bucket=%s} %s\n", name, quote(b), num(values[b]))
+		}
+	}
+	counter("favus_uploaded_bytes_total", "Bytes of parts uploaded (as sent to S3).", m.uploadedBytes)
+	counter("favus_parts_done_total", "Parts uploaded.", m.partsDone)
+	counter("favus_parts_failed_total", "Parts that failed after all retries.", m.partsFailed)
+	counter("favus_part_retries_total", "Part upload attempts after the first.", m.retries)
+
+	fmt.Fprintf(w, "# HELP favus_sessions_total Finished upload sessions by result.\n# TYPE favus_sessions_total counter\n")
+	keys := make([][2]string, 0, len(m.sessions))
+	for k := range m.sessions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i][0]+"\x00"+keys[i][1] < keys[j][0]+"\x00"+keys[j][1] })
+	for _, k := range keys {
+		fmt.Fprintf(w, "favus_sessions_total{bucket=%s,result=%s} %s\n", quote(k[0]), quote(k[1]), num(m.sessions[k]))
+	}
+
+	active := make(map[string]float64)
+	ids := make([]string, 0, len(m.active))
+	for id, run := range m.active {
+		active[labelBucket(run.bucket)]++
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	fmt.Fprintf(w, "# HELP favus_sessions_active Upload sessions in progress.\n# TYPE favus_sessions_active gauge\n")
+	for _, b := range sortedKeys(active) {
+		fmt.Fprintf(w, "favus_sessions_active{bucket=%s} %s\n", quote(b), num(active[b]))
+	}
+	fmt.Fprintf(w, "# HELP favus_session_throughput_bytes_per_second Current throughput of each active session.\n# TYPE favus_session_throughput_bytes_per_second gauge\n")
+	for _, id := range ids {
+		run := m.active[id]
+		bps := run.bps
+		if bps == 0 {
+			if el := now.Sub(run.started).Seconds(); el > 0 {
+				bps = run.partBytes / el
+			}
+		}
+		fmt.Fprintf(w, "favus_session_throughput_bytes_per_second{bucket=%s,key=%s,run_id=%s} %s\n",
+			quote(labelBucket(run.bucket)), quote(run.key), quote(id), num(bps))
+	}
+
+	fmt.Fprintf(w, "# HELP favus_session_duration_seconds Duration of finished upload sessions.\n# TYPE favus_session_duration_seconds histogram\n")
+	buckets := make([]string, 0, len(m.durations))
+	for b := range m.durations {
+		buckets = append(buckets, b)
+	}
+	sort.Strings(buckets)
+	for _, b := range buckets {
+		h := m.durations[b]
+		var cum float64
+		for i, le := range durationBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(w, "favus_session_duration_seconds_bucket{bucket=%s,le=%s} %s\n", quote(b), quote(num(le)), num(cum))
+		}
+		fmt.Fprintf(w, "favus_session_duration_seconds_bucket{bucket=%s,le=\"+Inf\"} %s\n", quote(b), num(h.count))
+		fmt.Fprintf(w, "favus_session_duration_seconds_sum{bucket=%s} %s\n", quote(b), num(h.sum))
+		fmt.Fprintf(w, "favus_session_duration_seconds_count{bucket=%s} %s\n", quote(b), num(h.count))
+	}
+}
+
+func labelBucket(b string) string {
+	if b == "" {
+		return "unknown"
+	}
+	return b
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quote escapes a label value (backslash, double quote and newline).
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+func num(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
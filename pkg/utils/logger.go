@@ -15,7 +15,7 @@ func init() {
 	var err error
 	logFile, err = os.OpenFile(LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		logger = log.New(os.Stdout, "[FAVUS] ", log.LstdFlags)
+		logger = log.New(os.Stderr, "[FAVUS] ", log.LstdFlags)
 		return
 	}
 	logger = log.New(logFile, "[FAVUS] ", log.LstdFlags)
@@ -1,19 +1,32 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
 
 func Retry(attempts int, sleep time.Duration, fn func() error) error {
+	return RetryContext(context.Background(), attempts, sleep, fn)
+}
+
+// RetryContext is Retry that gives up as soon as ctx is done.
+func RetryContext(ctx context.Context, attempts int, sleep time.Duration, fn func() error) error {
 	var err error
 	for i := 0; i < attempts; i++ {
 		err = fn()
 		if err == nil {
 			return nil
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		fmt.Printf("Retrying (%d/%d) after error: %v\n", i+1, attempts, err)
-		time.Sleep(sleep)
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return fmt.Errorf("all retries failed: %w", err)
 }